@@ -1,6 +1,9 @@
 package v1
 
-import "net/netip"
+import (
+	"net/netip"
+	"time"
+)
 
 type Port struct {
 	Name      string
@@ -8,6 +11,87 @@ type Port struct {
 	Id        *int
 	Internal  bool
 	IpAddress *netip.Prefix
+	// Qos are the traffic controls of the port. nil leaves the port without any
+	Qos *PortQos
+	// Tag is the VLAN of an access port, or the native VLAN of a trunk. nil leaves the port untagged
+	Tag *int
+	// Trunks are the VLANs a trunk port carries. Empty carries all of them
+	Trunks []int
+	// VlanMode is one of the VLAN_MODE_ values. Empty leaves it to OVS: access if the port has a tag, trunk otherwise.
+	// An existing port requested without Tag, Trunks or VlanMode keeps its VLAN configuration, which is cleared by
+	// setting the VLAN of the port on its own
+	VlanMode string
+	// Mtu is the MTU requested for the interface of the port. 0 leaves it as it is
+	Mtu int
+	// Role is one of the PORT_ROLE_ values, recorded in the external_ids of the port. Empty leaves the labels of an
+	// existing port as they are. Ports read from the switch only have it if talpa created them
+	Role string
+	// Bond makes the port a bond of several interfaces instead of a port with an interface of its own name. nil for other ports
+	Bond *Bond
+	// State is the state of the port, only set on ports read from the switch. Bonds have the state of their members instead
+	State *PortState
+}
+
+// Bond aggregates several interfaces into a single port of the bridge, which keeps forwarding while any of them is up.
+type Bond struct {
+	// Members are the interfaces of the bond, at least two
+	Members []string `json:"members"`
+	// Mode is one of the BOND_MODE_ values. Empty keeps the OVS default, active-backup
+	Mode string `json:"mode,omitempty"`
+	// Lacp is one of the LACP_ values. Empty disables LACP, like off
+	Lacp string `json:"lacp,omitempty"`
+	// Status is the state of the members, only set on bonds read from the switch
+	Status []BondMemberStatus `json:"-"`
+}
+
+// BondMemberStatus is the state of an interface of a bond, as seen by ovs-vswitchd.
+type BondMemberStatus struct {
+	Name      string
+	LinkState string
+	// Active is true for the member the bond sends its traffic through, in active-backup mode
+	Active bool
+	// LacpCurrent is true when the member has an up to date LACP negotiation with the other end
+	LacpCurrent bool
+}
+
+// PortState is the state of a port, as seen by ovs-vswitchd and, for the linux side, by the kernel.
+type PortState struct {
+	Ofport      int64
+	Type        string
+	AdminState  string
+	LinkState   string
+	Mac         string
+	Mtu         int64
+	ExternalIds map[string]string
+	Statistics  map[string]int64
+	// kernel state, empty if the interface is not in the namespace of talpa
+	OperState   string
+	Carrier     bool
+	PeerIfindex int
+}
+
+// PortQos are the traffic controls of a port: the policing of the traffic the port sends into the
+// switch and the egress queues of the traffic the switch sends out through it.
+type PortQos struct {
+	// IngressPolicingRate in kbps and IngressPolicingBurst in kb. 0 disables policing
+	IngressPolicingRate  int64 `json:"ingressPolicingRate,omitempty"`
+	IngressPolicingBurst int64 `json:"ingressPolicingBurst,omitempty"`
+	// Type of the egress QoS, linux-htb if empty
+	Type string `json:"type,omitempty"`
+	// MaxRate is the egress rate of the whole port in bps. 0 leaves it to the link speed
+	MaxRate int64   `json:"maxRate,omitempty"`
+	Queues  []Queue `json:"queues,omitempty"`
+}
+
+// Queue is an egress queue of a port, selected by the set_queue action of the flows.
+type Queue struct {
+	Id uint32 `json:"id"`
+	// MinRate and MaxRate in bps, Burst in bits. 0 keeps the OVS default
+	MinRate int64 `json:"minRate,omitempty"`
+	MaxRate int64 `json:"maxRate,omitempty"`
+	Burst   int64 `json:"burst,omitempty"`
+	// Priority of the queue, lower values are served first. Only used by linux-htb
+	Priority int64 `json:"priority,omitempty"`
 }
 
 type Bridge struct {
@@ -15,13 +99,153 @@ type Bridge struct {
 	Name       string
 	Protocol   string
 	DatapathId string
-	Ports      map[string]Port
-	Vxlans     map[string]Vxlan
+	FailMode   string
+	// DatapathType is one of the DATAPATH_ values. Empty is the OVS default, system
+	DatapathType string
+	Ports        map[string]Port
+	Vxlans       map[string]Vxlan
+	FlowExport   FlowExport
+	// SpanningTree is the loop protection of the bridge. The zero value disables it
+	SpanningTree SpanningTree
+	// Owner is the talpa instance the bridge belongs to, recorded in the external_ids of everything talpa creates on it
+	Owner Owner
+}
+
+// Owner identifies the talpa instance that created a bridge, port or interface, besides the name of its switch.
+type Owner struct {
+	Node     string
+	Provider string
+}
+
+// SpanningTree is the spanning tree protocol the bridge runs to block the tunnels that close loops in the topology.
+type SpanningTree struct {
+	// Protocol is one of the STP_PROTOCOL_ values. Empty disables the spanning tree
+	Protocol string `json:"protocol,omitempty"`
+	// Priority of the bridge, the lowest one becomes the root. nil keeps the OVS default, 32768.
+	// RSTP only accepts multiples of 4096
+	Priority *int `json:"priority,omitempty"`
+	// PathCost is the path cost of the tunnels that do not set their own. 0 keeps the OVS default, derived from the link speed
+	PathCost int `json:"pathCost,omitempty"`
 }
 
+// SpanningTreeStatus is the state of the spanning tree of a bridge, as reported by ovs-vswitchd.
+type SpanningTreeStatus struct {
+	Protocol string
+	BridgeId string
+	RootId   string
+	// IsRoot is true when the bridge is the root of the spanning tree
+	IsRoot       bool
+	RootPathCost int64
+	Ports        []SpanningTreePortStatus
+}
+
+// SpanningTreePortStatus is the state of a port of the spanning tree. Role and State are in lowercase,
+// e.g. root, designated or alternate and forwarding, learning, blocking or discarding.
+type SpanningTreePortStatus struct {
+	Name     string
+	Role     string
+	State    string
+	PathCost int
+	// Blocked is true when the port drops the traffic to break a loop
+	Blocked bool
+}
+
+// FlowExport are the collectors the bridge exports flow samples and statistics to. A nil collector is disabled.
+type FlowExport struct {
+	Sflow   *Sflow   `json:"sflow,omitempty"`
+	Ipfix   *Ipfix   `json:"ipfix,omitempty"`
+	Netflow *Netflow `json:"netflow,omitempty"`
+}
+
+// Sflow exports one in Sampling packets and the interface counters every Polling seconds to the sFlow collectors.
+type Sflow struct {
+	// Targets are the collectors, as ip:port
+	Targets []string `json:"targets"`
+	// Sampling, Polling and Header, the bytes of every sampled packet that are exported. 0 keeps the OVS default
+	Sampling int `json:"sampling,omitempty"`
+	Polling  int `json:"polling,omitempty"`
+	Header   int `json:"header,omitempty"`
+	// Agent is the interface whose address identifies the switch to the collectors. Empty lets OVS pick one
+	Agent string `json:"agent,omitempty"`
+}
+
+// Ipfix exports the flows of one in Sampling packets to the IPFIX collectors.
+type Ipfix struct {
+	// Targets are the collectors, as ip:port
+	Targets []string `json:"targets"`
+	// Sampling, CacheActiveTimeout in seconds and CacheMaxFlows. 0 keeps the OVS default
+	Sampling           int `json:"sampling,omitempty"`
+	CacheActiveTimeout int `json:"cacheActiveTimeout,omitempty"`
+	CacheMaxFlows      int `json:"cacheMaxFlows,omitempty"`
+}
+
+// Netflow exports the flows of the bridge to the NetFlow v5 collectors.
+type Netflow struct {
+	// Targets are the collectors, as ip:port
+	Targets []string `json:"targets"`
+	// ActiveTimeout is how often, in seconds, long lived flows are exported. 0 keeps the OVS default
+	ActiveTimeout int `json:"activeTimeout,omitempty"`
+}
+
+// Vxlan describes a tunnel port of the bridge. Despite its name it can be of any of the
+// tunnel types, VXLAN being the default when Type is empty.
 type Vxlan struct {
 	VxlanId  string
+	Type     string
 	LocalIp  string
 	RemoteIp string
-	UdpPort  string
+	// UdpPort is the destination port of UDP based tunnels, unused by GRE. Empty leaves the OVS default of the type
+	UdpPort string
+	// Key is the fixed tunnel id (VNI). Empty leaves it to the controller, which sets it per flow
+	Key string
+	// Tos and Ttl of the outer header, either a number or "inherit". Empty keeps the OVS default
+	Tos string
+	Ttl string
+	// DfDefault sets the don't fragment bit of the outer header, Csum enables the outer checksum.
+	// nil keeps the OVS default
+	DfDefault *bool
+	Csum      *bool
+	// EgressPktMark is the skb mark of the encapsulated packets, e.g. for IPsec policies
+	EgressPktMark string
+	// Options are additional type specific options of the interface, e.g. packet_type for GRE
+	Options map[string]string
+	// Qos are the traffic controls of the tunnel: its ingress policing caps what the neighbor can send
+	// into the switch and its egress queues what the switch sends to the neighbor. nil leaves it without any
+	Qos *PortQos
+	// Mtu is the MTU requested for the tunnel interface, which should leave room for the encapsulation
+	// on the underlay. 0 leaves it as it is
+	Mtu int
+	// PathCost is the spanning tree path cost of the tunnel. 0 leaves it as it is
+	PathCost int
+	// Neighbor is the node at the other end of the tunnel, recorded in its external_ids
+	Neighbor string
+	// Status is the state of the tunnel interface, only set on tunnels read from the switch
+	Status *TunnelStatus
+}
+
+// TunnelStatus is the state ovs-vswitchd reports for a tunnel interface.
+type TunnelStatus struct {
+	Ofport     int64
+	AdminState string
+	LinkState  string
+	// Error is set when ovs-vswitchd could not configure the interface
+	Error       string
+	BfdStatus   map[string]string
+	ExternalIds map[string]string
+	Statistics  map[string]int64
+}
+
+// Mirror copies the traffic of some ports or VLANs of the bridge to an output port, e.g. to capture it there.
+type Mirror struct {
+	Name string `json:"name"`
+	// SelectSrcPorts are the ports whose received traffic is mirrored, SelectDstPorts the ones whose sent traffic is
+	SelectSrcPorts []string `json:"selectSrcPorts,omitempty"`
+	SelectDstPorts []string `json:"selectDstPorts,omitempty"`
+	// SelectVlans mirrors the traffic of these VLANs, whatever port it goes through
+	SelectVlans []int `json:"selectVlans,omitempty"`
+	// SelectAll mirrors every packet of the bridge, ignoring the other selections
+	SelectAll  bool   `json:"selectAll,omitempty"`
+	OutputPort string `json:"outputPort"`
+	// ExpiresAt is when the mirror is removed. nil keeps it until it is deleted
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
@@ -7,8 +7,168 @@ const (
 	NEIGHBOR_FILE       = "neighbors.json"
 	DEFAULT_VXLAN_PORT  = "7000"
 	RESERVED_PROBE_ID   = 1999
+	MIN_MTU             = 68
+	MAX_MTU             = 65535
 )
 
+// Tunnel types, as named by the OVS interface type. STT is only available on kernels that support it.
+const (
+	TUNNEL_VXLAN  = "vxlan"
+	TUNNEL_GENEVE = "geneve"
+	TUNNEL_GRE    = "gre"
+	TUNNEL_STT    = "stt"
+)
+
+// IsTunnelType reports whether t is one of the supported tunnel types.
+func IsTunnelType(t string) bool {
+	switch t {
+	case TUNNEL_VXLAN, TUNNEL_GENEVE, TUNNEL_GRE, TUNNEL_STT:
+		return true
+	}
+	return false
+}
+
+// Egress QoS types of a port, as named by the OVS QoS type.
+const (
+	QOS_LINUX_HTB  = "linux-htb"
+	QOS_LINUX_HFSC = "linux-hfsc"
+)
+
+// IsQosType reports whether t is one of the supported egress QoS types.
+func IsQosType(t string) bool {
+	switch t {
+	case QOS_LINUX_HTB, QOS_LINUX_HFSC:
+		return true
+	}
+	return false
+}
+
+// VLAN modes of a port, as named by the vlan_mode column of the OVS Port table.
+const (
+	VLAN_MODE_ACCESS          = "access"
+	VLAN_MODE_TRUNK           = "trunk"
+	VLAN_MODE_NATIVE_TAGGED   = "native-tagged"
+	VLAN_MODE_NATIVE_UNTAGGED = "native-untagged"
+	MAX_VLAN_ID               = 4095
+)
+
+// IsVlanMode reports whether m is one of the supported VLAN modes.
+func IsVlanMode(m string) bool {
+	switch m {
+	case VLAN_MODE_ACCESS, VLAN_MODE_TRUNK, VLAN_MODE_NATIVE_TAGGED, VLAN_MODE_NATIVE_UNTAGGED:
+		return true
+	}
+	return false
+}
+
+// Fail modes of a bridge, which decide what it does while it has no controller connection:
+// in secure mode it keeps only the flows already installed, in standalone mode it acts as a learning switch.
+const (
+	FAIL_MODE_SECURE     = "secure"
+	FAIL_MODE_STANDALONE = "standalone"
+)
+
+// IsFailMode reports whether m is one of the supported fail modes.
+func IsFailMode(m string) bool {
+	switch m {
+	case FAIL_MODE_SECURE, FAIL_MODE_STANDALONE:
+		return true
+	}
+	return false
+}
+
+// Datapath types of a bridge, as named by the datapath_type column of the OVS Bridge table. The system datapath
+// forwards in the openvswitch kernel module, the netdev one in ovs-vswitchd itself, so it runs where the module
+// is not available, e.g. in CI containers or nested VMs.
+const (
+	DATAPATH_SYSTEM = "system"
+	DATAPATH_NETDEV = "netdev"
+)
+
+// IsDatapathType reports whether t is one of the supported datapath types.
+func IsDatapathType(t string) bool {
+	switch t {
+	case DATAPATH_SYSTEM, DATAPATH_NETDEV:
+		return true
+	}
+	return false
+}
+
+// Spanning tree protocols of a bridge, as enabled by the stp_enable and rstp_enable columns of the OVS Bridge table.
+const (
+	STP_PROTOCOL_STP  = "stp"
+	STP_PROTOCOL_RSTP = "rstp"
+)
+
+// IsSpanningTreeProtocol reports whether p is one of the supported spanning tree protocols.
+func IsSpanningTreeProtocol(p string) bool {
+	switch p {
+	case STP_PROTOCOL_STP, STP_PROTOCOL_RSTP:
+		return true
+	}
+	return false
+}
+
+// Bond modes of a port, as named by the bond_mode column of the OVS Port table. active-backup sends all the traffic
+// through one member and fails over to another, balance-slb spreads it by source MAC and VLAN without help from
+// the other end and balance-tcp by L3/L4 headers, which requires LACP.
+const (
+	BOND_MODE_ACTIVE_BACKUP = "active-backup"
+	BOND_MODE_BALANCE_SLB   = "balance-slb"
+	BOND_MODE_BALANCE_TCP   = "balance-tcp"
+)
+
+// IsBondMode reports whether m is one of the supported bond modes.
+func IsBondMode(m string) bool {
+	switch m {
+	case BOND_MODE_ACTIVE_BACKUP, BOND_MODE_BALANCE_SLB, BOND_MODE_BALANCE_TCP:
+		return true
+	}
+	return false
+}
+
+// LACP modes of a bond, as named by the lacp column of the OVS Port table. An active bond sends LACP PDUs on its
+// own, a passive one only answers the ones of the other end.
+const (
+	LACP_ACTIVE  = "active"
+	LACP_PASSIVE = "passive"
+	LACP_OFF     = "off"
+)
+
+// IsLacpMode reports whether m is one of the supported LACP modes.
+func IsLacpMode(m string) bool {
+	switch m {
+	case LACP_ACTIVE, LACP_PASSIVE, LACP_OFF:
+		return true
+	}
+	return false
+}
+
+// Roles of the ports talpa creates, recorded in their external_ids: the ports of the pods, the probe of the
+// monitoring, the end of a patch link on the other bridge, the tunnels to the neighbors and the bonded uplinks.
+const (
+	PORT_ROLE_PORT   = "port"
+	PORT_ROLE_PROBE  = "probe"
+	PORT_ROLE_PEER   = "peer"
+	PORT_ROLE_TUNNEL = "tunnel"
+	PORT_ROLE_BOND   = "bond"
+)
+
+// Policies of the orphan reaper: report only logs the resources talpa left behind, remove also deletes them.
+const (
+	REAPER_POLICY_REPORT = "report"
+	REAPER_POLICY_REMOVE = "remove"
+)
+
+// IsReaperPolicy reports whether p is one of the supported reaper policies.
+func IsReaperPolicy(p string) bool {
+	switch p {
+	case REAPER_POLICY_REPORT, REAPER_POLICY_REMOVE:
+		return true
+	}
+	return false
+}
+
 type Settings struct {
 	ControllerIP     []string `json:"controllerIp"`
 	ControllerPort   string   `json:"controllerPort"`
@@ -16,6 +176,39 @@ type Settings struct {
 	NodeName         string   `json:"nodeName,omitempty"`
 	SwitchName       string   `json:"switchName"`
 	InterfacesNumber int      `json:"interfacesNumber,omitempty"`
+	FailMode         string   `json:"failMode,omitempty"`
+	// TunnelType is the type of the tunnels to the neighbor nodes, vxlan by default
+	TunnelType string `json:"tunnelType,omitempty"`
+	// FailoverTimeout is the number of seconds without a controller connection after which the
+	// bridge falls back to standalone mode. 0 disables the fallback.
+	FailoverTimeout int `json:"failoverTimeout,omitempty"`
+	// PortQos are the default traffic controls of the ports talpa attaches to the switch
+	PortQos *PortQos `json:"portQos,omitempty"`
+	// FlowExport are the sFlow, IPFIX and NetFlow collectors of the switch. Collectors that are not set are removed
+	FlowExport *FlowExport `json:"flowExport,omitempty"`
+	// Mtu is the MTU of the ports and tunnels of the switch. 0 derives it from the MTU of the routes to the
+	// neighbors minus the overhead of the tunnels
+	Mtu int `json:"mtu,omitempty"`
+	// DatapathType is the datapath the switch runs on, system by default. On netdev the ports of the pods are
+	// internal ports of the switch instead of veths. Tunnels on netdev are sent out through the ports of
+	// ovs-vswitchd itself, so they only reach the neighbors if the underlay address is on an OVS bridge
+	DatapathType string `json:"datapathType,omitempty"`
+	// SpanningTree is the loop protection of the switch, for topologies whose links form cycles. nil disables it
+	SpanningTree *SpanningTree `json:"spanningTree,omitempty"`
+	// Bonds are the uplinks of the switch that aggregate several interfaces, e.g. two NICs to the same physical
+	// switch, keyed by the name of their port
+	Bonds map[string]Bond `json:"bonds,omitempty"`
+	// Reaper looks for the ports, veths and peers talpa left behind. nil only reports them on demand
+	Reaper *Reaper `json:"reaper,omitempty"`
+}
+
+// Reaper finds the resources talpa created that were left behind: OVS ports whose device is gone, veths whose
+// peer is gone or not enslaved to a bridge, and peers without their veth.
+type Reaper struct {
+	// Interval is the number of seconds between runs. 0 only runs it on demand
+	Interval int `json:"interval,omitempty"`
+	// Policy is one of the REAPER_POLICY_ values, report by default
+	Policy string `json:"policy,omitempty"`
 }
 
 type MonitoringSettings struct {
@@ -4,11 +4,19 @@ type Node struct {
 	Name          string   `json:"name"`
 	NodeIP        string   `json:"nodeIP"`
 	NeighborNodes []string `json:"neighborNodes,omitempty"`
+	// NeighborQos are the traffic controls of the tunnel to each neighbor, keyed by its address
+	NeighborQos map[string]PortQos `json:"neighborQos,omitempty"`
+	// NeighborPathCost is the spanning tree path cost of the tunnel to each neighbor, keyed by its address
+	NeighborPathCost map[string]int `json:"neighborPathCost,omitempty"`
 }
 
 type Link struct {
 	EndpointNodeA string `json:"endpointA"`
 	EndpointNodeB string `json:"endpointB"`
+	// Qos are the traffic controls of the tunnel of the link, applied on both ends
+	Qos *PortQos `json:"qos,omitempty"`
+	// PathCost is the spanning tree path cost of the tunnel of the link. 0 keeps the one of the switch configuration
+	PathCost int `json:"pathCost,omitempty"`
 }
 
 type Topology struct {
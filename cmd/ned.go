@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/netip"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -18,6 +19,7 @@ import (
 
 	"github.com/Networks-it-uc3m/l2sm-switch/internal/controller"
 	"github.com/Networks-it-uc3m/l2sm-switch/internal/filewatcher"
+	"github.com/Networks-it-uc3m/l2sm-switch/internal/metrics"
 	"github.com/Networks-it-uc3m/l2sm-switch/internal/server"
 )
 
@@ -66,16 +68,44 @@ to quickly create a Cobra application.`,
 			return
 		}
 
-		ctr := controller.NewSwitchManager(settings.SwitchName, settings.NodeName, sudo)
+		ctr := controller.NewSwitchManager(settings.SwitchName, settings.NodeName, sudo, ovsdbAddress)
+		if err = ctr.SetDatapathType(settings.DatapathType); err != nil {
+			fmt.Println("Error with the datapath type. Error:", err)
+			return
+		}
+		if err = ctr.SetTunnelType(settings.TunnelType); err != nil {
+			fmt.Println("Error with the tunnel type. Error:", err)
+			return
+		}
+		if err = ctr.SetPortQos(settings.PortQos); err != nil {
+			fmt.Println("Error with the port qos. Error:", err)
+			return
+		}
+		ctr.SetProviderName(settings.ProviderName)
+		ctr.SetFlowExport(settings.FlowExport)
+		ctr.SetSpanningTree(settings.SpanningTree)
+		if err = ctr.SetMtu(settings.Mtu); err != nil {
+			fmt.Println("Error with the mtu. Error:", err)
+			return
+		}
+		if err = ctr.SetReaper(settings.Reaper); err != nil {
+			fmt.Println("Error with the reaper. Error:", err)
+			return
+		}
 
 		_, err = ctr.ConfigureSwitch(
 			settings.ControllerPort,
 			settings.ControllerIP,
+			settings.FailMode,
 		)
 		if err != nil {
 			fmt.Println("Error configuring switch. Error:", err)
 			return
 		}
+		if err = ctr.AddBonds(settings.Bonds); err != nil {
+			fmt.Println("Error configuring the bonds. Error:", err)
+			return
+		}
 		ports, err := ctr.GetOrphanInterfaces(dp.NewIfId(settings.SwitchName))
 		if err != nil {
 
@@ -106,6 +136,18 @@ to quickly create a Cobra application.`,
 		ctx := context.Background()
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
+		if settings.FailoverTimeout > 0 {
+			if err = ctr.StartFailoverWatchdog(ctx, time.Duration(settings.FailoverTimeout)*time.Second); err != nil {
+				fmt.Println("Error starting the failover watchdog. Error:", err)
+			}
+		}
+		if metricsAddress != "" {
+			metrics.StartMetricsServer(ctx, metricsAddress, ctr)
+		}
+		if err = ctr.StartMirrorExpiry(ctx); err != nil {
+			fmt.Println("Error starting the mirror expiry. Error:", err)
+		}
+		ctr.StartReaper(ctx)
 		filewatcher.StartFileWatcher(ctx, configPath, ctr)
 
 		server.StartGrpcServer(port, ctr)
@@ -8,14 +8,18 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 var configPath string
 var monitorFile string
+var ovsdbAddress string
+var metricsAddress string
 var sudo *bool
 
 // rootCmd represents the base command when called without any subcommands
@@ -35,7 +39,10 @@ It can either act as a Slice Packet Switch (SPS) or as a Network Edge Device (NE
 			useSudo, _ := cmd.Flags().GetBool("sudo")
 			// OR if sudo is persistent on root: cmd.Root().Flags().GetBool("sudo")
 			ctx := cmd.Context()
-			return initOvs(ctx, useSudo)
+			// the settings are read again, and their errors reported, by the subcommand itself
+			var settings plsv1.Settings
+			_ = utils.ReadFile(filepath.Join(configPath, plsv1.SETTINGS_FILE), &settings)
+			return initOvs(ctx, useSudo, settings.DatapathType)
 		default:
 			return nil
 		}
@@ -60,11 +67,15 @@ func init() {
 	sudo = rootCmd.PersistentFlags().Bool("sudo", false, "Append sudo to commands (for debugging)")
 	// when this action is called directly.
 	rootCmd.PersistentFlags().StringVar(&monitorFile, "monitor_file", "", "Path to monitoring config file (enables monitoring sidecar/container)")
+	rootCmd.PersistentFlags().StringVar(&ovsdbAddress, "ovsdb", "", "ovsdb-server address (e.g. unix:/var/run/openvswitch/db.sock) to manage the switch through JSON-RPC instead of ovs-vsctl")
+	rootCmd.PersistentFlags().StringVar(&metricsAddress, "metrics_address", "", "address (e.g. :9090) to serve prometheus metrics on, under /metrics. Disabled if empty.")
 
 	//rootCmd.Flags().BoolP("grpc_server", "", false, "Help message for toggle")
 }
 
-func initOvs(ctx context.Context, useSudo bool) error {
+// initOvs starts ovsdb-server and ovs-vswitchd. On the netdev datapath ovs-vswitchd runs without the system one,
+// so it does not look for the openvswitch kernel module.
+func initOvs(ctx context.Context, useSudo bool, datapathType string) error {
 	// helper
 	run := func(name string, args ...string) error {
 		if useSudo {
@@ -97,10 +108,14 @@ func initOvs(ctx context.Context, useSudo bool) error {
 		return err
 	}
 
-	if err := run("ovs-vswitchd",
+	vswitchdArgs := []string{
 		"--pidfile=/var/run/openvswitch/ovs-vswitchd.pid",
 		"--detach",
-	); err != nil {
+	}
+	if datapathType == plsv1.DATAPATH_NETDEV {
+		vswitchdArgs = append(vswitchdArgs, "--disable-system")
+	}
+	if err := run("ovs-vswitchd", vswitchdArgs...); err != nil {
 		return err
 	}
 
@@ -4,6 +4,7 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/netip"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 	// Adjust the import path based on your module path
 	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
 	"github.com/Networks-it-uc3m/l2sm-switch/internal/controller"
+	"github.com/Networks-it-uc3m/l2sm-switch/internal/metrics"
 	dp "github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
 	"github.com/Networks-it-uc3m/l2sm-switch/pkg/utils"
 )
@@ -59,10 +61,30 @@ to quickly create a Cobra application.`,
 		fmt.Println("Initializing switch, connecting to controller: ", settings.ControllerIP)
 		switchName := dp.GetSwitchName(dp.DatapathParams{NodeName: nodeName, ProviderName: settings.ProviderName})
 
-		ctr := controller.NewSwitchManager(switchName, nodeName, *sudo)
+		ctr := controller.NewSwitchManager(switchName, nodeName, *sudo, ovsdbAddress)
+		if err = ctr.SetDatapathType(settings.DatapathType); err != nil {
+			fmt.Println("Error with the datapath type. Error:", err)
+			return
+		}
+		if err = ctr.SetTunnelType(settings.TunnelType); err != nil {
+			fmt.Println("Error with the tunnel type. Error:", err)
+			return
+		}
+		if err = ctr.SetPortQos(settings.PortQos); err != nil {
+			fmt.Println("Error with the port qos. Error:", err)
+			return
+		}
+		ctr.SetProviderName(settings.ProviderName)
+		ctr.SetFlowExport(settings.FlowExport)
+		ctr.SetSpanningTree(settings.SpanningTree)
+		if err = ctr.SetMtu(settings.Mtu); err != nil {
+			fmt.Println("Error with the mtu. Error:", err)
+			return
+		}
 		vs, err := ctr.ConfigureSwitch(
 			settings.ControllerPort,
 			settings.ControllerIP,
+			settings.FailMode,
 		)
 
 		if err != nil {
@@ -70,13 +92,31 @@ to quickly create a Cobra application.`,
 			fmt.Println("Could not initialize switch. Error:", err)
 			return
 		}
+		if err = ctr.AddBonds(settings.Bonds); err != nil {
+			fmt.Println("Could not configure the bonds. Error:", err)
+			return
+		}
 
 		fmt.Println("Switch initialized and connected to the controller.")
 
+		if metricsAddress != "" {
+			metrics.StartMetricsServer(context.Background(), metricsAddress, ctr)
+		}
+
+		if settings.FailoverTimeout > 0 {
+			if err = ctr.StartFailoverWatchdog(context.Background(), time.Duration(settings.FailoverTimeout)*time.Second); err != nil {
+				fmt.Println("Error starting the failover watchdog. Error:", err)
+			}
+		}
+
+		if err = ctr.StartMirrorExpiry(context.Background()); err != nil {
+			fmt.Println("Error starting the mirror expiry. Error:", err)
+		}
+
 		ports, err := ctr.GetOrphanInterfaces(dp.NewIfId(switchName))
 		if err != nil {
 
-			fmt.Println("error retrieving the existing interfaces. err: %v")
+			fmt.Printf("error retrieving the existing interfaces. err: %v\n", err)
 
 			return
 		}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/internal/controller"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/utils"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the connection status of the switch controllers",
+	Long: `Show, for every controller of the switch, whether it is connected, the OpenFlow role it has
+and the last connection error reported by Open vSwitch.
+
+The switch is the one given by --switch_name or, if it is not set, the one in config.json.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		switchName, err := cmd.Flags().GetString("switch_name")
+		if err != nil {
+			fmt.Println("Error with the switch name variable. Error:", err)
+			return
+		}
+		if switchName == "" {
+			var settings plsv1.Settings
+			if err = utils.ReadFile(filepath.Join(configPath, plsv1.SETTINGS_FILE), &settings); err != nil {
+				fmt.Println("Error with the config file. Error:", err)
+				return
+			}
+			switchName = settings.SwitchName
+		}
+
+		ctr := controller.NewSwitchManager(switchName, "", *sudo, ovsdbAddress)
+		statuses, err := ctr.GetControllerStatus()
+		if err != nil {
+			fmt.Println("Error getting the controller status. Error:", err)
+			return
+		}
+		if len(statuses) == 0 {
+			fmt.Printf("Switch %s has no controllers.\n", switchName)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TARGET\tCONNECTED\tROLE\tSTATE\tLAST ERROR")
+		for _, st := range statuses {
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", st.Target, st.IsConnected, st.Role, st.Status["state"], st.LastError)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().String("switch_name", "", "name of the switch to inspect. Defaults to the switch in config.json.")
+}
@@ -1,14 +1,18 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
 	"os/exec"
 	"regexp"
+	"sync"
 	"time"
 
 	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/internal/metrics"
 	"github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
 	dp "github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
 	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
@@ -16,14 +20,66 @@ import (
 	"github.com/Networks-it-uc3m/l2sm-switch/pkg/utils"
 )
 
+// ErrOrphanPort is returned when the next port name is already taken by an interface the switch does not know about,
+// and the reaper could not remove it.
+var ErrOrphanPort = errors.New("it looks like a talpa port is currently orphan")
+
+// ErrInvalidQos is returned when the traffic controls requested for a port cannot be applied.
+var ErrInvalidQos = errors.New("invalid port qos")
+
+// AttachError reports the step at which attaching an interface failed, together with any error
+// found while undoing the steps that had already been completed.
+type AttachError struct {
+	Step        string
+	Err         error
+	RollbackErr error
+}
+
+func (e *AttachError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("%s failed: %v (rollback failed: %v)", e.Step, e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+}
+
+func (e *AttachError) Unwrap() error {
+	return e.Err
+}
+
 type Controller struct {
 	switchName string
 	nodeName   string
-	sudo       bool
+	// providerName is the provider the switch belongs to, recorded with the node in the labels of what talpa creates
+	providerName string
+	sudo         bool
+	// ovsdb is the ovsdb-server address used instead of ovs-vsctl. Empty means ovs-vsctl.
+	ovsdb string
+	// backend replaces both ovs-vsctl and ovsdb-server when set, which only the tests do
+	backend ovs.Backend
+	// tunnelType is the type of the tunnels to the neighbors. Empty means vxlan.
+	tunnelType string
+	// portQos are the traffic controls of the ports attached without their own. nil means none.
+	portQos *plsv1.PortQos
+	// flowExport are the sFlow, IPFIX and NetFlow collectors of the switch. The zero value exports nothing.
+	flowExport plsv1.FlowExport
+	// mtu is the MTU of the ports and tunnels set in the configuration, 0 if it is derived from the underlay,
+	// in which case overlayMtu is the smallest tunnel MTU found so far.
+	mtu        int
+	overlayMtu int
+	// datapathType is the datapath the switch runs on. Empty means the OVS default, system.
+	datapathType string
+	// spanningTree is the loop protection of the switch. The zero value disables it.
+	spanningTree plsv1.SpanningTree
+	// reaper is the policy of the orphan reaper. The zero value only reports the orphans on demand.
+	reaper plsv1.Reaper
+	// mu keeps the reaper from taking the veths of an attachment in progress for orphans
+	mu sync.Mutex
 }
 
+// GetNewPort returns the next talpa port of the switch. If its name is taken by an interface the switch does not know
+// about and the reaper policy is remove, the orphans are reaped first so the id can be reused. The caller holds the lock.
 func (ctr *Controller) GetNewPort(ifid dp.Ifid) (plsv1.Port, error) {
-	vs, err := ovs.GetVirtualSwitch(ovs.WithName(ctr.switchName))
+	vs, err := ctr.getOvs()
 	if err != nil {
 
 		return plsv1.Port{}, fmt.Errorf("could not get virtual switch. error: %v", err)
@@ -34,27 +90,249 @@ func (ctr *Controller) GetNewPort(ifid dp.Ifid) (plsv1.Port, error) {
 		return plsv1.Port{}, fmt.Errorf("could not get a new port id: %v", err)
 	}
 	p := ifid.Port(id)
+	if linuxif.Exists(p) && ctr.reaper.Policy == plsv1.REAPER_POLICY_REMOVE {
+		if _, err = ctr.reapOrphans(true); err != nil {
+			return plsv1.Port{}, fmt.Errorf("could not reap orphans: %v", err)
+		}
+	}
 	if linuxif.Exists(p) {
-		// todo: give identity to the error so a recover process can be started
-		return plsv1.Port{}, fmt.Errorf("error getting new port id. it looks like a talpa port is currently orphan.")
+		return plsv1.Port{}, fmt.Errorf("error getting new port id %s: %w", p, ErrOrphanPort)
 	}
-	return plsv1.Port{Name: p, Id: &id}, nil
+	return plsv1.Port{Name: p, Id: &id, Role: plsv1.PORT_ROLE_PORT}, nil
 }
 
 func (ctr *Controller) GetNodeName() string {
 	return ctr.nodeName
 }
 
+// SetProviderName sets the provider the switch belongs to.
+func (ctr *Controller) SetProviderName(providerName string) {
+	ctr.providerName = providerName
+}
+
+// owner returns the labels of the talpa instance, set on the switch and on everything created on it.
+func (ctr *Controller) owner() plsv1.Owner {
+	return plsv1.Owner{Node: ctr.nodeName, Provider: ctr.providerName}
+}
+
 func (ctr *Controller) GetSwitchName() string {
 	return ctr.switchName
 }
 
-func NewSwitchManager(switchName, nodeName string, sudo bool) *Controller {
+func NewSwitchManager(switchName, nodeName string, sudo bool, ovsdb string) *Controller {
 
-	return &Controller{switchName, nodeName, sudo}
+	return &Controller{switchName: switchName, nodeName: nodeName, sudo: sudo, ovsdb: ovsdb}
 }
 
-func (ctr *Controller) ConfigureSwitch(controllerPort string, controllerIPs []string) (ovs.VirtualSwitch, error) {
+// SetTunnelType sets the type of the tunnels created towards the neighbors, one of the plsv1 TUNNEL_ types.
+// An empty type keeps the default, vxlan.
+func (ctr *Controller) SetTunnelType(tunnelType string) error {
+	if tunnelType != "" && !plsv1.IsTunnelType(tunnelType) {
+		return fmt.Errorf("unsupported tunnel type %s", tunnelType)
+	}
+	if err := checkTunnelDatapath(tunnelType, ctr.datapathType); err != nil {
+		return err
+	}
+	ctr.tunnelType = tunnelType
+	return nil
+}
+
+// SetDatapathType sets the datapath the switch runs on, one of the plsv1 DATAPATH_ types. An empty type keeps
+// the OVS default, system. On netdev the ports of the pods are internal ports of the switch instead of veths.
+func (ctr *Controller) SetDatapathType(datapathType string) error {
+	if datapathType != "" && !plsv1.IsDatapathType(datapathType) {
+		return fmt.Errorf("unsupported datapath type %s", datapathType)
+	}
+	if err := checkTunnelDatapath(ctr.tunnelType, datapathType); err != nil {
+		return err
+	}
+	ctr.datapathType = datapathType
+	return nil
+}
+
+// checkTunnelDatapath checks that the datapath implements the tunnels: STT only exists in the kernel one.
+func checkTunnelDatapath(tunnelType, datapathType string) error {
+	if tunnelType == plsv1.TUNNEL_STT && datapathType == plsv1.DATAPATH_NETDEV {
+		return fmt.Errorf("%s tunnels are not supported by the %s datapath", tunnelType, datapathType)
+	}
+	return nil
+}
+
+// userspace reports whether the switch runs on the userspace datapath.
+func (ctr *Controller) userspace() bool {
+	return ctr.datapathType == plsv1.DATAPATH_NETDEV
+}
+
+// SetPortQos sets the traffic controls of the ports attached to the switch without their own. nil attaches them without any.
+func (ctr *Controller) SetPortQos(qos *plsv1.PortQos) error {
+	if err := ValidatePortQos(qos); err != nil {
+		return err
+	}
+	ctr.portQos = qos
+	return nil
+}
+
+// SetFlowExport sets the flow collectors the switch is configured with. nil removes them all.
+func (ctr *Controller) SetFlowExport(export *plsv1.FlowExport) {
+	ctr.flowExport = plsv1.FlowExport{}
+	if export != nil {
+		ctr.flowExport = *export
+	}
+}
+
+// SetSpanningTree sets the spanning tree protocol of the switch, its priority and the default path cost of the
+// tunnels. nil disables it.
+func (ctr *Controller) SetSpanningTree(st *plsv1.SpanningTree) {
+	ctr.spanningTree = plsv1.SpanningTree{}
+	if st != nil {
+		ctr.spanningTree = *st
+	}
+}
+
+// tunnelPathCost sets the path cost of the tunnel, if any, in place of the default one of the switch.
+func (ctr *Controller) tunnelPathCost(vx *plsv1.Vxlan, cost int) error {
+	if cost == 0 {
+		return nil
+	}
+	if err := ovs.ValidatePathCost(ctr.spanningTree.Protocol, cost); err != nil {
+		return err
+	}
+	vx.PathCost = cost
+	return nil
+}
+
+// SetMtu sets the MTU of the ports and tunnels of the switch. 0 derives it from the MTU of the routes to the neighbors.
+func (ctr *Controller) SetMtu(mtu int) error {
+	if mtu != 0 && (mtu < plsv1.MIN_MTU || mtu > plsv1.MAX_MTU) {
+		return fmt.Errorf("mtu %d is out of range [%d, %d]", mtu, plsv1.MIN_MTU, plsv1.MAX_MTU)
+	}
+	ctr.mtu = mtu
+	return nil
+}
+
+// portMtu returns the MTU of the ports talpa attaches to the switch, 0 while it is not known.
+func (ctr *Controller) portMtu() int {
+	if ctr.mtu != 0 {
+		return ctr.mtu
+	}
+	return ctr.overlayMtu
+}
+
+// setTunnelMtu sets the MTU of every tunnel, either the configured one or the MTU of the route to the neighbor
+// minus the tunnel overhead, and returns the smallest of them. Tunnels whose route cannot be looked up keep theirs.
+func (ctr *Controller) setTunnelMtu(vxs []plsv1.Vxlan) int {
+	overlayMtu := 0
+	for i := range vxs {
+		if vxs[i].VxlanId == "" {
+			continue
+		}
+		mtu := ctr.mtu
+		if mtu == 0 {
+			underlayMtu, err := linuxif.RouteMtu(vxs[i].RemoteIp)
+			if err != nil {
+				fmt.Printf("Could not detect the underlay mtu of %s: %v\n", vxs[i].VxlanId, err)
+				continue
+			}
+			mtu = ovs.TunnelMtu(underlayMtu, vxs[i])
+		}
+		vxs[i].Mtu = mtu
+		if overlayMtu == 0 || mtu < overlayMtu {
+			overlayMtu = mtu
+		}
+	}
+	return overlayMtu
+}
+
+// applyPortMtu sets the MTU of the ports talpa attached to the switch, and of the other end of their veths,
+// to the overlay MTU once it is known.
+func (ctr *Controller) applyPortMtu(overlayMtu int) error {
+	if ctr.mtu != 0 || overlayMtu == 0 || overlayMtu == ctr.overlayMtu {
+		return nil
+	}
+	ctr.overlayMtu = overlayMtu
+
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	ports, err := vs.GetPorts()
+	if err != nil {
+		return err
+	}
+	update := []plsv1.Port{}
+	for _, port := range ports {
+		if (port.Role != plsv1.PORT_ROLE_PORT && port.Role != plsv1.PORT_ROLE_PROBE) || port.Mtu == overlayMtu {
+			continue
+		}
+		if port.Role == plsv1.PORT_ROLE_PORT {
+			if err = linuxif.SetMtu(datapath.GeneratePeerName(port), overlayMtu); err != nil && !errors.Is(err, linuxif.ErrLinkNotFound) {
+				return err
+			}
+		}
+		// the port is requested as it was read back, so only its MTU changes
+		port.Mtu = overlayMtu
+		update = append(update, port)
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	_, err = ctr.updateOvs(ovs.WithPorts(update))
+	return err
+}
+
+// ValidatePortQos checks that the traffic controls can be applied to a port. A nil qos is valid.
+func ValidatePortQos(qos *plsv1.PortQos) error {
+	if qos == nil {
+		return nil
+	}
+	if qos.Type != "" && !plsv1.IsQosType(qos.Type) {
+		return fmt.Errorf("%w: unsupported qos type %s", ErrInvalidQos, qos.Type)
+	}
+	if qos.IngressPolicingRate < 0 || qos.IngressPolicingBurst < 0 || qos.MaxRate < 0 {
+		return fmt.Errorf("%w: rates must not be negative", ErrInvalidQos)
+	}
+	ids := make(map[uint32]bool)
+	for _, queue := range qos.Queues {
+		if ids[queue.Id] {
+			return fmt.Errorf("%w: duplicated queue %d", ErrInvalidQos, queue.Id)
+		}
+		ids[queue.Id] = true
+		if queue.MinRate < 0 || queue.MaxRate < 0 || queue.Burst < 0 || queue.Priority < 0 {
+			return fmt.Errorf("%w: queue %d has negative values", ErrInvalidQos, queue.Id)
+		}
+		if queue.MaxRate > 0 && queue.MinRate > queue.MaxRate {
+			return fmt.Errorf("%w: queue %d min rate is above its max rate", ErrInvalidQos, queue.Id)
+		}
+	}
+	return nil
+}
+
+// newTunnel returns the tunnel from localIp to remoteIp, the address of neighbor, of the controller tunnel type. Its name
+// is derived from the type and the given identifier, so changing the type replaces the tunnels instead of reusing them.
+func (ctr *Controller) newTunnel(identifier, neighbor, localIp, remoteIp string) (plsv1.Vxlan, error) {
+	tunnelType := ctr.tunnelType
+	if tunnelType == "" {
+		tunnelType = plsv1.TUNNEL_VXLAN
+	}
+	vxID, err := utils.GenerateInterfaceName(tunnelType+"-", identifier)
+	if err != nil {
+		return plsv1.Vxlan{}, err
+	}
+	vx := plsv1.Vxlan{VxlanId: vxID, Type: tunnelType, LocalIp: localIp, RemoteIp: remoteIp, PathCost: ctr.spanningTree.PathCost, Neighbor: neighbor}
+	// the other types keep the port OVS uses for them, e.g. 6081 for geneve
+	if tunnelType == plsv1.TUNNEL_VXLAN {
+		vx.UdpPort = plsv1.DEFAULT_VXLAN_PORT
+	}
+	return vx, nil
+}
+
+// ConfigureSwitch connects the switch to the controllers with the given fail mode, creating the switch if needed,
+// and reconciles its flow collectors and spanning tree. An empty failMode keeps the fail mode of an existing switch
+// and leaves the OVS default on a new one.
+func (ctr *Controller) ConfigureSwitch(controllerPort string, controllerIPs []string, failMode string) (ovs.VirtualSwitch, error) {
+	if failMode != "" && !plsv1.IsFailMode(failMode) {
+		return ovs.VirtualSwitch{}, fmt.Errorf("unsupported fail mode %s", failMode)
+	}
 
 	re := regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
 
@@ -78,28 +356,138 @@ func (ctr *Controller) ConfigureSwitch(controllerPort string, controllerIPs []st
 	var err error
 	var vs ovs.VirtualSwitch
 
+	opts := []func(*ovs.BridgeConf){
+		ovs.WithController(controllers),
+		ovs.WithProtocol("OpenFlow13"),
+		ovs.WithDatapathId(datapathId),
+		ovs.WithDatapathType(ctr.datapathType),
+		ovs.WithFlowExport(ctr.flowExport),
+		ovs.WithSpanningTree(ctr.spanningTree),
+	}
+	// the fail mode of an existing switch is only changed when one is given
+	if failMode != "" {
+		opts = append(opts, ovs.WithFailMode(failMode))
+	}
+
 	_, err = ctr.getOvs()
 
 	if err != nil {
 		fmt.Println("Switch doesn't exist. Creating a new one.")
-		vs, err = ctr.newOvs(
-			ovs.WithController(controllers),
-			ovs.WithProtocol("OpenFlow13"),
-			ovs.WithDatapathId(datapathId),
-		)
+		vs, err = ctr.newOvs(opts...)
 
 		return vs, err
 	}
 
-	vs, err = ctr.updateOvs(
-		ovs.WithController(controllers),
-		ovs.WithProtocol("OpenFlow13"),
-		ovs.WithDatapathId(datapathId),
-	)
+	vs, err = ctr.updateOvs(opts...)
 
 	return vs, err
 }
 
+// GetControllerStatus returns the connection status of every controller of the switch.
+func (ctr *Controller) GetControllerStatus() ([]ovs.ControllerStatus, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	statuses, err := vs.GetControllerStatus()
+	if err != nil {
+		return nil, fmt.Errorf("could not get controller status: %v", err)
+	}
+	return statuses, nil
+}
+
+// GetSpanningTreeStatus returns the root bridge of the spanning tree and the role and state of the ports of the switch.
+func (ctr *Controller) GetSpanningTreeStatus() (plsv1.SpanningTreeStatus, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	st, err := vs.GetSpanningTreeStatus()
+	if err != nil {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("could not get spanning tree status: %v", err)
+	}
+	return st, nil
+}
+
+// GetPorts returns the ports of the switch with their OpenFlow port number and link state.
+func (ctr *Controller) GetPorts() ([]plsv1.Port, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	return vs.GetPorts()
+}
+
+// GetSwitch reads the switch once, for the metrics of a scrape.
+func (ctr *Controller) GetSwitch() (metrics.Switch, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	return &vs, nil
+}
+
+// StartFailoverWatchdog falls a secure switch back to standalone mode after timeout without a controller
+// connection, and returns it to secure mode when the controller is back, until ctx is done. Switches in
+// another fail mode are left as they are.
+func (ctr *Controller) StartFailoverWatchdog(ctx context.Context, timeout time.Duration) error {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	go ovs.NewFailoverWatchdog(vs, timeout).Run(ctx)
+	return nil
+}
+
+// CreateMirror copies the traffic the mirror selects to its output port or, if it has none, to the probe port
+// of the switch. A positive ttl removes the mirror once it elapses, see StartMirrorExpiry.
+func (ctr *Controller) CreateMirror(mirror plsv1.Mirror, ttl time.Duration) (plsv1.Mirror, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return plsv1.Mirror{}, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	if mirror.OutputPort == "" {
+		ifid := dp.NewIfId(ctr.switchName)
+		mirror.OutputPort = ifid.Probe(plsv1.RESERVED_PROBE_ID)
+	}
+	mirror.ExpiresAt = nil
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+		mirror.ExpiresAt = &expiresAt
+	}
+	if err = vs.CreateMirror(mirror); err != nil {
+		return plsv1.Mirror{}, err
+	}
+	return mirror, nil
+}
+
+func (ctr *Controller) DeleteMirror(mirrorName string) error {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	return vs.DeleteMirror(mirrorName)
+}
+
+// GetMirrors returns the mirrors of the switch.
+func (ctr *Controller) GetMirrors() ([]plsv1.Mirror, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	return vs.GetMirrors()
+}
+
+// StartMirrorExpiry removes the mirrors of the switch as they expire, until ctx is done.
+func (ctr *Controller) StartMirrorExpiry(ctx context.Context) error {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	go ovs.ExpireMirrors(ctx, vs)
+	return nil
+}
+
 /*
 *
 Example:
@@ -107,41 +495,56 @@ Example:
 	        {
 	            "Name": "l2sm1",
 	            "nodeIP": "10.1.14.53",
-				"neighborNodes":["10.4.2.3","10.4.2.5"]
+				"neighborNodes":["10.4.2.3","10.4.2.5"],
+				"neighborQos": {"10.4.2.3": {"ingressPolicingRate": 100000, "maxRate": 100000000}},
+				"neighborPathCost": {"10.4.2.5": 200}
 			}
 */
 func (ctr *Controller) ConnectToNeighbors(node plsv1.Node) error {
 	vxs := make([]plsv1.Vxlan, len(node.NeighborNodes))
 
 	for _, neighIP := range node.NeighborNodes {
-		vxID, err := utils.GenerateInterfaceName("vxlan-", fmt.Sprintf("%s%s", node.NodeIP, neighIP))
+		vx, err := ctr.newTunnel(fmt.Sprintf("%s%s", node.NodeIP, neighIP), neighIP, node.NodeIP, neighIP)
 		if err != nil {
 			return fmt.Errorf("error generating vxlan id: %v", err)
 		}
-		vxs = append(vxs, plsv1.Vxlan{VxlanId: vxID, LocalIp: node.NodeIP, RemoteIp: neighIP, UdpPort: plsv1.DEFAULT_VXLAN_PORT})
+		if qos, ok := node.NeighborQos[neighIP]; ok {
+			if err = ValidatePortQos(&qos); err != nil {
+				return fmt.Errorf("invalid qos for neighbor %s: %w", neighIP, err)
+			}
+			vx.Qos = &qos
+		}
+		if err = ctr.tunnelPathCost(&vx, node.NeighborPathCost[neighIP]); err != nil {
+			return fmt.Errorf("invalid path cost for neighbor %s: %w", neighIP, err)
+		}
+		vxs = append(vxs, vx)
 
 	}
-	_, err := ctr.updateOvs(ovs.WithVxlans(vxs))
+	overlayMtu := ctr.setTunnelMtu(vxs)
+	vs, err := ctr.updateOvs(ovs.WithVxlans(vxs))
 
 	if err != nil {
 		return fmt.Errorf("could not create vxlans with neighbors %s", node.NeighborNodes)
 	}
 
-	fmt.Printf("Created vxlan with neighbors %s\n", node.NeighborNodes)
+	fmt.Printf("Created vxlan with neighbors %s. Tunnels %s\n", node.NeighborNodes, vs.TunnelChanges())
 
+	if err = ctr.applyPortMtu(overlayMtu); err != nil {
+		return fmt.Errorf("could not set the mtu of the ports: %v", err)
+	}
 	return nil
 }
 
-// TODO: not finished the getVxlans method and getting localip
+// TODO: not finished getting localip
 func (ctr *Controller) ConnectNewNeighbor(ip string) error {
 	vs, _ := ctr.getOvs()
 	vxs, _ := vs.GetVxlans()
 
-	vxID, err := utils.GenerateInterfaceName("vxlan-", fmt.Sprintf("%s%s", "", ip))
+	vx, err := ctr.newTunnel(fmt.Sprintf("%s%s", "", ip), ip, "", ip)
 	if err != nil {
 		return fmt.Errorf("error generating vxlan id: %v", err)
 	}
-	vxs = append(vxs, plsv1.Vxlan{VxlanId: vxID, LocalIp: "", RemoteIp: ip, UdpPort: plsv1.DEFAULT_VXLAN_PORT})
+	vxs = append(vxs, vx)
 
 	_, err = ctr.updateOvs(ovs.WithVxlans(vxs))
 
@@ -172,7 +575,9 @@ Example:
 	    "Links": [
 	        {
 	            "endpointA": "l2sm1",
-	            "endpointB": "l2sm2"
+	            "endpointB": "l2sm2",
+	            "qos": {"maxRate": 100000000},
+	            "pathCost": 100
 	        }
 	    ]
 	}
@@ -199,26 +604,38 @@ func (ctr *Controller) CreateTopology(topology plsv1.Topology) error {
 
 	vxs := []plsv1.Vxlan{}
 	for _, link := range topology.Links {
-		var remoteIp string
+		var neighbor string
 		switch ctr.nodeName {
 		case link.EndpointNodeA:
-			remoteIp = nodeMap[link.EndpointNodeB]
+			neighbor = link.EndpointNodeB
 		case link.EndpointNodeB:
-			remoteIp = nodeMap[link.EndpointNodeA]
+			neighbor = link.EndpointNodeA
 		default:
 			continue
 		}
-		vxID, _ := utils.GenerateInterfaceName("vxlan-", remoteIp)
+		remoteIp := nodeMap[neighbor]
+		vx, _ := ctr.newTunnel(remoteIp, neighbor, localIp, remoteIp)
+		if err := ValidatePortQos(link.Qos); err != nil {
+			return fmt.Errorf("invalid qos for link %s-%s: %w", link.EndpointNodeA, link.EndpointNodeB, err)
+		}
+		vx.Qos = link.Qos
+		if err := ctr.tunnelPathCost(&vx, link.PathCost); err != nil {
+			return fmt.Errorf("invalid path cost for link %s-%s: %w", link.EndpointNodeA, link.EndpointNodeB, err)
+		}
 
-		vxs = append(vxs, plsv1.Vxlan{VxlanId: vxID, LocalIp: localIp, RemoteIp: remoteIp, UdpPort: plsv1.DEFAULT_VXLAN_PORT})
+		vxs = append(vxs, vx)
 
 	}
-	_, err := ctr.updateOvs(ovs.WithVxlans(vxs))
+	overlayMtu := ctr.setTunnelMtu(vxs)
+	vs, err := ctr.updateOvs(ovs.WithVxlans(vxs))
 
 	if err != nil {
-		return fmt.Errorf("could not update existing switch %s. Provided Vxlans: %s. Error:%s", ctr.switchName, vxs, err)
+		return fmt.Errorf("could not update existing switch %s. Provided Vxlans: %v. Error:%s", ctr.switchName, vxs, err)
 	} else {
-		fmt.Printf("Created topology %s.\n", vxs)
+		fmt.Printf("Created topology %v. Tunnels %s.\n", vxs, vs.TunnelChanges())
+	}
+	if err = ctr.applyPortMtu(overlayMtu); err != nil {
+		return fmt.Errorf("could not set the mtu of the ports: %v", err)
 	}
 	return nil
 
@@ -253,14 +670,31 @@ func (ctr *Controller) AddPorts(ports []plsv1.Port) error {
 	return err
 }
 
+// AddBonds creates the bonds of the switch, keyed by the name of their port, and reconciles the mode and members
+// of the ones that already exist. Other bonds are left as they are.
+func (ctr *Controller) AddBonds(bonds map[string]plsv1.Bond) error {
+	if len(bonds) == 0 {
+		return nil
+	}
+	ports := []plsv1.Port{}
+	for name := range bonds {
+		bond := bonds[name]
+		ports = append(ports, plsv1.Port{Name: name, Bond: &bond, Role: plsv1.PORT_ROLE_BOND})
+	}
+	_, err := ctr.updateOvs(ovs.WithPorts(ports))
+	return err
+}
+
 func (ctr *Controller) AddProbingPort(ip netip.Prefix, ifid dp.Ifid) error {
 	id := plsv1.RESERVED_PROBE_ID
 	ports := []plsv1.Port{
 		{
 			Name:      ifid.Probe(id),
 			Id:        &id,
+			Role:      plsv1.PORT_ROLE_PROBE,
 			Internal:  true,
 			IpAddress: &ip,
+			Mtu:       ctr.portMtu(),
 		},
 	}
 	_, err := ctr.updateOvs(
@@ -288,11 +722,37 @@ func (ctr *Controller) GetOrphanInterfaces(ifid dp.Ifid) ([]plsv1.Port, error) {
 			continue
 		}
 
-		id, _, _, parseErr := datapath.Parse(name)
+		id, typ, _, parseErr := datapath.Parse(name)
 		if parseErr != nil {
 			continue
 		}
-		ports = append(ports, plsv1.Port{Name: name, Id: &id})
+		port := plsv1.Port{Name: name, Id: &id, Mtu: ctr.portMtu(), Role: plsv1.PORT_ROLE_PROBE}
+		// the default traffic controls are for the ports of the pods, not for the probe
+		if typ == datapath.TypePort {
+			port.Qos = ctr.portQos
+			port.Role = plsv1.PORT_ROLE_PORT
+		}
+		ports = append(ports, port)
+	}
+
+	// the ports attached before talpa labelled them are labelled too, also the ones whose device is gone, so the
+	// reaper can tell them by their labels alone
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return ports, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	attached, err := vs.GetPorts()
+	if err != nil {
+		return ports, fmt.Errorf("could not get the ports of the switch: %v", err)
+	}
+	found := make(map[string]bool)
+	for _, port := range ports {
+		found[port.Name] = true
+	}
+	for _, port := range adoptedPorts(ifid, attached) {
+		if !found[port.Name] {
+			ports = append(ports, port)
+		}
 	}
 
 	return ports, nil
@@ -301,45 +761,185 @@ func (ctr *Controller) GetOrphanInterfaces(ifid dp.Ifid) ([]plsv1.Port, error) {
 // Wrapper for ovs.UpdateVirtualSwitch, including the default name and sudo option
 func (ctr *Controller) updateOvs(opts ...func(*ovs.BridgeConf)) (ovs.VirtualSwitch, error) {
 	allOpts := append([]func(*ovs.BridgeConf){
-		ovs.WithName(ctr.switchName), ovs.WithSudo(ctr.sudo),
+		ovs.WithName(ctr.switchName), ovs.WithSudo(ctr.sudo), ovs.WithOvsdb(ctr.ovsdb), ovs.WithBackend(ctr.backend), ovs.WithOwner(ctr.owner()),
 	}, opts...)
 
-	return ovs.UpdateVirtualSwitch(allOpts...)
+	start := time.Now()
+	vs, err := ovs.UpdateVirtualSwitch(allOpts...)
+	metrics.Observe("reconcile", "update", start, err)
+	return vs, err
 }
 
 // Wrapper for ovs.UpdateVirtualSwitch, including the default name and sudo option
 func (ctr *Controller) newOvs(opts ...func(*ovs.BridgeConf)) (ovs.VirtualSwitch, error) {
 	allOpts := append([]func(*ovs.BridgeConf){
-		ovs.WithName(ctr.switchName), ovs.WithSudo(ctr.sudo),
+		ovs.WithName(ctr.switchName), ovs.WithSudo(ctr.sudo), ovs.WithOvsdb(ctr.ovsdb), ovs.WithBackend(ctr.backend), ovs.WithOwner(ctr.owner()),
 	}, opts...)
 
-	return ovs.NewVirtualSwitch(allOpts...)
+	start := time.Now()
+	vs, err := ovs.NewVirtualSwitch(allOpts...)
+	metrics.Observe("reconcile", "create", start, err)
+	return vs, err
 }
 
 // Wrapper for ovs.UpdateVirtualSwitch, including the default name and sudo option
 func (ctr *Controller) getOvs() (ovs.VirtualSwitch, error) {
 
-	return ovs.GetVirtualSwitch(ovs.WithName(ctr.switchName), ovs.WithSudo(ctr.sudo))
+	return ovs.GetVirtualSwitch(ovs.WithName(ctr.switchName), ovs.WithSudo(ctr.sudo), ovs.WithOvsdb(ctr.ovsdb), ovs.WithBackend(ctr.backend), ovs.WithOwner(ctr.owner()))
+}
+
+// AttachInterface creates a new talpa port and plugs it both into the switch and into the linux bridge spsEndBridge.
+// The port gets the given traffic controls or, if qos is nil, the default ones of the controller.
+// The attachment is all or nothing: if any step fails, the steps already taken are undone before returning an *AttachError.
+func (ctr *Controller) AttachInterface(spsEndBridge string, qos *plsv1.PortQos) (plsv1.Port, error) {
+	ctr.mu.Lock()
+	defer ctr.mu.Unlock()
+	ifid := dp.NewIfId(ctr.switchName)
+
+	if qos == nil {
+		qos = ctr.portQos
+	}
+	if err := ValidatePortQos(qos); err != nil {
+		return plsv1.Port{}, &AttachError{Step: "validate qos", Err: err}
+	}
+
+	p, err := ctr.GetNewPort(ifid)
+	if err != nil {
+		return plsv1.Port{}, &AttachError{Step: "allocate port", Err: err}
+	}
+	p.Qos = qos
+	p.Mtu = ctr.portMtu()
+
+	if ctr.userspace() {
+		return ctr.attachInternalPort(p, spsEndBridge)
+	}
+
+	if err = ctr.CreatePort(p, spsEndBridge); err != nil {
+		// CreatePort already cleans up after itself
+		return plsv1.Port{}, &AttachError{Step: "create port", Err: err}
+	}
+
+	if err = ctr.AddPorts([]plsv1.Port{p}); err != nil {
+		attachErr := &AttachError{Step: "add port to switch", Err: err}
+		attachErr.RollbackErr = errors.Join(ctr.removeOvsPort(p.Name), ctr.DeletePort(p))
+		return plsv1.Port{}, attachErr
+	}
+
+	return p, nil
+}
+
+// AttachPatch links the switch to spsBridge, a bridge of the same OVS instance such as the SPS itself, with a pair
+// of patch ports instead of the veth and linux bridge of AttachInterface. Deleting the port of the switch deletes its
+// peer on spsBridge too. Both ports are created in the same transaction, so a failed attachment leaves nothing behind.
+func (ctr *Controller) AttachPatch(spsBridge string, qos *plsv1.PortQos) (plsv1.Port, error) {
+	ctr.mu.Lock()
+	defer ctr.mu.Unlock()
+	return ctr.attachPatchPort(spsBridge, qos)
+}
+
+// attachPatchPort is AttachPatch for callers that already hold the lock of the controller. Each port is named and
+// numbered as the next talpa port of its bridge, so the sps sees the link as one of its own ports. Patch ports never
+// reach the kernel, so they cannot have traffic controls: requesting some is an error, and the defaults are not applied.
+func (ctr *Controller) attachPatchPort(spsBridge string, qos *plsv1.PortQos) (plsv1.Port, error) {
+	if qos != nil {
+		return plsv1.Port{}, &AttachError{Step: "validate qos", Err: fmt.Errorf("%w: patch ports have no traffic controls", ErrInvalidQos)}
+	}
+	if spsBridge == ctr.switchName {
+		return plsv1.Port{}, &AttachError{Step: "allocate port", Err: fmt.Errorf("cannot patch %s to itself", ctr.switchName)}
+	}
+	sps, err := ovs.GetVirtualSwitch(ovs.WithName(spsBridge), ovs.WithSudo(ctr.sudo), ovs.WithOvsdb(ctr.ovsdb))
+	if err != nil {
+		return plsv1.Port{}, &AttachError{Step: "allocate port", Err: fmt.Errorf("could not get bridge %s: %v", spsBridge, err)}
+	}
+
+	p, err := ctr.GetNewPort(dp.NewIfId(ctr.switchName))
+	if err != nil {
+		return plsv1.Port{}, &AttachError{Step: "allocate port", Err: err}
+	}
+	spsId, err := sps.GetNewPortId()
+	if err != nil {
+		return plsv1.Port{}, &AttachError{Step: "allocate port", Err: fmt.Errorf("could not get a new port id of %s: %v", sps.GetName(), err)}
+	}
+	spsIfid := dp.NewIfId(sps.GetName())
+	peer := ovs.PatchPort{Bridge: sps.GetName(), Name: spsIfid.Port(spsId), Ofport: spsId}
+
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return plsv1.Port{}, &AttachError{Step: "add port to switch", Err: fmt.Errorf("could not get virtual switch: %v", err)}
+	}
+	// both ports are created in the same transaction, so there is nothing to undo if it fails
+	if err = vs.CreatePatch(p.Name, *p.Id, peer); err != nil {
+		return plsv1.Port{}, &AttachError{Step: "add port to switch", Err: err}
+	}
+
+	return p, nil
+}
+
+// attachInternalPort attaches the port on the userspace datapath, which reads veths through raw sockets instead of
+// forwarding them in the kernel. The port is an internal port of the switch instead, a tap device ovs-vswitchd creates,
+// and that device is the one plugged into the linux bridge. Removing the port from the switch removes the tap with it.
+func (ctr *Controller) attachInternalPort(p plsv1.Port, spsEndBridge string) (plsv1.Port, error) {
+	p.Internal = true
+	if err := ctr.AddPorts([]plsv1.Port{p}); err != nil {
+		attachErr := &AttachError{Step: "add port to switch", Err: err}
+		attachErr.RollbackErr = ctr.removeOvsPort(p.Name)
+		return plsv1.Port{}, attachErr
+	}
+
+	if err := linuxif.AddInterfaceToLinuxBridge(p.Name, spsEndBridge); err != nil {
+		attachErr := &AttachError{Step: "add port to linux bridge", Err: fmt.Errorf("failed to add %s to bridge %s: %w", p.Name, spsEndBridge, err)}
+		attachErr.RollbackErr = ctr.removeOvsPort(p.Name)
+		return plsv1.Port{}, attachErr
+	}
+
+	return p, nil
+}
+
+// removeOvsPort detaches the port from the switch if it is attached.
+func (ctr *Controller) removeOvsPort(portName string) error {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	return vs.DeletePort(portName)
 }
 
 // AddInterfaceToBridge creates a new veth pair, attaches one end to the specified bridge,
+// and removes the pair again if it cannot be attached, so no half created port is left behind.
 func (ctr *Controller) CreatePort(port plsv1.Port, spsEndBridge string) error {
 	var err error
 	// Generate unique interface names
 	peerName := datapath.GeneratePeerName(port)
 
 	// Create the veth pair
-	err = linuxif.AddVethPair(port.Name, peerName)
+	err = linuxif.AddVethPair(port.Name, peerName, port.Mtu)
 
 	if err != nil {
-		return fmt.Errorf("failed to create veth pair: %v", err)
+		return fmt.Errorf("failed to create veth pair: %w", err)
 	}
 
 	err = linuxif.AddInterfaceToLinuxBridge(peerName, spsEndBridge)
 
 	if err != nil {
-		return fmt.Errorf("failed to add %s to bridge %s: %v", peerName, spsEndBridge, err)
+		return errors.Join(fmt.Errorf("failed to add %s to bridge %s: %w", peerName, spsEndBridge, err), ctr.DeletePort(port))
 	}
 
 	return nil
 }
+
+// DeletePort undoes CreatePort: it releases the peer from its linux bridge and removes the veth pair.
+func (ctr *Controller) DeletePort(port plsv1.Port) error {
+	peerName := datapath.GeneratePeerName(port)
+
+	if err := linuxif.RemoveFromLinuxBridge(peerName); err != nil {
+		return fmt.Errorf("failed to release %s from its bridge: %v", peerName, err)
+	}
+	if err := linuxif.DeleteLink(port.Name); err != nil {
+		return fmt.Errorf("failed to delete veth pair: %v", err)
+	}
+	// the peer goes away with the veth, unless it was left over on its own
+	if err := linuxif.DeleteLink(peerName); err != nil {
+		return fmt.Errorf("failed to delete %s: %v", peerName, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/ovs"
+	"github.com/vishvananda/netlink"
+)
+
+// fakeBackend is a switch with no tunnels or controllers whose ports only live in memory. Transactions that add
+// ports fail once they are applied, as ovs-vsctl does when it cannot set up the interface of a port it added.
+type fakeBackend struct {
+	ovs.Backend
+	ports map[string]plsv1.Port
+}
+
+func (b *fakeBackend) BridgeExists(bridgeName string) bool { return true }
+func (b *fakeBackend) GetController(bridgeName string) ([]string, error) {
+	return nil, nil
+}
+func (b *fakeBackend) GetFailMode(bridgeName string) (string, error) { return "", nil }
+func (b *fakeBackend) GetVxlans(bridgeName string) (map[string]plsv1.Vxlan, error) {
+	return map[string]plsv1.Vxlan{}, nil
+}
+func (b *fakeBackend) GetNewPortID(bridgeName string) (int, error) { return 4000, nil }
+func (b *fakeBackend) GetPorts(bridgeName string) (map[string]plsv1.Port, error) {
+	ports := map[string]plsv1.Port{}
+	for name, port := range b.ports {
+		ports[name] = port
+	}
+	return ports, nil
+}
+func (b *fakeBackend) NewTxn(bridgeName string) ovs.Txn { return &fakeTxn{backend: b} }
+
+type fakeTxn struct {
+	ovs.Txn
+	backend *fakeBackend
+	added   []string
+	deleted []string
+}
+
+func (t *fakeTxn) AddPort(portName string, netIndex int, internal bool) {
+	t.added = append(t.added, portName)
+}
+func (t *fakeTxn) SetExternalIds(table, record string, externalIds map[string]string) {}
+func (t *fakeTxn) SetMtu(interfaceName string, mtu int)                               {}
+func (t *fakeTxn) SetPortQos(portName string, qos *plsv1.PortQos)                     {}
+func (t *fakeTxn) DeletePort(portName string)                                         { t.deleted = append(t.deleted, portName) }
+func (t *fakeTxn) Commit() error {
+	for _, name := range t.deleted {
+		delete(t.backend.ports, name)
+	}
+	for _, name := range t.added {
+		t.backend.ports[name] = plsv1.Port{Name: name}
+	}
+	if len(t.added) > 0 {
+		return errors.New("could not set up the interface")
+	}
+	return nil
+}
+
+func TestAttachPatchPort(t *testing.T) {
+	ctr := &Controller{switchName: "br0"}
+
+	// patch ports never reach the kernel, so they cannot have traffic controls
+	_, err := ctr.attachPatchPort("sps", &plsv1.PortQos{IngressPolicingRate: 1000})
+	var attachErr *AttachError
+	if !errors.As(err, &attachErr) || attachErr.Step != "validate qos" || !errors.Is(err, ErrInvalidQos) {
+		t.Errorf("expected an invalid qos error, got: %v", err)
+	}
+
+	if _, err = ctr.attachPatchPort("br0", nil); !errors.As(err, &attachErr) || attachErr.Step != "allocate port" {
+		t.Errorf("expected an error patching the switch to itself, got: %v", err)
+	}
+}
+
+func TestAttachInterfaceRollback(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating veth pairs and linux bridges requires root")
+	}
+	spsBridge := "talpatestbr"
+	if err := netlink.LinkAdd(&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: spsBridge}}); err != nil {
+		t.Fatalf("could not create bridge %s: %v", spsBridge, err)
+	}
+	t.Cleanup(func() { linuxif.DeleteLink(spsBridge) })
+
+	backend := &fakeBackend{ports: map[string]plsv1.Port{}}
+	ctr := &Controller{switchName: "br0", backend: backend}
+	id := 4000
+	ifid := datapath.NewIfId("br0")
+	port := plsv1.Port{Name: ifid.Port(id), Id: &id}
+	peer := datapath.GeneratePeerName(port)
+	t.Cleanup(func() { ctr.DeletePort(port) })
+
+	// the port makes it into the switch before the attachment fails, so it has to be removed again
+	_, err := ctr.AttachInterface(spsBridge, nil)
+	var attachErr *AttachError
+	if !errors.As(err, &attachErr) || attachErr.Step != "add port to switch" {
+		t.Fatalf("expected an error adding the port to the switch, got: %v", err)
+	}
+	if attachErr.RollbackErr != nil {
+		t.Errorf("expected the attachment to be rolled back, got: %v", attachErr.RollbackErr)
+	}
+	if _, ok := backend.ports[port.Name]; ok {
+		t.Errorf("expected port %s to be removed from the switch", port.Name)
+	}
+	if linuxif.Exists(port.Name) || linuxif.Exists(peer) {
+		t.Errorf("expected the veth pair %s and %s to be deleted", port.Name, peer)
+	}
+	bridge, err := linuxif.GetLinkState(spsBridge)
+	if err != nil {
+		t.Fatalf("could not get bridge %s: %v", spsBridge, err)
+	}
+	links, err := linuxif.ListLinks()
+	if err != nil {
+		t.Fatalf("could not list links: %v", err)
+	}
+	for name, link := range links {
+		if link.MasterIndex == bridge.Index {
+			t.Errorf("expected bridge %s to have no members, found %s", spsBridge, name)
+		}
+	}
+}
+
+func TestNewTunnel(t *testing.T) {
+	for tunnelType, udpPort := range map[string]string{"": plsv1.DEFAULT_VXLAN_PORT, plsv1.TUNNEL_GENEVE: "", plsv1.TUNNEL_STT: "", plsv1.TUNNEL_GRE: ""} {
+		ctr := &Controller{switchName: "br0", tunnelType: tunnelType}
+		vx, err := ctr.newTunnel("node-b", "node-b", "10.0.0.1", "10.0.0.2")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if vx.UdpPort != udpPort {
+			t.Errorf("expected udp port %q for a %s tunnel, got: %q", udpPort, vx.Type, vx.UdpPort)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	dp "github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
+)
+
+// Kinds of the resources the reaper removes.
+const (
+	ORPHAN_OVS_PORT = "ovs-port"
+	ORPHAN_VETH     = "veth"
+	ORPHAN_PEER     = "peer"
+)
+
+// Orphan is a resource talpa created that nothing uses anymore, and what the reaper did with it.
+type Orphan struct {
+	Kind   string
+	Name   string
+	Reason string
+	// Removed is set once the resource is deleted, Err if deleting it failed
+	Removed bool
+	Err     error
+}
+
+func (o Orphan) String() string {
+	return fmt.Sprintf("%s %s (%s)", o.Kind, o.Name, o.Reason)
+}
+
+// SetReaper sets how often the reaper runs and what it does with the orphaned resources it finds. nil only reports
+// them on demand.
+func (ctr *Controller) SetReaper(reaper *plsv1.Reaper) error {
+	ctr.reaper = plsv1.Reaper{}
+	if reaper == nil {
+		return nil
+	}
+	if reaper.Policy != "" && !plsv1.IsReaperPolicy(reaper.Policy) {
+		return fmt.Errorf("unsupported reaper policy %s", reaper.Policy)
+	}
+	if reaper.Interval < 0 {
+		return fmt.Errorf("reaper interval must not be negative")
+	}
+	ctr.reaper = *reaper
+	return nil
+}
+
+// StartReaper runs the reaper every interval of its settings, until ctx is done. Nothing is started without one.
+func (ctr *Controller) StartReaper(ctx context.Context) {
+	if ctr.reaper.Interval == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(ctr.reaper.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := ctr.ReapOrphans(false); err != nil {
+					log.Printf("reaper of %s: %v", ctr.switchName, err)
+				}
+			}
+		}
+	}()
+}
+
+// ReapOrphans finds the resources talpa left behind and, if the reaper policy is remove and dryRun is not set,
+// deletes them. Every orphan found is logged, and returned with the outcome of its removal.
+func (ctr *Controller) ReapOrphans(dryRun bool) ([]Orphan, error) {
+	ctr.mu.Lock()
+	defer ctr.mu.Unlock()
+	return ctr.reapOrphans(!dryRun && ctr.reaper.Policy == plsv1.REAPER_POLICY_REMOVE)
+}
+
+// reapOrphans is ReapOrphans for callers that already hold the lock of the controller.
+func (ctr *Controller) reapOrphans(remove bool) ([]Orphan, error) {
+	vs, err := ctr.getOvs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get virtual switch: %v", err)
+	}
+	ports, err := vs.GetPorts()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the ports of the switch: %v", err)
+	}
+	links, err := linuxif.ListLinks()
+	if err != nil {
+		return nil, fmt.Errorf("could not list interfaces: %v", err)
+	}
+
+	attached := make(map[string]bool)
+	for _, port := range ports {
+		attached[port.Name] = true
+	}
+	orphans := findOrphans(dp.NewIfId(ctr.switchName), ports, links)
+	for i := range orphans {
+		orphan := &orphans[i]
+		if !remove {
+			log.Printf("reaper of %s: found orphaned %s", ctr.switchName, orphan)
+			continue
+		}
+		switch orphan.Kind {
+		case ORPHAN_OVS_PORT:
+			orphan.Err = vs.DeletePort(orphan.Name)
+		case ORPHAN_VETH:
+			// the port of the veth goes first, so the switch is not left with a port without device
+			if attached[orphan.Name] {
+				orphan.Err = vs.DeletePort(orphan.Name)
+			}
+			if orphan.Err == nil {
+				orphan.Err = linuxif.DeleteLink(orphan.Name)
+			}
+		case ORPHAN_PEER:
+			orphan.Err = linuxif.DeleteLink(orphan.Name)
+		}
+		if orphan.Err != nil {
+			log.Printf("reaper of %s: could not remove orphaned %s: %v", ctr.switchName, orphan, orphan.Err)
+			continue
+		}
+		orphan.Removed = true
+		log.Printf("reaper of %s: removed orphaned %s", ctr.switchName, orphan)
+	}
+	return orphans, nil
+}
+
+// findOrphans returns the resources of the switch with the given ifid that nothing uses anymore, given the ports of
+// the switch and the interfaces of the namespace:
+//   - talpa ports of the switch whose device is gone, told by their labels. Internal and patch ports have their device
+//     made by OVS itself
+//   - veths of the switch whose peer is gone or not enslaved to a bridge
+//   - peers that are not veths anymore, so their veth is gone
+//
+// Peers are not named after the switch, so the ones of the veths of other switches are only reaped once their veth is gone.
+// A peer that is still a veth is left alone even if its other end is not in the namespace, as it may be in another one.
+func findOrphans(ifid dp.Ifid, ports []plsv1.Port, links map[string]linuxif.LinkState) []Orphan {
+	orphans := []Orphan{}
+	for _, port := range ports {
+		if !talpaPort(port) {
+			continue
+		}
+		if port.State != nil && port.State.Type != "" && port.State.Type != "system" {
+			continue
+		}
+		if _, ok := links[port.Name]; !ok {
+			orphans = append(orphans, Orphan{Kind: ORPHAN_OVS_PORT, Name: port.Name, Reason: "its device is gone"})
+		}
+	}
+
+	names := make([]string, 0, len(links))
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		link := links[name]
+		id, typ, _, err := dp.Parse(name)
+		if err != nil {
+			continue
+		}
+		switch {
+		case typ == dp.TypePort && ifid.IsManaged(name) && link.Type == "veth":
+			peerName := dp.GeneratePeerName(plsv1.Port{Name: name, Id: &id})
+			peer, ok := links[peerName]
+			switch {
+			case !ok:
+				orphans = append(orphans, Orphan{Kind: ORPHAN_VETH, Name: name, Reason: fmt.Sprintf("its peer %s is gone", peerName)})
+			case peer.MasterIndex == 0:
+				orphans = append(orphans, Orphan{Kind: ORPHAN_VETH, Name: name, Reason: fmt.Sprintf("its peer %s is not in a bridge", peerName)})
+			}
+		case typ == dp.TypePeer && link.Type != "veth":
+			orphans = append(orphans, Orphan{Kind: ORPHAN_PEER, Name: name, Reason: "its veth is gone"})
+		}
+	}
+	return orphans
+}
+
+// talpaPort reports whether the port is one talpa attached to the switch for a pod or the probe. Ports attached before
+// talpa labelled them are labelled when they are adopted, see adoptedPorts.
+func talpaPort(port plsv1.Port) bool {
+	return port.Role == plsv1.PORT_ROLE_PORT || port.Role == plsv1.PORT_ROLE_PROBE
+}
+
+// adoptedPorts returns the ports of the switch with the given ifid that talpa attached before it labelled them, told by
+// their name, with the role they are labelled with. Ports of other tools and of other switches are left out.
+func adoptedPorts(ifid dp.Ifid, ports []plsv1.Port) []plsv1.Port {
+	adopted := []plsv1.Port{}
+	for _, port := range ports {
+		if port.Role != "" || !ifid.IsManaged(port.Name) {
+			continue
+		}
+		_, typ, _, err := dp.Parse(port.Name)
+		if err != nil {
+			continue
+		}
+		switch typ {
+		case dp.TypePort:
+			port.Role = plsv1.PORT_ROLE_PORT
+		case dp.TypeProbe:
+			port.Role = plsv1.PORT_ROLE_PROBE
+		default:
+			continue
+		}
+		adopted = append(adopted, port)
+	}
+	return adopted
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	dp "github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
+)
+
+func TestFindOrphans(t *testing.T) {
+	ifid := dp.NewIfId("br0")
+	other := dp.NewIfId("br1")
+	port := func(name, role, typ string) plsv1.Port {
+		return plsv1.Port{Name: name, Role: role, State: &plsv1.PortState{Type: typ}}
+	}
+	ports := []plsv1.Port{
+		port(ifid.Port(1), plsv1.PORT_ROLE_PORT, ""),
+		// its device is gone
+		port(ifid.Port(2), plsv1.PORT_ROLE_PORT, ""),
+		// attached before the labels and not adopted yet, so not known to be talpa's
+		port(ifid.Port(3), "", "system"),
+		// patch and internal ports have no device of their own in the namespace
+		port(ifid.Port(4), plsv1.PORT_ROLE_PORT, "patch"),
+		port(ifid.Probe(plsv1.RESERVED_PROBE_ID), plsv1.PORT_ROLE_PROBE, "internal"),
+		// tunnels and ports of other tools are not talpa's
+		port("vxlan-abc", plsv1.PORT_ROLE_TUNNEL, "vxlan"),
+		port("eth1", "", ""),
+	}
+	links := map[string]linuxif.LinkState{
+		"br10": {Index: 10, Type: "bridge"},
+		// in use: the veth of port 1, with its peer in the bridge
+		ifid.Port(1): {Index: 11, Type: "veth", PeerIfindex: 12},
+		"lspeer1":    {Index: 12, Type: "veth", PeerIfindex: 11, MasterIndex: 10},
+		// the peer of port 5 is not in a bridge
+		ifid.Port(5): {Index: 15, Type: "veth", PeerIfindex: 16},
+		"lspeer5":    {Index: 16, Type: "veth", PeerIfindex: 15},
+		// the peer of port 6 is gone
+		ifid.Port(6): {Index: 17, Type: "veth", PeerIfindex: 30},
+		// a peer without its veth
+		"lspeer7": {Index: 18, Type: "dummy"},
+		// the veth of another switch, whose peer is not in a bridge, is left to it
+		other.Port(8): {Index: 19, Type: "veth", PeerIfindex: 20},
+		"lspeer8":     {Index: 20, Type: "veth", PeerIfindex: 19},
+		// the other end of lspeer9 may be in another namespace
+		"lspeer9": {Index: 21, Type: "veth", PeerIfindex: 40},
+	}
+
+	expected := []Orphan{
+		{Kind: ORPHAN_OVS_PORT, Name: ifid.Port(2), Reason: "its device is gone"},
+		{Kind: ORPHAN_VETH, Name: ifid.Port(5), Reason: "its peer lspeer5 is not in a bridge"},
+		{Kind: ORPHAN_VETH, Name: ifid.Port(6), Reason: "its peer lspeer6 is gone"},
+		{Kind: ORPHAN_PEER, Name: "lspeer7", Reason: "its veth is gone"},
+	}
+	if orphans := findOrphans(ifid, ports, links); !reflect.DeepEqual(orphans, expected) {
+		t.Errorf("unexpected orphans:\n got: %v\nwant: %v", orphans, expected)
+	}
+}
+
+func TestAdoptedPorts(t *testing.T) {
+	ifid := dp.NewIfId("br0")
+	other := dp.NewIfId("br1")
+	ports := []plsv1.Port{
+		{Name: ifid.Port(1)},
+		{Name: ifid.Probe(plsv1.RESERVED_PROBE_ID)},
+		// already labelled
+		{Name: ifid.Port(2), Role: plsv1.PORT_ROLE_PORT},
+		// ports of other switches and of other tools
+		{Name: other.Port(3)},
+		{Name: "eth1"},
+	}
+
+	expected := []plsv1.Port{
+		{Name: ifid.Port(1), Role: plsv1.PORT_ROLE_PORT},
+		{Name: ifid.Probe(plsv1.RESERVED_PROBE_ID), Role: plsv1.PORT_ROLE_PROBE},
+	}
+	if adopted := adoptedPorts(ifid, ports); !reflect.DeepEqual(adopted, expected) {
+		t.Errorf("unexpected adopted ports:\n got: %v\nwant: %v", adopted, expected)
+	}
+}
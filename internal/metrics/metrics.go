@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/ovs"
+)
+
+const NAMESPACE = "talpa"
+
+// Source is where the switch metrics are read from on every scrape. It is implemented by controller.Controller.
+type Source interface {
+	GetSwitchName() string
+	// GetSwitch reads the switch once per scrape, and every section of its state is taken from it
+	GetSwitch() (Switch, error)
+}
+
+// Switch is the state of the switch a scrape exports. It is implemented by *ovs.VirtualSwitch.
+type Switch interface {
+	GetPorts() ([]plsv1.Port, error)
+	GetVxlans() ([]plsv1.Vxlan, error)
+	GetControllerStatus() ([]ovs.ControllerStatus, error)
+}
+
+// interfaceCounters are the Interface statistics exported for ports and tunnels.
+var interfaceCounters = []string{
+	"rx_bytes", "tx_bytes", "rx_packets", "tx_packets", "rx_dropped", "tx_dropped", "rx_errors", "tx_errors",
+}
+
+var (
+	operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "operations_total",
+		Help:      "Operations run by talpa, by kind (grpc, command, reconcile), name and result.",
+	}, []string{"kind", "operation", "result"})
+
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the operations run by talpa, by kind and name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind", "operation"})
+)
+
+// Observe records an operation of the given kind that started at start and ended with err.
+func Observe(kind, operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	operations.WithLabelValues(kind, operation, result).Inc()
+	operationDuration.WithLabelValues(kind, operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCommand is an ovs.Observer that records every ovs-vsctl, ovs-ofctl, ip or ovsdb-server call.
+func ObserveCommand(command string, duration time.Duration, err error) {
+	Observe("command", command, time.Now().Add(-duration), err)
+}
+
+// UnaryServerInterceptor records every gRPC call.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	Observe("grpc", info.FullMethod, start, err)
+	return resp, err
+}
+
+// switchCollector reads the state of the switch from its source on every scrape.
+type switchCollector struct {
+	source Source
+
+	controllerConnected *prometheus.Desc
+	bondMemberUp        *prometheus.Desc
+	bondMemberActive    *prometheus.Desc
+	scrapeErrors        *prometheus.Desc
+	portCounters        map[string]*prometheus.Desc
+	tunnelCounters      map[string]*prometheus.Desc
+}
+
+func newSwitchCollector(source Source) *switchCollector {
+	c := &switchCollector{
+		source: source,
+		controllerConnected: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "controller", "connected"),
+			"Whether the switch is connected to the controller (1) or not (0).", []string{"bridge", "target", "role"}, nil),
+		bondMemberUp: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "bond", "member_up"),
+			"Whether the link of the bond member is up (1) or not (0).", []string{"bridge", "bond", "member"}, nil),
+		bondMemberActive: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "bond", "member_active"),
+			"Whether the bond sends its traffic through the member (1) or not (0), in active-backup mode.", []string{"bridge", "bond", "member"}, nil),
+		scrapeErrors: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "scrape", "errors"),
+			"Number of sections of the switch state that could not be read in this scrape.", []string{"bridge"}, nil),
+		portCounters:   make(map[string]*prometheus.Desc),
+		tunnelCounters: make(map[string]*prometheus.Desc),
+	}
+	for _, counter := range interfaceCounters {
+		c.portCounters[counter] = prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "port", counter+"_total"),
+			"Interface "+counter+" statistic of the switch ports.", []string{"bridge", "port", "ofport"}, nil)
+		c.tunnelCounters[counter] = prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "tunnel", counter+"_total"),
+			"Interface "+counter+" statistic of the switch tunnels.", []string{"bridge", "tunnel", "type", "remote_ip"}, nil)
+	}
+	return c
+}
+
+func (c *switchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.controllerConnected
+	ch <- c.bondMemberUp
+	ch <- c.bondMemberActive
+	ch <- c.scrapeErrors
+	for _, counter := range interfaceCounters {
+		ch <- c.portCounters[counter]
+		ch <- c.tunnelCounters[counter]
+	}
+}
+
+func (c *switchCollector) Collect(ch chan<- prometheus.Metric) {
+	bridge := c.source.GetSwitchName()
+	errors := 0
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(c.scrapeErrors, prometheus.GaugeValue, float64(errors), bridge)
+	}()
+
+	vs, err := c.source.GetSwitch()
+	if err != nil {
+		log.Printf("metrics: could not get the switch: %v", err)
+		errors++
+		return
+	}
+
+	ports, err := vs.GetPorts()
+	if err != nil {
+		log.Printf("metrics: could not get ports: %v", err)
+		errors++
+	}
+	for _, port := range ports {
+		if port.Bond != nil {
+			for _, member := range port.Bond.Status {
+				ch <- prometheus.MustNewConstMetric(c.bondMemberUp, prometheus.GaugeValue, gauge(member.LinkState == "up"), bridge, port.Name, member.Name)
+				ch <- prometheus.MustNewConstMetric(c.bondMemberActive, prometheus.GaugeValue, gauge(member.Active), bridge, port.Name, member.Name)
+			}
+		}
+		// tunnels are exported on their own, with their remote endpoint
+		if port.State == nil || plsv1.IsTunnelType(port.State.Type) {
+			continue
+		}
+		c.collectCounters(ch, c.portCounters, port.State.Statistics, bridge, port.Name, ofportLabel(port.State.Ofport))
+	}
+
+	tunnels, err := vs.GetVxlans()
+	if err != nil {
+		log.Printf("metrics: could not get tunnels: %v", err)
+		errors++
+	}
+	for _, tunnel := range tunnels {
+		if tunnel.Status == nil {
+			continue
+		}
+		c.collectCounters(ch, c.tunnelCounters, tunnel.Status.Statistics, bridge, tunnel.VxlanId, tunnel.Type, tunnel.RemoteIp)
+	}
+
+	statuses, err := vs.GetControllerStatus()
+	if err != nil {
+		log.Printf("metrics: could not get controller status: %v", err)
+		errors++
+	}
+	for _, status := range statuses {
+		ch <- prometheus.MustNewConstMetric(c.controllerConnected, prometheus.GaugeValue, gauge(status.IsConnected), bridge, status.Target, status.Role)
+	}
+}
+
+func (c *switchCollector) collectCounters(ch chan<- prometheus.Metric, descs map[string]*prometheus.Desc, statistics map[string]int64, labels ...string) {
+	keys := make([]string, 0, len(statistics))
+	for k := range statistics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if desc, ok := descs[k]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(statistics[k]), labels...)
+		}
+	}
+}
+
+// gauge returns the value of a boolean gauge, 1 for true.
+func gauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func ofportLabel(ofport int64) string {
+	if ofport <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(ofport, 10)
+}
+
+// NewRegistry returns a registry with talpa's operation metrics, the switch metrics read from source
+// and the standard go and process collectors.
+func NewRegistry(source Source) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		operations,
+		operationDuration,
+		newSwitchCollector(source),
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// StartMetricsServer serves the metrics of source on address, under /metrics, until ctx is done.
+// Commands run by pkg/ovs are recorded from then on.
+func StartMetricsServer(ctx context.Context, address string, source Source) {
+	ovs.SetObserver(ObserveCommand)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(NewRegistry(source), promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: address, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		log.Printf("metrics server listening on %s", address)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("metrics server failed: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/ovs"
+)
+
+// fakeSource is both the source and the switch it reads. switchErr fails reading the switch, err each of its sections.
+type fakeSource struct {
+	ports     []plsv1.Port
+	tunnels   []plsv1.Vxlan
+	statuses  []ovs.ControllerStatus
+	err       error
+	switchErr error
+	// reads counts the times the switch is read
+	reads int
+}
+
+func (s *fakeSource) GetSwitchName() string { return "br0" }
+func (s *fakeSource) GetSwitch() (Switch, error) {
+	s.reads++
+	return s, s.switchErr
+}
+func (s *fakeSource) GetPorts() ([]plsv1.Port, error)   { return s.ports, s.err }
+func (s *fakeSource) GetVxlans() ([]plsv1.Vxlan, error) { return s.tunnels, s.err }
+func (s *fakeSource) GetControllerStatus() ([]ovs.ControllerStatus, error) {
+	return s.statuses, s.err
+}
+
+func TestSwitchCollector(t *testing.T) {
+	source := &fakeSource{
+		ports: []plsv1.Port{
+			{Name: "br0p1", State: &plsv1.PortState{Ofport: 1, Type: "", Statistics: map[string]int64{"rx_bytes": 100, "tx_bytes": 200, "collisions": 0}}},
+			// tunnels are only reported as tunnels
+			{Name: "vxlan-a", State: &plsv1.PortState{Ofport: 2, Type: plsv1.TUNNEL_VXLAN, Statistics: map[string]int64{"rx_bytes": 1}}},
+			{Name: "bond0", Bond: &plsv1.Bond{Status: []plsv1.BondMemberStatus{
+				{Name: "eth1", LinkState: "up", Active: true}, {Name: "eth2", LinkState: "down"},
+			}}},
+		},
+		tunnels: []plsv1.Vxlan{
+			{VxlanId: "vxlan-a", Type: plsv1.TUNNEL_VXLAN, RemoteIp: "10.0.0.2", Status: &plsv1.TunnelStatus{Statistics: map[string]int64{"tx_packets": 7}}},
+		},
+		statuses: []ovs.ControllerStatus{{Target: "tcp:10.0.0.1:6633", IsConnected: true, Role: "master"}},
+	}
+
+	expected := `
+# HELP talpa_bond_member_active Whether the bond sends its traffic through the member (1) or not (0), in active-backup mode.
+# TYPE talpa_bond_member_active gauge
+talpa_bond_member_active{bond="bond0",bridge="br0",member="eth1"} 1
+talpa_bond_member_active{bond="bond0",bridge="br0",member="eth2"} 0
+# HELP talpa_bond_member_up Whether the link of the bond member is up (1) or not (0).
+# TYPE talpa_bond_member_up gauge
+talpa_bond_member_up{bond="bond0",bridge="br0",member="eth1"} 1
+talpa_bond_member_up{bond="bond0",bridge="br0",member="eth2"} 0
+# HELP talpa_controller_connected Whether the switch is connected to the controller (1) or not (0).
+# TYPE talpa_controller_connected gauge
+talpa_controller_connected{bridge="br0",role="master",target="tcp:10.0.0.1:6633"} 1
+# HELP talpa_port_rx_bytes_total Interface rx_bytes statistic of the switch ports.
+# TYPE talpa_port_rx_bytes_total counter
+talpa_port_rx_bytes_total{bridge="br0",ofport="1",port="br0p1"} 100
+# HELP talpa_port_tx_bytes_total Interface tx_bytes statistic of the switch ports.
+# TYPE talpa_port_tx_bytes_total counter
+talpa_port_tx_bytes_total{bridge="br0",ofport="1",port="br0p1"} 200
+# HELP talpa_scrape_errors Number of sections of the switch state that could not be read in this scrape.
+# TYPE talpa_scrape_errors gauge
+talpa_scrape_errors{bridge="br0"} 0
+# HELP talpa_tunnel_tx_packets_total Interface tx_packets statistic of the switch tunnels.
+# TYPE talpa_tunnel_tx_packets_total counter
+talpa_tunnel_tx_packets_total{bridge="br0",remote_ip="10.0.0.2",tunnel="vxlan-a",type="vxlan"} 7
+`
+	if err := testutil.CollectAndCompare(newSwitchCollector(source), strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected metrics: %v", err)
+	}
+	if source.reads != 1 {
+		t.Errorf("expected the switch to be read once per scrape, got: %d", source.reads)
+	}
+}
+
+func TestSwitchCollectorErrors(t *testing.T) {
+	source := &fakeSource{err: errors.New("ovsdb is down")}
+
+	expected := `
+# HELP talpa_scrape_errors Number of sections of the switch state that could not be read in this scrape.
+# TYPE talpa_scrape_errors gauge
+talpa_scrape_errors{bridge="br0"} 3
+`
+	if err := testutil.CollectAndCompare(newSwitchCollector(source), strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected metrics: %v", err)
+	}
+
+	source = &fakeSource{switchErr: errors.New("no bridge br0")}
+	expected = `
+# HELP talpa_scrape_errors Number of sections of the switch state that could not be read in this scrape.
+# TYPE talpa_scrape_errors gauge
+talpa_scrape_errors{bridge="br0"} 1
+`
+	if err := testutil.CollectAndCompare(newSwitchCollector(source), strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected metrics: %v", err)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	Observe("reconcile", "test", time.Now(), nil)
+	Observe("reconcile", "test", time.Now(), errors.New("failed"))
+	ObserveCommand("test-command", time.Millisecond, nil)
+
+	if n := testutil.ToFloat64(operations.WithLabelValues("reconcile", "test", "success")); n != 1 {
+		t.Errorf("expected 1 successful operation, got: %v", n)
+	}
+	if n := testutil.ToFloat64(operations.WithLabelValues("reconcile", "test", "failure")); n != 1 {
+		t.Errorf("expected 1 failed operation, got: %v", n)
+	}
+	if n := testutil.ToFloat64(operations.WithLabelValues("command", "test-command", "success")); n != 1 {
+		t.Errorf("expected 1 command, got: %v", n)
+	}
+	if n := testutil.CollectAndCount(operationDuration, "talpa_operation_duration_seconds"); n != 2 {
+		t.Errorf("expected 2 duration histograms, got: %d", n)
+	}
+}
@@ -2,19 +2,25 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	// Adjust the import path based on your module path
 
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
 	"github.com/Networks-it-uc3m/l2sm-switch/internal/controller"
+	"github.com/Networks-it-uc3m/l2sm-switch/internal/metrics"
 
-	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
-	dp "github.com/Networks-it-uc3m/l2sm-switch/pkg/datapath"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
 	"github.com/Networks-it-uc3m/l2sm-switch/pkg/nedpb"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/ovs"
 )
 
 // server is used to implement nedpb.VxlanServiceServer
@@ -32,7 +38,7 @@ func StartGrpcServer(port string, ctr *controller.Controller) {
 	}
 
 	// Create a gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(metrics.UnaryServerInterceptor))
 
 	// Register the server
 	nedpb.RegisterNedServiceServer(grpcServer, &server{Ctr: ctr})
@@ -64,24 +70,25 @@ func (s *server) CreateVxlan(ctx context.Context, req *nedpb.CreateVxlanRequest)
 // AttachInterface implements nedpb.VxlanServiceServer
 func (s *server) AttachInterface(ctx context.Context, req *nedpb.AttachInterfaceRequest) (*nedpb.AttachInterfaceResponse, error) {
 
-	ifid := dp.NewIfId(s.Ctr.GetSwitchName())
-
-	p, err := s.Ctr.GetNewPort(ifid)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate a new port name. error: %v", err)
-	}
 	// sps end bridge is the end bridge that belongs to sps, the one that will connect l2sm with the ned. the end path will be something like this:
 	// nedSwitchName -> ifid -> ifidPeer -> spsEndBridge -> spsIfIdPeer -> spsIfid -> spsSwitchName
 	// complicated, i know, but when i made it i didnt have much time to do a proper implementation :/
 	// if anyone is reading this and is willing to make it right, a feasible path would be: remove bridge spsEndBridge dependency, (by removing multus dependency)
 	// and then moving the sps to the host namespace, so the integration is much more fluent, as this induces a lot of jargon
+	// when the sps runs in the same ovs instance, patch lets interface_name name its bridge instead, and both are linked with patch ports:
+	// nedSwitchName -> ifid -> spsIfid -> spsSwitchName
 	spsEndBridge := req.GetInterfaceName()
-	s.Ctr.CreatePort(p, spsEndBridge)
-	//todo: new port must be created!!!
-	err = s.Ctr.AddPorts([]plsv1.Port{p})
+	if spsEndBridge == "" {
+		return nil, status.Error(codes.InvalidArgument, "interface_name must be set")
+	}
 
+	attach := s.Ctr.AttachInterface
+	if req.GetPatch() {
+		attach = s.Ctr.AttachPatch
+	}
+	p, err := attach(spsEndBridge, portQos(req.GetQos()))
 	if err != nil {
-		return nil, fmt.Errorf("failed to add interface to bridge: %v", err)
+		return nil, attachStatus(err)
 	}
 
 	return &nedpb.AttachInterfaceResponse{
@@ -89,3 +96,174 @@ func (s *server) AttachInterface(ctx context.Context, req *nedpb.AttachInterface
 		NodeName:     s.Ctr.GetNodeName(),
 	}, nil
 }
+
+// GetNodeName implements nedpb.NedServiceServer
+func (s *server) GetNodeName(ctx context.Context, req *nedpb.GetNodeNameRequest) (*nedpb.GetNodeNameResponse, error) {
+	return &nedpb.GetNodeNameResponse{NodeName: s.Ctr.GetNodeName()}, nil
+}
+
+// GetControllerStatus implements nedpb.NedServiceServer
+func (s *server) GetControllerStatus(ctx context.Context, req *nedpb.GetControllerStatusRequest) (*nedpb.GetControllerStatusResponse, error) {
+	statuses, err := s.Ctr.GetControllerStatus()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to get controller status: %v", err)
+	}
+
+	resp := &nedpb.GetControllerStatusResponse{}
+	for _, st := range statuses {
+		resp.Controllers = append(resp.Controllers, &nedpb.ControllerStatus{
+			Target:      st.Target,
+			IsConnected: st.IsConnected,
+			Role:        st.Role,
+			Status:      st.Status,
+			LastError:   st.LastError,
+		})
+	}
+	return resp, nil
+}
+
+// CreateMirror implements nedpb.NedServiceServer
+func (s *server) CreateMirror(ctx context.Context, req *nedpb.CreateMirrorRequest) (*nedpb.CreateMirrorResponse, error) {
+	if req.GetMirror() == nil {
+		return nil, status.Error(codes.InvalidArgument, "mirror must be set")
+	}
+	if req.GetTtlSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl_seconds must not be negative")
+	}
+
+	mirror, err := s.Ctr.CreateMirror(mirrorFromPb(req.GetMirror()), time.Duration(req.GetTtlSeconds())*time.Second)
+	if err != nil {
+		return nil, mirrorStatus(err)
+	}
+	return &nedpb.CreateMirrorResponse{Mirror: mirrorToPb(mirror)}, nil
+}
+
+// DeleteMirror implements nedpb.NedServiceServer
+func (s *server) DeleteMirror(ctx context.Context, req *nedpb.DeleteMirrorRequest) (*nedpb.DeleteMirrorResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name must be set")
+	}
+	if err := s.Ctr.DeleteMirror(req.GetName()); err != nil {
+		return nil, mirrorStatus(err)
+	}
+	return &nedpb.DeleteMirrorResponse{}, nil
+}
+
+// ListMirrors implements nedpb.NedServiceServer
+func (s *server) ListMirrors(ctx context.Context, req *nedpb.ListMirrorsRequest) (*nedpb.ListMirrorsResponse, error) {
+	mirrors, err := s.Ctr.GetMirrors()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to list mirrors: %v", err)
+	}
+	resp := &nedpb.ListMirrorsResponse{}
+	for _, mirror := range mirrors {
+		resp.Mirrors = append(resp.Mirrors, mirrorToPb(mirror))
+	}
+	return resp, nil
+}
+
+// ReapOrphans implements nedpb.NedServiceServer
+func (s *server) ReapOrphans(ctx context.Context, req *nedpb.ReapOrphansRequest) (*nedpb.ReapOrphansResponse, error) {
+	orphans, err := s.Ctr.ReapOrphans(req.GetDryRun())
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to reap orphans: %v", err)
+	}
+	resp := &nedpb.ReapOrphansResponse{}
+	for _, orphan := range orphans {
+		o := &nedpb.Orphan{
+			Kind:    orphan.Kind,
+			Name:    orphan.Name,
+			Reason:  orphan.Reason,
+			Removed: orphan.Removed,
+		}
+		if orphan.Err != nil {
+			o.Error = orphan.Err.Error()
+		}
+		resp.Orphans = append(resp.Orphans, o)
+	}
+	return resp, nil
+}
+
+func mirrorFromPb(mirror *nedpb.Mirror) plsv1.Mirror {
+	m := plsv1.Mirror{
+		Name:           mirror.GetName(),
+		SelectSrcPorts: mirror.GetSelectSrcPorts(),
+		SelectDstPorts: mirror.GetSelectDstPorts(),
+		SelectAll:      mirror.GetSelectAll(),
+		OutputPort:     mirror.GetOutputPort(),
+	}
+	for _, vlan := range mirror.GetSelectVlans() {
+		m.SelectVlans = append(m.SelectVlans, int(vlan))
+	}
+	return m
+}
+
+func mirrorToPb(mirror plsv1.Mirror) *nedpb.Mirror {
+	m := &nedpb.Mirror{
+		Name:           mirror.Name,
+		SelectSrcPorts: mirror.SelectSrcPorts,
+		SelectDstPorts: mirror.SelectDstPorts,
+		SelectAll:      mirror.SelectAll,
+		OutputPort:     mirror.OutputPort,
+	}
+	for _, vlan := range mirror.SelectVlans {
+		m.SelectVlans = append(m.SelectVlans, int32(vlan))
+	}
+	if mirror.ExpiresAt != nil {
+		m.ExpiresAt = mirror.ExpiresAt.Unix()
+	}
+	return m
+}
+
+// mirrorStatus maps a failed mirror operation to the gRPC status that best describes it.
+func mirrorStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ovs.ErrInvalidMirror):
+		code = codes.InvalidArgument
+	case errors.Is(err, ovs.ErrMirrorNotFound):
+		code = codes.NotFound
+	}
+	return status.Errorf(code, "mirror operation failed: %v", err)
+}
+
+// portQos converts the traffic controls of a request, which are nil if the request has none.
+func portQos(qos *nedpb.PortQos) *plsv1.PortQos {
+	if qos == nil {
+		return nil
+	}
+	portQos := &plsv1.PortQos{
+		IngressPolicingRate:  qos.GetIngressPolicingRate(),
+		IngressPolicingBurst: qos.GetIngressPolicingBurst(),
+		Type:                 qos.GetType(),
+		MaxRate:              qos.GetMaxRate(),
+	}
+	for _, queue := range qos.GetQueues() {
+		portQos.Queues = append(portQos.Queues, plsv1.Queue{
+			Id:       queue.GetId(),
+			MinRate:  queue.GetMinRate(),
+			MaxRate:  queue.GetMaxRate(),
+			Burst:    queue.GetBurst(),
+			Priority: queue.GetPriority(),
+		})
+	}
+	return portQos
+}
+
+// attachStatus maps a failed attachment to the gRPC status that best describes it.
+func attachStatus(err error) error {
+	code := codes.Internal
+	var attachErr *controller.AttachError
+	switch {
+	case errors.As(err, &attachErr) && attachErr.RollbackErr != nil:
+		// the node may have been left with part of the port, so report it as an internal error whatever the cause
+		code = codes.Internal
+	case errors.Is(err, controller.ErrInvalidQos):
+		code = codes.InvalidArgument
+	case errors.Is(err, controller.ErrOrphanPort):
+		code = codes.FailedPrecondition
+	case errors.Is(err, linuxif.ErrLinkNotFound):
+		code = codes.NotFound
+	}
+	return status.Errorf(code, "failed to attach interface: %v", err)
+}
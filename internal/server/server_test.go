@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Networks-it-uc3m/l2sm-switch/internal/controller"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/ovs"
+)
+
+func TestAttachStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"invalid qos", &controller.AttachError{Step: "validate qos", Err: fmt.Errorf("%w: unsupported qos type cbq", controller.ErrInvalidQos)}, codes.InvalidArgument},
+		{"orphan", &controller.AttachError{Step: "allocate port", Err: fmt.Errorf("id 3: %w", controller.ErrOrphanPort)}, codes.FailedPrecondition},
+		{"missing bridge", &controller.AttachError{Step: "create port", Err: fmt.Errorf("br10: %w", linuxif.ErrLinkNotFound)}, codes.NotFound},
+		{"ovs failure", &controller.AttachError{Step: "add port to switch", Err: errors.New("add-port error")}, codes.Internal},
+		{"rollback failure", &controller.AttachError{Step: "create port", Err: fmt.Errorf("br10: %w", linuxif.ErrLinkNotFound), RollbackErr: errors.New("busy")}, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(attachStatus(tt.err)); got != tt.want {
+				t.Fatalf("attachStatus(): got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"invalid", fmt.Errorf("%w: mirror m1 selects no traffic", ovs.ErrInvalidMirror), codes.InvalidArgument},
+		{"invalid port", fmt.Errorf("could not create mirror m1: %w", fmt.Errorf("create mirror error: %w: no port named eth9", ovs.ErrInvalidMirror)), codes.InvalidArgument},
+		{"not found", fmt.Errorf("could not delete mirror m1: delete mirror error: %w: m1", ovs.ErrMirrorNotFound), codes.NotFound},
+		{"ovs failure", errors.New("create mirror error: no port named eth9"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(mirrorStatus(tt.err)); got != tt.want {
+				t.Fatalf("mirrorStatus(): got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,13 +1,31 @@
 package linuxif
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"sort"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
+// ErrLinkNotFound is returned when an interface that an operation relies on does not exist.
+var ErrLinkNotFound = errors.New("link not found")
+
+// linkByName wraps netlink.LinkByName so a missing interface can be identified with ErrLinkNotFound.
+func linkByName(name string) (netlink.Link, error) {
+	l, err := netlink.LinkByName(name)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%s: %w", name, ErrLinkNotFound)
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
 // ListNames returns all interface names currently present in the network namespace.
 func ListNames() ([]string, error) {
 	interfaces, err := net.Interfaces()
@@ -23,6 +41,62 @@ func ListNames() ([]string, error) {
 	return names, nil
 }
 
+// LinkState is the kernel view of an interface.
+type LinkState struct {
+	Index int
+	// Type is the kind of link, e.g. veth, bridge or openvswitch
+	Type      string
+	OperState string
+	// Carrier is true when the link layer is up (IFF_LOWER_UP)
+	Carrier bool
+	// PeerIfindex is the index of the other end of a veth pair, in the namespace of the peer, or 0
+	PeerIfindex int
+	// MasterIndex is the index of the bridge the interface is enslaved to, or 0
+	MasterIndex int
+}
+
+// GetLinkState returns the kernel state of the interface.
+func GetLinkState(name string) (LinkState, error) {
+	l, err := linkByName(name)
+	if err != nil {
+		return LinkState{}, err
+	}
+	attrs := l.Attrs()
+	state := LinkState{
+		Index:       attrs.Index,
+		Type:        l.Type(),
+		OperState:   attrs.OperState.String(),
+		Carrier:     attrs.RawFlags&unix.IFF_LOWER_UP != 0,
+		MasterIndex: attrs.MasterIndex,
+	}
+	// for veths the kernel reports the peer as the parent link
+	if l.Type() == "veth" {
+		state.PeerIfindex = attrs.ParentIndex
+	}
+	return state, nil
+}
+
+// ListLinks returns the kernel state of every interface in the network namespace, keyed by name. Interfaces removed
+// while they are listed are left out.
+func ListLinks() (map[string]LinkState, error) {
+	names, err := ListNames()
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string]LinkState, len(names))
+	for _, name := range names {
+		state, err := GetLinkState(name)
+		if errors.Is(err, ErrLinkNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		links[name] = state
+	}
+	return links, nil
+}
+
 func Exists(name string) bool {
 	if name == "" {
 		return false
@@ -38,11 +112,14 @@ func Exists(name string) bool {
 }
 
 func AddInterfaceToLinuxBridge(interfaceName, switchName string) error {
-	l, err := netlink.LinkByName(interfaceName)
+	l, err := linkByName(interfaceName)
+	if err != nil {
+		return fmt.Errorf("could not find link by name. erro: %w", err)
+	}
+	master, err := linkByName(switchName)
 	if err != nil {
-		return fmt.Errorf("could not find link by name. erro: %v", err)
+		return fmt.Errorf("could not find bridge by name. error: %w", err)
 	}
-	master, err := netlink.LinkByName(switchName)
 	err = netlink.LinkSetMaster(l, master)
 	if err != nil {
 		return fmt.Errorf("command error: %v", err)
@@ -51,11 +128,15 @@ func AddInterfaceToLinuxBridge(interfaceName, switchName string) error {
 
 }
 
-func AddVethPair(vethName, peerName string) error {
+// AddVethPair creates the veth pair with the given MTU on both ends, 0 keeping the kernel default, and sets
+// both ends up. If the pair was created but could not be set up, it is deleted again.
+func AddVethPair(vethName, peerName string, mtu int) error {
 	v := &netlink.Veth{
 		LinkAttrs: netlink.LinkAttrs{
 			Name: vethName,
+			MTU:  mtu,
 		},
+		// the peer gets the same MTU
 		PeerName: peerName,
 	}
 
@@ -63,6 +144,14 @@ func AddVethPair(vethName, peerName string) error {
 		return fmt.Errorf("add veth %s<->%s: %v", vethName, peerName, err)
 	}
 
+	if err := setVethUp(vethName, peerName); err != nil {
+		return errors.Join(err, DeleteLink(vethName))
+	}
+
+	return nil
+}
+
+func setVethUp(vethName, peerName string) error {
 	hostL, err := netlink.LinkByName(vethName)
 	if err != nil {
 		return fmt.Errorf("get link %s: %v", vethName, err)
@@ -83,3 +172,72 @@ func AddVethPair(vethName, peerName string) error {
 
 	return nil
 }
+
+// RemoveFromLinuxBridge releases the interface from the bridge it is enslaved to, if any.
+func RemoveFromLinuxBridge(interfaceName string) error {
+	l, err := linkByName(interfaceName)
+	if errors.Is(err, ErrLinkNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get link %s: %v", interfaceName, err)
+	}
+	if l.Attrs().MasterIndex == 0 {
+		return nil
+	}
+	if err := netlink.LinkSetNoMaster(l); err != nil {
+		return fmt.Errorf("release %s from its bridge: %v", interfaceName, err)
+	}
+	return nil
+}
+
+// DeleteLink removes the interface, and its peer when it is a veth. Missing interfaces are ignored.
+func DeleteLink(name string) error {
+	l, err := linkByName(name)
+	if errors.Is(err, ErrLinkNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get link %s: %v", name, err)
+	}
+	if err := netlink.LinkDel(l); err != nil {
+		return fmt.Errorf("delete link %s: %v", name, err)
+	}
+	return nil
+}
+
+// SetMtu sets the MTU of the interface.
+func SetMtu(name string, mtu int) error {
+	l, err := linkByName(name)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMTU(l, mtu); err != nil {
+		return fmt.Errorf("set mtu of %s: %v", name, err)
+	}
+	return nil
+}
+
+// RouteMtu returns the MTU of the path to ip: the MTU of the route to it if the route sets one, or else the
+// MTU of the interface the route goes through.
+func RouteMtu(ip string) (int, error) {
+	dst := net.ParseIP(ip)
+	if dst == nil {
+		return 0, fmt.Errorf("invalid ip address %s", ip)
+	}
+	routes, err := netlink.RouteGet(dst)
+	if err != nil {
+		return 0, fmt.Errorf("get route to %s: %v", ip, err)
+	}
+	if len(routes) == 0 {
+		return 0, fmt.Errorf("no route to %s", ip)
+	}
+	if routes[0].MTU > 0 {
+		return routes[0].MTU, nil
+	}
+	l, err := netlink.LinkByIndex(routes[0].LinkIndex)
+	if err != nil {
+		return 0, fmt.Errorf("get link of the route to %s: %v", ip, err)
+	}
+	return l.Attrs().MTU, nil
+}
@@ -19,8 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	NedService_CreateVxlan_FullMethodName     = "/nedpb.NedService/CreateVxlan"
-	NedService_AttachInterface_FullMethodName = "/nedpb.NedService/AttachInterface"
+	NedService_CreateVxlan_FullMethodName         = "/nedpb.NedService/CreateVxlan"
+	NedService_AttachInterface_FullMethodName     = "/nedpb.NedService/AttachInterface"
+	NedService_GetNodeName_FullMethodName         = "/nedpb.NedService/GetNodeName"
+	NedService_GetControllerStatus_FullMethodName = "/nedpb.NedService/GetControllerStatus"
+	NedService_CreateMirror_FullMethodName        = "/nedpb.NedService/CreateMirror"
+	NedService_DeleteMirror_FullMethodName        = "/nedpb.NedService/DeleteMirror"
+	NedService_ListMirrors_FullMethodName         = "/nedpb.NedService/ListMirrors"
+	NedService_ReapOrphans_FullMethodName         = "/nedpb.NedService/ReapOrphans"
 )
 
 // NedServiceClient is the client API for NedService service.
@@ -31,6 +37,18 @@ type NedServiceClient interface {
 	CreateVxlan(ctx context.Context, in *CreateVxlanRequest, opts ...grpc.CallOption) (*CreateVxlanResponse, error)
 	// Attaches the specified interface to the bridge.
 	AttachInterface(ctx context.Context, in *AttachInterfaceRequest, opts ...grpc.CallOption) (*AttachInterfaceResponse, error)
+	// Returns this neds node name
+	GetNodeName(ctx context.Context, in *GetNodeNameRequest, opts ...grpc.CallOption) (*GetNodeNameResponse, error)
+	// Returns the connection status of every controller of the bridge.
+	GetControllerStatus(ctx context.Context, in *GetControllerStatusRequest, opts ...grpc.CallOption) (*GetControllerStatusResponse, error)
+	// Copies the traffic of ports or VLANs of the switch to an output port, replacing the mirror with the same name.
+	CreateMirror(ctx context.Context, in *CreateMirrorRequest, opts ...grpc.CallOption) (*CreateMirrorResponse, error)
+	// Removes a mirror from the switch.
+	DeleteMirror(ctx context.Context, in *DeleteMirrorRequest, opts ...grpc.CallOption) (*DeleteMirrorResponse, error)
+	// Returns the mirrors of the switch.
+	ListMirrors(ctx context.Context, in *ListMirrorsRequest, opts ...grpc.CallOption) (*ListMirrorsResponse, error)
+	// Finds the ports, veths and peers talpa left behind, and removes them if the reaper policy of the switch is remove.
+	ReapOrphans(ctx context.Context, in *ReapOrphansRequest, opts ...grpc.CallOption) (*ReapOrphansResponse, error)
 }
 
 type nedServiceClient struct {
@@ -61,6 +79,66 @@ func (c *nedServiceClient) AttachInterface(ctx context.Context, in *AttachInterf
 	return out, nil
 }
 
+func (c *nedServiceClient) GetNodeName(ctx context.Context, in *GetNodeNameRequest, opts ...grpc.CallOption) (*GetNodeNameResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetNodeNameResponse)
+	err := c.cc.Invoke(ctx, NedService_GetNodeName_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nedServiceClient) GetControllerStatus(ctx context.Context, in *GetControllerStatusRequest, opts ...grpc.CallOption) (*GetControllerStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetControllerStatusResponse)
+	err := c.cc.Invoke(ctx, NedService_GetControllerStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nedServiceClient) CreateMirror(ctx context.Context, in *CreateMirrorRequest, opts ...grpc.CallOption) (*CreateMirrorResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateMirrorResponse)
+	err := c.cc.Invoke(ctx, NedService_CreateMirror_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nedServiceClient) DeleteMirror(ctx context.Context, in *DeleteMirrorRequest, opts ...grpc.CallOption) (*DeleteMirrorResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMirrorResponse)
+	err := c.cc.Invoke(ctx, NedService_DeleteMirror_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nedServiceClient) ListMirrors(ctx context.Context, in *ListMirrorsRequest, opts ...grpc.CallOption) (*ListMirrorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMirrorsResponse)
+	err := c.cc.Invoke(ctx, NedService_ListMirrors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nedServiceClient) ReapOrphans(ctx context.Context, in *ReapOrphansRequest, opts ...grpc.CallOption) (*ReapOrphansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReapOrphansResponse)
+	err := c.cc.Invoke(ctx, NedService_ReapOrphans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NedServiceServer is the server API for NedService service.
 // All implementations must embed UnimplementedNedServiceServer
 // for forward compatibility.
@@ -69,6 +147,18 @@ type NedServiceServer interface {
 	CreateVxlan(context.Context, *CreateVxlanRequest) (*CreateVxlanResponse, error)
 	// Attaches the specified interface to the bridge.
 	AttachInterface(context.Context, *AttachInterfaceRequest) (*AttachInterfaceResponse, error)
+	// Returns this neds node name
+	GetNodeName(context.Context, *GetNodeNameRequest) (*GetNodeNameResponse, error)
+	// Returns the connection status of every controller of the bridge.
+	GetControllerStatus(context.Context, *GetControllerStatusRequest) (*GetControllerStatusResponse, error)
+	// Copies the traffic of ports or VLANs of the switch to an output port, replacing the mirror with the same name.
+	CreateMirror(context.Context, *CreateMirrorRequest) (*CreateMirrorResponse, error)
+	// Removes a mirror from the switch.
+	DeleteMirror(context.Context, *DeleteMirrorRequest) (*DeleteMirrorResponse, error)
+	// Returns the mirrors of the switch.
+	ListMirrors(context.Context, *ListMirrorsRequest) (*ListMirrorsResponse, error)
+	// Finds the ports, veths and peers talpa left behind, and removes them if the reaper policy of the switch is remove.
+	ReapOrphans(context.Context, *ReapOrphansRequest) (*ReapOrphansResponse, error)
 	mustEmbedUnimplementedNedServiceServer()
 }
 
@@ -85,6 +175,24 @@ func (UnimplementedNedServiceServer) CreateVxlan(context.Context, *CreateVxlanRe
 func (UnimplementedNedServiceServer) AttachInterface(context.Context, *AttachInterfaceRequest) (*AttachInterfaceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AttachInterface not implemented")
 }
+func (UnimplementedNedServiceServer) GetNodeName(context.Context, *GetNodeNameRequest) (*GetNodeNameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeName not implemented")
+}
+func (UnimplementedNedServiceServer) GetControllerStatus(context.Context, *GetControllerStatusRequest) (*GetControllerStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetControllerStatus not implemented")
+}
+func (UnimplementedNedServiceServer) CreateMirror(context.Context, *CreateMirrorRequest) (*CreateMirrorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateMirror not implemented")
+}
+func (UnimplementedNedServiceServer) DeleteMirror(context.Context, *DeleteMirrorRequest) (*DeleteMirrorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMirror not implemented")
+}
+func (UnimplementedNedServiceServer) ListMirrors(context.Context, *ListMirrorsRequest) (*ListMirrorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMirrors not implemented")
+}
+func (UnimplementedNedServiceServer) ReapOrphans(context.Context, *ReapOrphansRequest) (*ReapOrphansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReapOrphans not implemented")
+}
 func (UnimplementedNedServiceServer) mustEmbedUnimplementedNedServiceServer() {}
 func (UnimplementedNedServiceServer) testEmbeddedByValue()                    {}
 
@@ -142,6 +250,114 @@ func _NedService_AttachInterface_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NedService_GetNodeName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NedServiceServer).GetNodeName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NedService_GetNodeName_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NedServiceServer).GetNodeName(ctx, req.(*GetNodeNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NedService_GetControllerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetControllerStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NedServiceServer).GetControllerStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NedService_GetControllerStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NedServiceServer).GetControllerStatus(ctx, req.(*GetControllerStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NedService_CreateMirror_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMirrorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NedServiceServer).CreateMirror(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NedService_CreateMirror_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NedServiceServer).CreateMirror(ctx, req.(*CreateMirrorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NedService_DeleteMirror_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMirrorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NedServiceServer).DeleteMirror(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NedService_DeleteMirror_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NedServiceServer).DeleteMirror(ctx, req.(*DeleteMirrorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NedService_ListMirrors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMirrorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NedServiceServer).ListMirrors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NedService_ListMirrors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NedServiceServer).ListMirrors(ctx, req.(*ListMirrorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NedService_ReapOrphans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReapOrphansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NedServiceServer).ReapOrphans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NedService_ReapOrphans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NedServiceServer).ReapOrphans(ctx, req.(*ReapOrphansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NedService_ServiceDesc is the grpc.ServiceDesc for NedService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -157,6 +373,30 @@ var NedService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AttachInterface",
 			Handler:    _NedService_AttachInterface_Handler,
 		},
+		{
+			MethodName: "GetNodeName",
+			Handler:    _NedService_GetNodeName_Handler,
+		},
+		{
+			MethodName: "GetControllerStatus",
+			Handler:    _NedService_GetControllerStatus_Handler,
+		},
+		{
+			MethodName: "CreateMirror",
+			Handler:    _NedService_CreateMirror_Handler,
+		},
+		{
+			MethodName: "DeleteMirror",
+			Handler:    _NedService_DeleteMirror_Handler,
+		},
+		{
+			MethodName: "ListMirrors",
+			Handler:    _NedService_ListMirrors_Handler,
+		},
+		{
+			MethodName: "ReapOrphans",
+			Handler:    _NedService_ReapOrphans_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "ned.proto",
@@ -0,0 +1,113 @@
+package ovs
+
+import (
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// Backend is the set of switch operations a VirtualSwitch relies on. It is implemented by
+// OvsService, which shells out to ovs-vsctl, and by OvsdbService, which talks to ovsdb-server directly.
+type Backend interface {
+	BridgeExists(bridgeName string) bool
+	AddBridge(bridgeName string) error
+	DeleteBridge(bridgeName string) error
+	SetDatapathID(bridgeName, datapathId string) error
+	SetDatapathType(bridgeName, datapathType string) error
+	SetProtocol(bridgeName, protocol string) error
+	SetController(bridgeName string, controller ...string) error
+	SetFailMode(bridgeName, failMode string) error
+	SetFlowExport(bridgeName string, export plsv1.FlowExport) error
+	SetSpanningTree(bridgeName string, st plsv1.SpanningTree) error
+	GetSpanningTreeStatus(bridgeName string) (plsv1.SpanningTreeStatus, error)
+	GetFailMode(bridgeName string) (string, error)
+	GetControllerStatus(bridgeName string) ([]ControllerStatus, error)
+	CreateVxlan(bridgeName string, vxlan plsv1.Vxlan) error
+	DeleteVxlan(bridgeName, vxlanId string) error
+	ModifyVxlan(vxlan plsv1.Vxlan) error
+	SetMtu(interfaceName string, mtu int) error
+	SetPathCost(portName string, cost int) error
+	SetExternalIds(table, record string, externalIds map[string]string) error
+	AddPort(bridgeName, portName string, netIndex int, internal bool) error
+	AddBond(bridgeName string, port plsv1.Port) error
+	CreatePatch(port, peer PatchPort) error
+	DeletePatch(portName, peerName string) error
+	SetPortQos(bridgeName, portName string, qos *plsv1.PortQos) error
+	SetPortVlan(bridgeName string, port plsv1.Port) error
+	DeletePort(bridgeName, portName string) error
+	GetPortNumber(portName string) (int64, error)
+	GetPorts(bridgeName string) (map[string]plsv1.Port, error)
+	GetNewPortID(bridgeName string) (int, error)
+	GetController(bridgeName string) ([]string, error)
+	GetVxlans(bridgeName string) (map[string]plsv1.Vxlan, error)
+	CreateMirror(bridgeName string, mirror plsv1.Mirror) error
+	DeleteMirror(bridgeName, mirrorName string) error
+	GetMirrors(bridgeName string) (map[string]plsv1.Mirror, error)
+	NewTxn(bridgeName string) Txn
+}
+
+// ControllerStatus is the connection status of one of the controllers of a bridge, as reported
+// by ovs-vswitchd in the Controller table.
+type ControllerStatus struct {
+	Target      string
+	IsConnected bool
+	// Role is other, master or slave, or empty if the bridge never connected
+	Role string
+	// Status holds the status column, e.g. state, sec_since_connect or last_error
+	Status    map[string]string
+	LastError string
+}
+
+// controllerStatus builds the status of a controller from its row in the Controller table.
+func controllerStatus(row ovsdbRow) ControllerStatus {
+	status := row.strMap("status")
+	isConnected := row.elems("is_connected")
+	return ControllerStatus{
+		Target:      row.str("target"),
+		IsConnected: len(isConnected) > 0 && isConnected[0] == true,
+		Role:        row.str("role"),
+		Status:      status,
+		LastError:   status["last_error"],
+	}
+}
+
+// Txn collects changes to a bridge and applies them as a single transaction on Commit:
+// either every change is applied or, if any of them fails, none is.
+type Txn interface {
+	SetController(controller ...string)
+	SetProtocol(protocol string)
+	SetDatapathID(datapathId string)
+	SetDatapathType(datapathType string)
+	SetFailMode(failMode string)
+	SetFlowExport(export plsv1.FlowExport)
+	SetSpanningTree(st plsv1.SpanningTree)
+	AddPort(portName string, netIndex int, internal bool)
+	AddBond(port plsv1.Port)
+	SetBond(port plsv1.Port)
+	AddBondMember(portName, member string)
+	RemoveBondMember(portName, member string)
+	SetPortQos(portName string, qos *plsv1.PortQos)
+	SetPortVlan(port plsv1.Port)
+	DeletePort(portName string)
+	CreateVxlan(vxlan plsv1.Vxlan)
+	ModifyVxlan(vxlan plsv1.Vxlan)
+	SetMtu(interfaceName string, mtu int)
+	SetPathCost(portName string, cost int)
+	SetExternalIds(table, record string, externalIds map[string]string)
+	DeleteVxlan(vxlanId string)
+	Commit() error
+}
+
+// newBackend returns the backend selected in the bridge configuration, ovs-vsctl being the default.
+func newBackend(bridgeConf *BridgeConf) Backend {
+	if bridgeConf.backend != nil {
+		return bridgeConf.backend
+	}
+	if bridgeConf.setFields[FieldOvsdb] {
+		return NewOvsdbService(bridgeConf.ovsdb)
+	}
+	ovsService := NewOvsService()
+
+	if bridgeConf.setFields[FieldSudo] {
+		ovsService = NewSudoOvsService()
+	}
+	return &ovsService
+}
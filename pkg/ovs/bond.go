@@ -0,0 +1,139 @@
+package ovs
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// bondColumns are the Port columns the configuration and the active member of a bond are read from,
+// bondMemberColumns the Interface columns of the state of its members besides the ones of portColumns.
+var bondColumns = []string{"bond_mode", "lacp", "bond_active_slave"}
+var bondMemberColumns = []string{"lacp_current"}
+
+// validateBond checks that OVS accepts the bond of the port, if it is one.
+func validateBond(port plsv1.Port) error {
+	bond := port.Bond
+	if bond == nil {
+		return nil
+	}
+	if len(bond.Members) < 2 {
+		return fmt.Errorf("bond %s needs at least two members", port.Name)
+	}
+	seen := make(map[string]bool)
+	for _, member := range bond.Members {
+		if member == "" || member == port.Name || seen[member] {
+			return fmt.Errorf("invalid member %q of bond %s", member, port.Name)
+		}
+		seen[member] = true
+	}
+	if bond.Mode != "" && !plsv1.IsBondMode(bond.Mode) {
+		return fmt.Errorf("unsupported bond mode %s for bond %s", bond.Mode, port.Name)
+	}
+	if bond.Lacp != "" && !plsv1.IsLacpMode(bond.Lacp) {
+		return fmt.Errorf("unsupported lacp mode %s for bond %s", bond.Lacp, port.Name)
+	}
+	if bond.Mode == plsv1.BOND_MODE_BALANCE_TCP && (bond.Lacp == "" || bond.Lacp == plsv1.LACP_OFF) {
+		return fmt.Errorf("bond %s in %s mode requires lacp", port.Name, bond.Mode)
+	}
+	// the rest of the settings of a port go to the interface with its name, which a bond does not have
+	if port.Internal || port.Id != nil || port.Qos != nil {
+		return fmt.Errorf("bond %s cannot be internal nor have an ofport or qos", port.Name)
+	}
+	return nil
+}
+
+// portInterfaces returns the interfaces of the port: the members of a bond, or the one with the name of the port.
+func portInterfaces(port plsv1.Port) []string {
+	if port.Bond != nil {
+		return sortedMembers(port.Bond)
+	}
+	return []string{port.Name}
+}
+
+func sortedMembers(bond *plsv1.Bond) []string {
+	members := append([]string{}, bond.Members...)
+	sort.Strings(members)
+	return members
+}
+
+// bondMembersDiff returns the members the requested bond adds to and removes from the current one.
+func bondMembersDiff(current, bond *plsv1.Bond) (added, removed []string) {
+	for _, member := range sortedMembers(bond) {
+		if !slices.Contains(current.Members, member) {
+			added = append(added, member)
+		}
+	}
+	for _, member := range sortedMembers(current) {
+		if !slices.Contains(bond.Members, member) {
+			removed = append(removed, member)
+		}
+	}
+	return added, removed
+}
+
+// bondSettingsEqual reports whether both bonds have the same mode and LACP mode.
+func bondSettingsEqual(a, b *plsv1.Bond) bool {
+	return a.Mode == b.Mode && a.Lacp == b.Lacp
+}
+
+// bondSettingsArgs returns the bond_mode and lacp columns of the bond, cleared when they are not set.
+func bondSettingsArgs(bond *plsv1.Bond) []string {
+	mode, lacp := "bond_mode=[]", "lacp=[]"
+	if bond.Mode != "" {
+		mode = "bond_mode=" + bond.Mode
+	}
+	if bond.Lacp != "" {
+		lacp = "lacp=" + bond.Lacp
+	}
+	return []string{mode, lacp}
+}
+
+// addBondArgs returns the command that adds the bond and its members to the bridge.
+func addBondArgs(bridgeName string, port plsv1.Port) []string {
+	args := append([]string{"add-bond", bridgeName, port.Name}, sortedMembers(port.Bond)...)
+	return append(args, bondSettingsArgs(port.Bond)...)
+}
+
+func setBondArgs(port plsv1.Port) []string {
+	return append([]string{"set", "port", port.Name}, bondSettingsArgs(port.Bond)...)
+}
+
+// bondColumnValues returns the bond_mode and lacp columns of the Port row of the bond, empty sets clearing them.
+func bondColumnValues(bond *plsv1.Bond) map[string]any {
+	mode, lacp := ovsdbSet(), ovsdbSet()
+	if bond.Mode != "" {
+		mode = ovsdbSet(bond.Mode)
+	}
+	if bond.Lacp != "" {
+		lacp = ovsdbSet(bond.Lacp)
+	}
+	return map[string]any{"bond_mode": mode, "lacp": lacp}
+}
+
+// bondFromRow builds the bond from its Port row and the Interface rows of its members.
+func bondFromRow(row ovsdbRow, members []ovsdbRow) *plsv1.Bond {
+	bond := &plsv1.Bond{
+		Members: []string{},
+		Mode:    row.str("bond_mode"),
+		Lacp:    row.str("lacp"),
+		Status:  []plsv1.BondMemberStatus{},
+	}
+	// ovs-vswitchd records the active member by its MAC address
+	activeMac := row.str("bond_active_slave")
+	for _, iface := range members {
+		lacpCurrent := iface.elems("lacp_current")
+		bond.Members = append(bond.Members, iface.str("name"))
+		bond.Status = append(bond.Status, plsv1.BondMemberStatus{
+			Name:        iface.str("name"),
+			LinkState:   iface.str("link_state"),
+			Active:      activeMac != "" && iface.str("mac_in_use") == activeMac,
+			LacpCurrent: len(lacpCurrent) > 0 && lacpCurrent[0] == true,
+		})
+	}
+	sort.Strings(bond.Members)
+	sort.Slice(bond.Status, func(i, j int) bool { return bond.Status[i].Name < bond.Status[j].Name })
+	return bond
+}
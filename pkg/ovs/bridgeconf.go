@@ -8,17 +8,26 @@ import (
 type ConfigurableField string
 
 const (
-	FieldController ConfigurableField = "controller"
-	FieldName       ConfigurableField = "name"
-	FieldProtocol   ConfigurableField = "protocol"
-	FieldDatapathId ConfigurableField = "datapathid"
-	FieldPorts      ConfigurableField = "ports"
-	FieldVxlans     ConfigurableField = "vxlans"
-	FieldSudo       ConfigurableField = "sudo"
+	FieldController   ConfigurableField = "controller"
+	FieldName         ConfigurableField = "name"
+	FieldProtocol     ConfigurableField = "protocol"
+	FieldDatapathId   ConfigurableField = "datapathid"
+	FieldPorts        ConfigurableField = "ports"
+	FieldVxlans       ConfigurableField = "vxlans"
+	FieldSudo         ConfigurableField = "sudo"
+	FieldOvsdb        ConfigurableField = "ovsdb"
+	FieldFailMode     ConfigurableField = "failmode"
+	FieldFlowExport   ConfigurableField = "flowexport"
+	FieldDatapathType ConfigurableField = "datapathtype"
+	FieldSpanningTree ConfigurableField = "spanningtree"
+	FieldOwner        ConfigurableField = "owner"
 )
 
 type BridgeConf struct {
-	bridge    plsv1.Bridge
+	bridge plsv1.Bridge
+	ovsdb  string
+	// backend replaces the ovs-vsctl and ovsdb-server backends when set
+	backend   Backend
 	setFields map[ConfigurableField]bool
 }
 
@@ -50,6 +59,48 @@ func WithDatapathId(datapathid string) func(*BridgeConf) {
 	}
 }
 
+// WithFailMode sets the bridge fail_mode, plsv1.FAIL_MODE_SECURE or plsv1.FAIL_MODE_STANDALONE.
+// An empty mode clears it, leaving the OVS default (standalone).
+func WithFailMode(failMode string) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		v.bridge.FailMode = failMode
+		v.setFields[FieldFailMode] = true
+	}
+}
+
+// WithFlowExport sets the sFlow, IPFIX and NetFlow collectors of the bridge, removing the ones export leaves unset.
+func WithFlowExport(export plsv1.FlowExport) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		v.bridge.FlowExport = export
+		v.setFields[FieldFlowExport] = true
+	}
+}
+
+// WithDatapathType sets the datapath the bridge runs on, plsv1.DATAPATH_SYSTEM or plsv1.DATAPATH_NETDEV.
+// An empty type leaves the OVS default (system).
+func WithDatapathType(datapathType string) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		v.bridge.DatapathType = datapathType
+		v.setFields[FieldDatapathType] = true
+	}
+}
+
+// WithSpanningTree sets the spanning tree protocol of the bridge and its priority. An empty protocol disables it.
+func WithSpanningTree(st plsv1.SpanningTree) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		v.bridge.SpanningTree = st
+		v.setFields[FieldSpanningTree] = true
+	}
+}
+
+// WithOwner sets the talpa instance the bridge belongs to, which labels the bridge and the ports and tunnels created on it.
+func WithOwner(owner plsv1.Owner) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		v.bridge.Owner = owner
+		v.setFields[FieldOwner] = true
+	}
+}
+
 func WithPorts(ports []plsv1.Port) func(*BridgeConf) {
 	return func(v *BridgeConf) {
 		portMap := make(map[string]plsv1.Port)
@@ -88,3 +139,23 @@ func WithSudo(s bool) func(*BridgeConf) {
 	}
 
 }
+
+// WithBackend makes the switch use the given backend instead of ovs-vsctl or ovsdb-server, e.g. a fake one in the tests
+// of its callers. nil keeps the backend selected by the other options.
+func WithBackend(backend Backend) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		v.backend = backend
+	}
+}
+
+// WithOvsdb makes the switch talk JSON-RPC to the ovsdb-server listening on address (e.g. unix:/var/run/openvswitch/db.sock)
+// instead of running ovs-vsctl. An empty address keeps the ovs-vsctl backend.
+func WithOvsdb(address string) func(*BridgeConf) {
+	return func(v *BridgeConf) {
+		if address == "" {
+			return
+		}
+		v.ovsdb = address
+		v.setFields[FieldOvsdb] = true
+	}
+}
@@ -3,6 +3,8 @@ package ovs
 import (
 	"bytes"
 	"os/exec"
+	"sync/atomic"
+	"time"
 )
 
 // CommandExecutor defines an interface for running external commands.
@@ -18,9 +20,32 @@ type ClientCommand string
 
 const (
 	OvsVsctlClient ClientCommand = "ovs-vsctl"
+	OvsOfctlClient ClientCommand = "ovs-ofctl"
 	IpClient       ClientCommand = "ip"
 )
 
+// Observer is called after every command run by a DefaultClient and every ovsdb-server transaction,
+// with the command (e.g. ovs-vsctl) or "ovsdb", how long it took and its error. It lets callers export metrics.
+type Observer func(command string, duration time.Duration, err error)
+
+// observer is read by every command, which may run in the goroutines of the watchdogs while it is set
+var observer atomic.Pointer[Observer]
+
+// SetObserver sets the function called after every command. nil stops calling it.
+func SetObserver(o Observer) {
+	if o == nil {
+		observer.Store(nil)
+		return
+	}
+	observer.Store(&o)
+}
+
+func observe(command string, start time.Time, err error) {
+	if o := observer.Load(); o != nil {
+		(*o)(command, time.Since(start), err)
+	}
+}
+
 // DefaultClient is the standard implementation that uses os/exec.
 type DefaultClient struct {
 	command string
@@ -36,24 +61,36 @@ func (e *DefaultClient) buildCommand(args ...string) *exec.Cmd {
 }
 
 func (e *DefaultClient) CombinedOutput(args ...string) ([]byte, error) {
+	start := time.Now()
 	cmd := e.buildCommand(args...)
-	return cmd.CombinedOutput()
+	output, err := cmd.CombinedOutput()
+	observe(e.command, start, err)
+	return output, err
 }
 
 func (e *DefaultClient) Run(args ...string) error {
+	start := time.Now()
 	cmd := e.buildCommand(args...)
-	return cmd.Run()
+	err := cmd.Run()
+	observe(e.command, start, err)
+	return err
 }
 
 func (e *DefaultClient) Output(args ...string) ([]byte, error) {
+	start := time.Now()
 	cmd := e.buildCommand(args...)
-	return cmd.Output()
+	output, err := cmd.Output()
+	observe(e.command, start, err)
+	return output, err
 }
 
 func (e *DefaultClient) OutputToBuffer(stdout *bytes.Buffer, args ...string) error {
+	start := time.Now()
 	cmd := e.buildCommand(args...)
 	cmd.Stdout = stdout
-	return cmd.Run()
+	err := cmd.Run()
+	observe(e.command, start, err)
+	return err
 }
 
 // NewClient creates a new instance of the default command executor with optional sudo.
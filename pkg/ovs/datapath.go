@@ -0,0 +1,15 @@
+package ovs
+
+import (
+	"fmt"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// validateDatapathType checks that the bridge can run on the datapath, an empty type standing for the OVS default.
+func validateDatapathType(datapathType string) error {
+	if datapathType != "" && !plsv1.IsDatapathType(datapathType) {
+		return fmt.Errorf("unsupported datapath type %s", datapathType)
+	}
+	return nil
+}
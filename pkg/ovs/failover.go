@@ -0,0 +1,87 @@
+package ovs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// FAILOVER_CHECK_INTERVAL is how often the failover watchdog checks the controller connection.
+const FAILOVER_CHECK_INTERVAL = 2 * time.Second
+
+// validateFailMode checks that the bridge supports the fail mode, an empty mode clearing it.
+func validateFailMode(failMode string) error {
+	if failMode != "" && !plsv1.IsFailMode(failMode) {
+		return fmt.Errorf("unsupported fail mode %s", failMode)
+	}
+	return nil
+}
+
+// FailoverWatchdog switches a secure bridge to standalone mode once it has been without a controller
+// connection for longer than timeout, so it keeps forwarding traffic as a learning switch, and switches
+// it back to secure mode as soon as a controller connects again. Bridges in any other fail mode already
+// forward traffic on their own and are left as they are.
+type FailoverWatchdog struct {
+	vs      VirtualSwitch
+	timeout time.Duration
+	// failMode is the fail mode the bridge was configured with, restored when the controller is back
+	failMode   string
+	lastSeen   time.Time
+	failedOver bool
+}
+
+// NewFailoverWatchdog returns the watchdog of the switch, which records the fail mode the switch has now as
+// the configured one.
+func NewFailoverWatchdog(vs VirtualSwitch, timeout time.Duration) *FailoverWatchdog {
+	return &FailoverWatchdog{vs: vs, timeout: timeout, failMode: vs.GetFailMode(), lastSeen: time.Now()}
+}
+
+// Run checks the controller connection every FAILOVER_CHECK_INTERVAL until ctx is done.
+func (w *FailoverWatchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(FAILOVER_CHECK_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := w.check(now); err != nil {
+				log.Printf("failover watchdog of %s: %v", w.vs.bridge.Name, err)
+			}
+		}
+	}
+}
+
+func (w *FailoverWatchdog) check(now time.Time) error {
+	if w.failMode != plsv1.FAIL_MODE_SECURE {
+		return nil
+	}
+	connected, err := w.vs.ControllerConnected()
+	if err != nil {
+		return err
+	}
+
+	if connected {
+		w.lastSeen = now
+		if w.failedOver {
+			if err := w.vs.SetFailMode(w.failMode); err != nil {
+				return err
+			}
+			w.failedOver = false
+			log.Printf("controller of %s is back, switched to %s mode", w.vs.bridge.Name, w.failMode)
+		}
+		return nil
+	}
+
+	if !w.failedOver && now.Sub(w.lastSeen) >= w.timeout {
+		if err := w.vs.SetFailMode(plsv1.FAIL_MODE_STANDALONE); err != nil {
+			return err
+		}
+		w.failedOver = true
+		log.Printf("no controller connection on %s for %s, switched to %s mode", w.vs.bridge.Name, now.Sub(w.lastSeen), plsv1.FAIL_MODE_STANDALONE)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package ovs
+
+import (
+	"testing"
+	"time"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+func TestFailoverWatchdog(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetFailMode("br0", plsv1.FAIL_MODE_SECURE); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetController("br0", "tcp:127.0.0.1:6633"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	failMode := func() string {
+		mode, err := svc.GetFailMode("br0")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		return mode
+	}
+
+	vs := VirtualSwitch{bridge: plsv1.Bridge{Name: "br0", FailMode: plsv1.FAIL_MODE_SECURE}, ovsService: svc}
+	start := time.Now()
+	w := NewFailoverWatchdog(vs, 10*time.Second)
+	w.lastSeen = start
+
+	if err := w.check(start.Add(5 * time.Second)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mode := failMode(); mode != plsv1.FAIL_MODE_SECURE {
+		t.Errorf("expected secure mode before the timeout, got: %s", mode)
+	}
+
+	if err := w.check(start.Add(10 * time.Second)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mode := failMode(); mode != plsv1.FAIL_MODE_STANDALONE {
+		t.Errorf("expected standalone mode after the timeout, got: %s", mode)
+	}
+
+	fake.update("Controller", map[string]any{"target": "tcp:127.0.0.1:6633"}, map[string]any{"is_connected": true})
+	if err := w.check(start.Add(11 * time.Second)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mode := failMode(); mode != plsv1.FAIL_MODE_SECURE {
+		t.Errorf("expected secure mode once the controller is back, got: %s", mode)
+	}
+
+	// the timeout counts again from the last time the controller was seen
+	fake.update("Controller", map[string]any{"target": "tcp:127.0.0.1:6633"}, map[string]any{"is_connected": false})
+	if err := w.check(start.Add(15 * time.Second)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mode := failMode(); mode != plsv1.FAIL_MODE_SECURE {
+		t.Errorf("expected secure mode before the timeout, got: %s", mode)
+	}
+}
+
+func TestFailoverWatchdogNotSecure(t *testing.T) {
+	for _, configured := range []string{plsv1.FAIL_MODE_STANDALONE, ""} {
+		svc, _ := newTestOvsdbService(t)
+		if err := svc.AddBridge("br0"); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if configured != "" {
+			if err := svc.SetFailMode("br0", configured); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		}
+		if err := svc.SetController("br0", "tcp:127.0.0.1:6633"); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		vs, err := GetVirtualSwitch(WithName("br0"), WithOvsdb(svc.address))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		start := time.Now()
+		w := NewFailoverWatchdog(vs, 10*time.Second)
+		w.lastSeen = start
+		if err := w.check(start.Add(20 * time.Second)); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if w.failedOver {
+			t.Errorf("expected a bridge in fail mode %q not to fail over", configured)
+		}
+		if mode, err := svc.GetFailMode("br0"); err != nil || mode != configured {
+			t.Errorf("expected fail mode %q to be kept, got: %q (%v)", configured, mode, err)
+		}
+	}
+}
+
+func TestUpdateVirtualSwitchFailMode(t *testing.T) {
+	svc, _ := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithFailMode(plsv1.FAIL_MODE_SECURE)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// an unsupported mode is rejected before anything changes, also when the bridge would be replaced
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithFailMode("fail-open")); err == nil {
+		t.Errorf("expected an error on an unsupported fail mode")
+	}
+	if _, err := NewVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithFailMode("fail-open")); err == nil {
+		t.Errorf("expected an error on an unsupported fail mode")
+	}
+	if mode, err := svc.GetFailMode("br0"); err != nil || mode != plsv1.FAIL_MODE_SECURE {
+		t.Errorf("expected br0 to keep secure mode, got: %s, %v", mode, err)
+	}
+}
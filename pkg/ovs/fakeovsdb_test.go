@@ -0,0 +1,540 @@
+package ovs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOvsdb is a small in-process ovsdb-server. It keeps the Open_vSwitch database in memory and
+// implements the transact operations talpa uses, garbage collection of unreferenced rows, and
+// plays the part of ovs-vswitchd by acknowledging every configuration change and assigning ofports.
+type fakeOvsdb struct {
+	mu       sync.Mutex
+	tables   map[string]map[string]ovsdbRow
+	monitors []fakeMonitor
+	nextUUID int
+	nextPort int64
+}
+
+// fakeConn serializes the messages written to a client, which may come from its own requests
+// or from monitor updates triggered by other clients.
+type fakeConn struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (c *fakeConn) send(msg any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enc.Encode(msg)
+}
+
+type fakeMonitor struct {
+	conn    *fakeConn
+	id      any
+	columns map[string][]any
+}
+
+// fakeRootTables are the tables of the schema whose rows are kept when nothing references them, as in
+// config/vswitch.ovsschema.
+var fakeRootTables = map[string]bool{"Open_vSwitch": true, "QoS": true, "Queue": true, "Flow_Sample_Collector_Set": true}
+
+// newFakeOvsdb starts a fake server on a unix socket and returns it with the address to reach it.
+func newFakeOvsdb(t *testing.T) (*fakeOvsdb, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("could not start fake ovsdb: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	f := &fakeOvsdb{tables: make(map[string]map[string]ovsdbRow), nextPort: 1}
+	id := f.newUUID()
+	f.table("Open_vSwitch")[id] = ovsdbRow{"_uuid": uuidAtom(id), "bridges": ovsdbSet(), "next_cfg": json.Number("0"), "cur_cfg": json.Number("0")}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, "unix:" + path
+}
+
+func (f *fakeOvsdb) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	dec.UseNumber()
+	fc := &fakeConn{enc: json.NewEncoder(conn)}
+	defer f.removeMonitors(fc)
+	for {
+		var req struct {
+			Method string          `json:"method"`
+			Params []any           `json:"params"`
+			Id     json.RawMessage `json:"id"`
+		}
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		switch req.Method {
+		case "transact":
+			fc.send(map[string]any{"result": f.transact(req.Params[1:]), "error": nil, "id": req.Id})
+		case "monitor":
+			fc.send(map[string]any{"result": f.monitor(fc, req.Params[1], req.Params[2].(map[string]any)), "error": nil, "id": req.Id})
+		case "echo":
+			fc.send(map[string]any{"result": req.Params, "error": nil, "id": req.Id})
+		default:
+			fc.send(map[string]any{"result": nil, "error": "unknown method", "id": req.Id})
+		}
+	}
+}
+
+// monitor registers a monitor for the connection and returns the current contents of the monitored tables.
+func (f *fakeOvsdb) monitor(conn *fakeConn, id any, requests map[string]any) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := fakeMonitor{conn: conn, id: id, columns: make(map[string][]any)}
+	for table, request := range requests {
+		columns, _ := request.(map[string]any)["columns"].([]any)
+		m.columns[table] = columns
+	}
+	f.monitors = append(f.monitors, m)
+	return m.updates(make(map[string]map[string]ovsdbRow), f.tables)
+}
+
+func (f *fakeOvsdb) removeMonitors(conn *fakeConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	monitors := []fakeMonitor{}
+	for _, m := range f.monitors {
+		if m.conn != conn {
+			monitors = append(monitors, m)
+		}
+	}
+	f.monitors = monitors
+}
+
+// updates returns the table-updates object describing the monitored changes from old to current.
+func (m fakeMonitor) updates(old, current map[string]map[string]ovsdbRow) map[string]any {
+	updates := make(map[string]any)
+	for table, columns := range m.columns {
+		rows := make(map[string]any)
+		for id, row := range current[table] {
+			oldRow, existed := old[table][id]
+			if !existed {
+				rows[id] = map[string]any{"new": project(row, columns)}
+			} else if !fakeEqual(project(oldRow, columns), project(row, columns)) {
+				rows[id] = map[string]any{"old": project(oldRow, columns), "new": project(row, columns)}
+			}
+		}
+		for id, row := range old[table] {
+			if _, exists := current[table][id]; !exists {
+				rows[id] = map[string]any{"old": project(row, columns)}
+			}
+		}
+		if len(rows) > 0 {
+			updates[table] = rows
+		}
+	}
+	return updates
+}
+
+// update changes the columns of the rows matching the given values, as ovs-vswitchd does with status columns.
+func (f *fakeOvsdb) update(table string, match map[string]any, row map[string]any) {
+	conditions := []any{}
+	for column, value := range match {
+		conditions = append(conditions, []any{column, "==", value})
+	}
+	f.transact([]any{map[string]any{"op": "update", "table": table, "where": conditions, "row": row}})
+}
+
+func (f *fakeOvsdb) newUUID() string {
+	f.nextUUID++
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", f.nextUUID)
+}
+
+func (f *fakeOvsdb) table(name string) map[string]ovsdbRow {
+	if f.tables[name] == nil {
+		f.tables[name] = make(map[string]ovsdbRow)
+	}
+	return f.tables[name]
+}
+
+// rows returns a copy of the rows of a table matching the given column values.
+func (f *fakeOvsdb) rows(table string, match map[string]any) []ovsdbRow {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rows := []ovsdbRow{}
+	for _, row := range f.tables[table] {
+		matches := true
+		for column, value := range match {
+			if !fakeEqual(row[column], value) {
+				matches = false
+			}
+		}
+		if matches {
+			rows = append(rows, copyRow(row))
+		}
+	}
+	return rows
+}
+
+func (f *fakeOvsdb) transact(ops []any) []any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// operations run against a copy of the database that replaces it only if all of them succeed
+	snapshot := make(map[string]map[string]ovsdbRow)
+	for name, table := range f.tables {
+		snapshot[name] = make(map[string]ovsdbRow)
+		for id, row := range table {
+			snapshot[name][id] = copyRow(row)
+		}
+	}
+	committed := f.tables
+	f.tables = snapshot
+
+	results := []any{}
+	names := make(map[string]string)
+	for _, o := range ops {
+		op := o.(map[string]any)
+		result, err := f.execute(op, names)
+		if err != nil {
+			f.tables = committed
+			return append(results, map[string]any{"error": "constraint violation", "details": err.Error()})
+		}
+		results = append(results, result)
+	}
+	f.collectGarbage()
+	f.reconfigure()
+	for _, m := range f.monitors {
+		if updates := m.updates(committed, f.tables); len(updates) > 0 {
+			m.conn.send(map[string]any{"method": "update", "params": []any{m.id, updates}, "id": nil})
+		}
+	}
+	return results
+}
+
+func (f *fakeOvsdb) execute(op map[string]any, names map[string]string) (map[string]any, error) {
+	table := f.table(op["table"].(string))
+	var matched []string
+	if w, ok := op["where"].([]any); ok {
+		for id, row := range table {
+			if fakeMatches(row, resolveNames(w, names).([]any)) {
+				matched = append(matched, id)
+			}
+		}
+		sort.Strings(matched)
+	}
+
+	switch op["op"] {
+	case "insert":
+		id := f.newUUID()
+		row := ovsdbRow{"_uuid": uuidAtom(id)}
+		for column, value := range op["row"].(map[string]any) {
+			row[column] = resolveNames(value, names)
+		}
+		table[id] = row
+		if name, ok := op["uuid-name"].(string); ok {
+			names[name] = id
+		}
+		return map[string]any{"uuid": uuidAtom(id)}, nil
+	case "select":
+		rows := []any{}
+		for _, id := range matched {
+			rows = append(rows, project(table[id], op["columns"]))
+		}
+		return map[string]any{"rows": rows}, nil
+	case "update":
+		for _, id := range matched {
+			for column, value := range op["row"].(map[string]any) {
+				table[id][column] = resolveNames(value, names)
+			}
+		}
+		return map[string]any{"count": len(matched)}, nil
+	case "mutate":
+		for _, id := range matched {
+			for _, m := range op["mutations"].([]any) {
+				mut := resolveNames(m, names).([]any)
+				value, err := mutate(table[id][mut[0].(string)], mut[1].(string), mut[2])
+				if err != nil {
+					return nil, err
+				}
+				table[id][mut[0].(string)] = value
+			}
+		}
+		return map[string]any{"count": len(matched)}, nil
+	case "delete":
+		for _, id := range matched {
+			delete(table, id)
+		}
+		return map[string]any{"count": len(matched)}, nil
+	case "wait":
+		got := []string{}
+		for _, id := range matched {
+			got = append(got, canonical(project(table[id], op["columns"])))
+		}
+		want := []string{}
+		for _, r := range op["rows"].([]any) {
+			want = append(want, canonical(r))
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if (strings.Join(got, ",") == strings.Join(want, ",")) != (op["until"] == "==") {
+			return nil, fmt.Errorf("wait on %s timed out", op["table"])
+		}
+		return map[string]any{}, nil
+	}
+	return nil, fmt.Errorf("unsupported operation %v", op["op"])
+}
+
+// collectGarbage removes the rows of non root tables that are no longer referenced.
+func (f *fakeOvsdb) collectGarbage() {
+	reachable := make(map[string]bool)
+	var visit func(value any)
+	visit = func(value any) {
+		if id := atomUUID(value); id != "" {
+			if !reachable[id] {
+				reachable[id] = true
+				for _, table := range f.tables {
+					if row, ok := table[id]; ok {
+						for column, v := range row {
+							if column != "_uuid" {
+								visit(v)
+							}
+						}
+					}
+				}
+			}
+			return
+		}
+		if list, ok := value.([]any); ok {
+			for _, v := range list {
+				visit(v)
+			}
+		}
+	}
+	for name, table := range f.tables {
+		if fakeRootTables[name] {
+			for id := range table {
+				visit(uuidAtom(id))
+			}
+		}
+	}
+	for name, table := range f.tables {
+		if fakeRootTables[name] {
+			continue
+		}
+		for id := range table {
+			if !reachable[id] {
+				delete(table, id)
+			}
+		}
+	}
+}
+
+// reconfigure does what ovs-vswitchd would: give every interface an ofport and catch up with next_cfg.
+func (f *fakeOvsdb) reconfigure() {
+	for _, row := range f.table("Interface") {
+		if _, ok := row.integer("ofport"); ok {
+			continue
+		}
+		if request, ok := row.integer("ofport_request"); ok {
+			row["ofport"] = json.Number(fmt.Sprint(request))
+			continue
+		}
+		row["ofport"] = json.Number(fmt.Sprint(f.nextPort))
+		f.nextPort++
+	}
+	for _, row := range f.table("Open_vSwitch") {
+		row["cur_cfg"] = row["next_cfg"]
+	}
+}
+
+func mutate(current any, mutator string, value any) (any, error) {
+	switch mutator {
+	case "+=":
+		a, _ := atomInt(current)
+		b, _ := atomInt(value)
+		return json.Number(fmt.Sprint(a + b)), nil
+	case "insert", "delete":
+	default:
+		return nil, fmt.Errorf("unsupported mutator %s", mutator)
+	}
+
+	if isMap(current) || isMap(value) {
+		m := ovsdbRow{"m": current}.strMap("m")
+		switch {
+		case mutator == "insert":
+			for k, v := range (ovsdbRow{"m": value}).strMap("m") {
+				if _, ok := m[k]; !ok {
+					m[k] = v
+				}
+			}
+		case isMap(value):
+			for k, v := range (ovsdbRow{"m": value}).strMap("m") {
+				if m[k] == v {
+					delete(m, k)
+				}
+			}
+		default:
+			for _, k := range setElems(value) {
+				delete(m, atomString(k))
+			}
+		}
+		return ovsdbMap(m), nil
+	}
+
+	elems := []any{}
+	for _, e := range setElems(current) {
+		if mutator == "delete" && containsElem(setElems(value), e) {
+			continue
+		}
+		elems = append(elems, e)
+	}
+	if mutator == "insert" {
+		for _, e := range setElems(value) {
+			if !containsElem(elems, e) {
+				elems = append(elems, e)
+			}
+		}
+	}
+	return ovsdbSet(elems...), nil
+}
+
+func fakeMatches(row ovsdbRow, conditions []any) bool {
+	for _, c := range conditions {
+		condition := c.([]any)
+		value := row[condition[0].(string)]
+		switch condition[1] {
+		case "==":
+			if !fakeEqual(value, condition[2]) {
+				return false
+			}
+		case "!=":
+			if fakeEqual(value, condition[2]) {
+				return false
+			}
+		case "includes":
+			for _, e := range setElems(condition[2]) {
+				if !containsElem(setElems(value), e) {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func fakeEqual(a, b any) bool {
+	return canonical(a) == canonical(b)
+}
+
+// canonical encodes a value so that equal OVSDB values (a set in any order, an atom and a set
+// holding only that atom, a missing column and an empty set) have the same representation.
+func canonical(value any) string {
+	if row, ok := value.(map[string]any); ok {
+		keys := []string{}
+		for k := range row {
+			keys = append(keys, k+"="+canonical(row[k]))
+		}
+		sort.Strings(keys)
+		return "{" + strings.Join(keys, ",") + "}"
+	}
+	if row, ok := value.(ovsdbRow); ok {
+		return canonical(map[string]any(row))
+	}
+	if isMap(value) {
+		m := ovsdbRow{"m": value}.strMap("m")
+		keys := []string{}
+		for k, v := range m {
+			keys = append(keys, k+"="+v)
+		}
+		sort.Strings(keys)
+		return "map[" + strings.Join(keys, ",") + "]"
+	}
+	elems := []string{}
+	for _, e := range setElems(value) {
+		b, _ := json.Marshal(atomCanonical(e))
+		elems = append(elems, string(b))
+	}
+	sort.Strings(elems)
+	return "[" + strings.Join(elems, ",") + "]"
+}
+
+func atomCanonical(e any) any {
+	switch v := e.(type) {
+	case json.Number:
+		return v.String()
+	case int, int64, float64:
+		return fmt.Sprint(v)
+	}
+	return e
+}
+
+func containsElem(elems []any, e any) bool {
+	for _, x := range elems {
+		if reflect.DeepEqual(atomCanonical(x), atomCanonical(e)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMap(value any) bool {
+	v, ok := value.([]any)
+	return ok && len(v) == 2 && v[0] == "map"
+}
+
+func resolveNames(value any, names map[string]string) any {
+	list, ok := value.([]any)
+	if !ok {
+		return value
+	}
+	if len(list) == 2 && list[0] == "named-uuid" {
+		return uuidAtom(names[list[1].(string)])
+	}
+	resolved := make([]any, len(list))
+	for i, v := range list {
+		resolved[i] = resolveNames(v, names)
+	}
+	return resolved
+}
+
+func project(row ovsdbRow, columns any) map[string]any {
+	out := make(map[string]any)
+	if columns == nil {
+		for k, v := range row {
+			out[k] = v
+		}
+		return out
+	}
+	for _, c := range columns.([]any) {
+		if v, ok := row[c.(string)]; ok {
+			out[c.(string)] = v
+		} else {
+			out[c.(string)] = ovsdbSet()
+		}
+	}
+	return out
+}
+
+func copyRow(row ovsdbRow) ovsdbRow {
+	b, _ := json.Marshal(row)
+	var c ovsdbRow
+	decodeOvsdb(b, &c)
+	return c
+}
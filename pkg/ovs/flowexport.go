@@ -0,0 +1,122 @@
+package ovs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// flowCollector is one of the sFlow, IPFIX or NetFlow rows of the bridge. Column is both the Bridge column
+// that references it and, case aside, the table it is in.
+type flowCollector struct {
+	column  string
+	table   string
+	enabled bool
+	targets []string
+	// options are the columns besides targets that are set, integers left out when 0 and strings when empty
+	options map[string]any
+}
+
+// flowCollectors returns the three collectors of the bridge, the ones not in export disabled.
+func flowCollectors(export plsv1.FlowExport) []flowCollector {
+	sflow := flowCollector{column: "sflow", table: "sFlow", options: map[string]any{}}
+	if export.Sflow != nil {
+		sflow.enabled = true
+		sflow.targets = export.Sflow.Targets
+		setIntOption(sflow.options, "sampling", export.Sflow.Sampling)
+		setIntOption(sflow.options, "polling", export.Sflow.Polling)
+		setIntOption(sflow.options, "header", export.Sflow.Header)
+		if export.Sflow.Agent != "" {
+			sflow.options["agent"] = export.Sflow.Agent
+		}
+	}
+	ipfix := flowCollector{column: "ipfix", table: "IPFIX", options: map[string]any{}}
+	if export.Ipfix != nil {
+		ipfix.enabled = true
+		ipfix.targets = export.Ipfix.Targets
+		setIntOption(ipfix.options, "sampling", export.Ipfix.Sampling)
+		setIntOption(ipfix.options, "cache_active_timeout", export.Ipfix.CacheActiveTimeout)
+		setIntOption(ipfix.options, "cache_max_flows", export.Ipfix.CacheMaxFlows)
+	}
+	netflow := flowCollector{column: "netflow", table: "NetFlow", options: map[string]any{}}
+	if export.Netflow != nil {
+		netflow.enabled = true
+		netflow.targets = export.Netflow.Targets
+		setIntOption(netflow.options, "active_timeout", export.Netflow.ActiveTimeout)
+	}
+	return []flowCollector{sflow, ipfix, netflow}
+}
+
+func setIntOption(options map[string]any, column string, value int) {
+	if value > 0 {
+		options[column] = value
+	}
+}
+
+// validateFlowExport checks that every enabled collector has valid ip:port targets and no negative settings.
+func validateFlowExport(export plsv1.FlowExport) error {
+	negative := false
+	if export.Sflow != nil {
+		negative = export.Sflow.Sampling < 0 || export.Sflow.Polling < 0 || export.Sflow.Header < 0
+	}
+	if export.Ipfix != nil {
+		negative = negative || export.Ipfix.Sampling < 0 || export.Ipfix.CacheActiveTimeout < 0 || export.Ipfix.CacheMaxFlows < 0
+	}
+	if export.Netflow != nil {
+		negative = negative || export.Netflow.ActiveTimeout < 0
+	}
+	if negative {
+		return fmt.Errorf("flow export settings must not be negative")
+	}
+
+	for _, collector := range flowCollectors(export) {
+		if !collector.enabled {
+			continue
+		}
+		if len(collector.targets) == 0 {
+			return fmt.Errorf("%s export has no targets", collector.column)
+		}
+		for _, target := range collector.targets {
+			host, port, err := net.SplitHostPort(target)
+			if err != nil {
+				return fmt.Errorf("invalid %s target %s: %v", collector.column, target, err)
+			}
+			if net.ParseIP(host) == nil {
+				return fmt.Errorf("invalid %s target %s: %s is not an ip address", collector.column, target, host)
+			}
+			if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+				return fmt.Errorf("invalid %s target %s: bad port %s", collector.column, target, port)
+			}
+		}
+	}
+	return nil
+}
+
+// createFlowCollectorArgs returns the command that creates the row of the collector with the given record name.
+func createFlowCollectorArgs(collector flowCollector, id string) []string {
+	targets := []string{}
+	for _, target := range collector.targets {
+		targets = append(targets, strconv.Quote(target))
+	}
+	args := []string{"--id=" + id, "create", collector.column, fmt.Sprintf("targets=[%s]", strings.Join(targets, ","))}
+	for _, column := range sortedKeys(collector.options) {
+		value := fmt.Sprint(collector.options[column])
+		if s, ok := collector.options[column].(string); ok {
+			value = strconv.Quote(s)
+		}
+		args = append(args, fmt.Sprintf("%s=%s", column, value))
+	}
+	return args
+}
+
+// flowCollectorRow returns the row of the collector in its table.
+func flowCollectorRow(collector flowCollector) map[string]any {
+	row := map[string]any{"targets": ovsdbStringSet(collector.targets)}
+	for column, value := range collector.options {
+		row[column] = value
+	}
+	return row
+}
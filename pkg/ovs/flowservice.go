@@ -0,0 +1,282 @@
+package ovs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OPENFLOW_VERSION is the protocol ovs-ofctl speaks to the bridges, which are configured with it.
+const OPENFLOW_VERSION = "OpenFlow13"
+
+// DEFAULT_FLOW_PRIORITY is the priority OVS gives to flows that do not set one.
+const DEFAULT_FLOW_PRIORITY = 32768
+
+// Flow is an OpenFlow flow entry. Match and Actions use the ovs-ofctl syntax,
+// e.g. Match "in_port=1,dl_type=0x0800" and Actions "output:2".
+type Flow struct {
+	Cookie uint64
+	Table  int
+	// Priority of the flow. nil is DEFAULT_FLOW_PRIORITY, as OVS does for flows that do not set one
+	Priority    *int
+	Match       string
+	Actions     string
+	IdleTimeout int
+	HardTimeout int
+	// counters, only filled in by DumpFlows
+	Packets  uint64
+	Bytes    uint64
+	Duration time.Duration
+}
+
+// String returns the flow in the format add-flow and mod-flows expect.
+func (flow Flow) String() string {
+	fields := []string{}
+	if flow.Cookie != 0 {
+		fields = append(fields, fmt.Sprintf("cookie=%#x", flow.Cookie))
+	}
+	priority := DEFAULT_FLOW_PRIORITY
+	if flow.Priority != nil {
+		priority = *flow.Priority
+	}
+	fields = append(fields, fmt.Sprintf("table=%d", flow.Table), fmt.Sprintf("priority=%d", priority))
+	if flow.IdleTimeout != 0 {
+		fields = append(fields, fmt.Sprintf("idle_timeout=%d", flow.IdleTimeout))
+	}
+	if flow.HardTimeout != 0 {
+		fields = append(fields, fmt.Sprintf("hard_timeout=%d", flow.HardTimeout))
+	}
+	if flow.Match != "" {
+		fields = append(fields, flow.Match)
+	}
+	return strings.Join(fields, ",") + " actions=" + flow.Actions
+}
+
+// PortStats are the counters of a port as reported by dump-ports.
+// Counters the datapath does not support are reported as 0.
+type PortStats struct {
+	Port       string
+	RxPackets  uint64
+	RxBytes    uint64
+	RxDropped  uint64
+	RxErrors   uint64
+	RxFrameErr uint64
+	RxOverErr  uint64
+	RxCrcErr   uint64
+	TxPackets  uint64
+	TxBytes    uint64
+	TxDropped  uint64
+	TxErrors   uint64
+	Collisions uint64
+	Duration   time.Duration
+}
+
+// FlowService manages the OpenFlow tables of the bridges through ovs-ofctl, to inspect
+// what the SDN controller installed or to push static rules when it is unavailable.
+type FlowService struct {
+	exec Client
+}
+
+func NewFlowService() FlowService {
+	return FlowService{exec: NewClient(OvsOfctlClient)}
+}
+
+func NewSudoFlowService() FlowService {
+	return FlowService{exec: NewSudoClient(OvsOfctlClient)}
+}
+
+func (flowService *FlowService) run(args ...string) ([]byte, error) {
+	output, err := flowService.exec.CombinedOutput(append([]string{"-O", OPENFLOW_VERSION}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s error: %v\nOutput: %s", args[0], err, output)
+	}
+	return output, nil
+}
+
+func (flowService *FlowService) AddFlow(bridgeName string, flow Flow) error {
+	_, err := flowService.run("add-flow", bridgeName, flow.String())
+	return err
+}
+
+// ModFlows changes the actions of the flows matching the flow's table and match.
+// With strict set only the flow with the exact same match and priority is modified.
+func (flowService *FlowService) ModFlows(bridgeName string, flow Flow, strict bool) error {
+	if strict {
+		_, err := flowService.run("--strict", "mod-flows", bridgeName, flow.String())
+		return err
+	}
+	_, err := flowService.run("mod-flows", bridgeName, flow.String())
+	return err
+}
+
+// DeleteFlows deletes the flows matching match, in the ovs-ofctl syntax. An empty match deletes every flow of the bridge.
+func (flowService *FlowService) DeleteFlows(bridgeName, match string) error {
+	if match == "" {
+		_, err := flowService.run("del-flows", bridgeName)
+		return err
+	}
+	_, err := flowService.run("del-flows", bridgeName, match)
+	return err
+}
+
+func (flowService *FlowService) DumpFlows(bridgeName string) ([]Flow, error) {
+	output, err := flowService.run("dump-flows", bridgeName)
+	if err != nil {
+		return nil, err
+	}
+	return parseFlows(string(output))
+}
+
+func (flowService *FlowService) DumpPorts(bridgeName string) ([]PortStats, error) {
+	output, err := flowService.run("dump-ports", bridgeName)
+	if err != nil {
+		return nil, err
+	}
+	return parsePortStats(string(output))
+}
+
+// parseFlows parses the dump-flows output, made of a reply header followed by one flow per line:
+//
+//	cookie=0x0, duration=5.123s, table=0, n_packets=10, n_bytes=840, priority=100,in_port=1 actions=output:2
+func parseFlows(output string) ([]Flow, error) {
+	flows := []Flow{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "OFPST_") || strings.HasPrefix(line, "NXST_") {
+			continue
+		}
+		flow, err := parseFlow(line)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+func parseFlow(line string) (Flow, error) {
+	priority := DEFAULT_FLOW_PRIORITY
+	flow := Flow{Priority: &priority}
+	var fields string
+	if i := strings.Index(line, " actions="); i >= 0 {
+		fields, flow.Actions = line[:i], line[i+len(" actions="):]
+	} else if strings.HasPrefix(line, "actions=") {
+		flow.Actions = strings.TrimPrefix(line, "actions=")
+	} else {
+		return flow, fmt.Errorf("could not parse flow %q: no actions", line)
+	}
+
+	match := []string{}
+	for _, field := range strings.Split(strings.ReplaceAll(fields, ", ", ","), ",") {
+		if field == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(field, "=")
+		var err error
+		switch key {
+		case "cookie":
+			flow.Cookie, err = strconv.ParseUint(value, 0, 64)
+		case "duration":
+			flow.Duration, err = time.ParseDuration(value)
+		case "table":
+			flow.Table, err = strconv.Atoi(value)
+		case "n_packets":
+			flow.Packets, err = strconv.ParseUint(value, 10, 64)
+		case "n_bytes":
+			flow.Bytes, err = strconv.ParseUint(value, 10, 64)
+		case "priority":
+			priority, err = strconv.Atoi(value)
+		case "idle_timeout":
+			flow.IdleTimeout, err = strconv.Atoi(value)
+		case "hard_timeout":
+			flow.HardTimeout, err = strconv.Atoi(value)
+		case "idle_age", "hard_age", "importance", "reset_counts", "send_flow_rem", "no_packet_counts", "no_byte_counts":
+			// flow flags and ages, not part of the match
+		default:
+			match = append(match, field)
+		}
+		if err != nil {
+			return flow, fmt.Errorf("could not parse %s of flow %q: %v", key, line, err)
+		}
+	}
+	flow.Match = strings.Join(match, ",")
+	return flow, nil
+}
+
+// parsePortStats parses the dump-ports output, where every port spans several lines:
+//
+//	port  1: rx pkts=10, bytes=840, drop=0, errs=0, frame=0, over=0, crc=0
+//	         tx pkts=5, bytes=420, drop=0, errs=0, coll=0
+//	         duration=10.5s
+func parsePortStats(output string) ([]PortStats, error) {
+	stats := []PortStats{}
+	var current *PortStats
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "port ") {
+			name, rest, found := strings.Cut(strings.TrimPrefix(line, "port "), ":")
+			if !found {
+				return nil, fmt.Errorf("could not parse port stats %q", line)
+			}
+			stats = append(stats, PortStats{Port: strings.Trim(strings.TrimSpace(name), `"`)})
+			current = &stats[len(stats)-1]
+			line = strings.TrimSpace(rest)
+		}
+		if current == nil || line == "" {
+			continue
+		}
+
+		direction, counters, _ := strings.Cut(line, " ")
+		if strings.HasPrefix(line, "duration=") {
+			duration, err := time.ParseDuration(strings.TrimPrefix(line, "duration="))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse duration of port %s: %v", current.Port, err)
+			}
+			current.Duration = duration
+			continue
+		}
+		for _, counter := range strings.Split(counters, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(counter), "=")
+			n, err := parseCounter(value)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse %s %s of port %s: %v", direction, key, current.Port, err)
+			}
+			switch direction + " " + key {
+			case "rx pkts":
+				current.RxPackets = n
+			case "rx bytes":
+				current.RxBytes = n
+			case "rx drop":
+				current.RxDropped = n
+			case "rx errs":
+				current.RxErrors = n
+			case "rx frame":
+				current.RxFrameErr = n
+			case "rx over":
+				current.RxOverErr = n
+			case "rx crc":
+				current.RxCrcErr = n
+			case "tx pkts":
+				current.TxPackets = n
+			case "tx bytes":
+				current.TxBytes = n
+			case "tx drop":
+				current.TxDropped = n
+			case "tx errs":
+				current.TxErrors = n
+			case "tx coll":
+				current.Collisions = n
+			}
+		}
+	}
+	return stats, nil
+}
+
+// parseCounter parses a dump-ports counter, which is "?" when the datapath does not support it.
+func parseCounter(value string) (uint64, error) {
+	if value == "?" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
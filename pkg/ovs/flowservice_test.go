@@ -0,0 +1,140 @@
+package ovs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func priority(p int) *int {
+	return &p
+}
+
+func TestAddFlow(t *testing.T) {
+	key := "-O OpenFlow13 add-flow br0 table=0,priority=100,in_port=1 actions=output:2"
+	mock := &MockClient{
+		Commands: map[string][]byte{key: []byte("")},
+		Errors:   map[string]error{},
+	}
+	svc := FlowService{exec: mock}
+	if err := svc.AddFlow("br0", Flow{Priority: priority(100), Match: "in_port=1", Actions: "output:2"}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mock.Called) != 1 || mock.Called[0] != key {
+		t.Errorf("unexpected command: %v", mock.Called)
+	}
+}
+
+func TestFlowStringPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		flow Flow
+		want string
+	}{
+		{"unset", Flow{Actions: "NORMAL"}, "table=0,priority=32768 actions=NORMAL"},
+		{"table miss", Flow{Priority: priority(0), Actions: "drop"}, "table=0,priority=0 actions=drop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flow.String(); got != tt.want {
+				t.Errorf("String(): got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteFlows(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := FlowService{exec: mock}
+	if err := svc.DeleteFlows("br0", "in_port=1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.DeleteFlows("br0", ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mock.Called[0] != "-O OpenFlow13 del-flows br0 in_port=1" || mock.Called[1] != "-O OpenFlow13 del-flows br0" {
+		t.Errorf("unexpected commands: %v", mock.Called)
+	}
+}
+
+func TestModFlows(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := FlowService{exec: mock}
+	flow := Flow{Table: 1, Priority: priority(10), Match: "dl_vlan=10", Actions: "drop"}
+	if err := svc.ModFlows("br0", flow, true); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mock.Called[0] != "-O OpenFlow13 --strict mod-flows br0 table=1,priority=10,dl_vlan=10 actions=drop" {
+		t.Errorf("unexpected command: %v", mock.Called)
+	}
+}
+
+func TestDumpFlows(t *testing.T) {
+	raw := `OFPST_FLOW reply (OF1.3) (xid=0x2):
+ cookie=0x1f, duration=5.123s, table=0, n_packets=10, n_bytes=840, idle_timeout=30, priority=100,ip,in_port=1,nw_dst=10.0.0.0/24 actions=output:2
+ cookie=0x0, duration=1.5s, table=1, n_packets=0, n_bytes=0, reset_counts actions=NORMAL
+ cookie=0x0, duration=120s, table=0, n_packets=3, n_bytes=180, priority=0 actions=CONTROLLER:65535
+`
+	mock := &MockClient{
+		Commands: map[string][]byte{"-O OpenFlow13 dump-flows br0": []byte(raw)},
+		Errors:   map[string]error{},
+	}
+	svc := FlowService{exec: mock}
+	flows, err := svc.DumpFlows("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(flows) != 3 {
+		t.Fatalf("expected 3 flows, got: %d", len(flows))
+	}
+
+	expected := Flow{Cookie: 0x1f, Table: 0, Priority: priority(100), Match: "ip,in_port=1,nw_dst=10.0.0.0/24", Actions: "output:2",
+		IdleTimeout: 30, Packets: 10, Bytes: 840, Duration: 5123 * time.Millisecond}
+	if !reflect.DeepEqual(flows[0], expected) {
+		t.Errorf("unexpected flow: %+v", flows[0])
+	}
+	if flows[1].Table != 1 || *flows[1].Priority != DEFAULT_FLOW_PRIORITY || flows[1].Match != "" || flows[1].Actions != "NORMAL" {
+		t.Errorf("unexpected flow: %+v", flows[1])
+	}
+	if *flows[2].Priority != 0 || flows[2].Actions != "CONTROLLER:65535" || flows[2].Packets != 3 {
+		t.Errorf("unexpected flow: %+v", flows[2])
+	}
+}
+
+func TestDumpPorts(t *testing.T) {
+	raw := `OFPST_PORT reply (OF1.3) (xid=0x2): 2 ports
+  port LOCAL: rx pkts=0, bytes=0, drop=0, errs=0, frame=0, over=0, crc=0
+           tx pkts=0, bytes=0, drop=?, errs=0, coll=0
+           duration=1234.567s
+  port  1: rx pkts=10, bytes=840, drop=1, errs=2, frame=3, over=4, crc=5
+           tx pkts=5, bytes=420, drop=6, errs=7, coll=8
+           duration=10.5s
+`
+	mock := &MockClient{
+		Commands: map[string][]byte{"-O OpenFlow13 dump-ports br0": []byte(raw)},
+		Errors:   map[string]error{},
+	}
+	svc := FlowService{exec: mock}
+	stats, err := svc.DumpPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 ports, got: %d", len(stats))
+	}
+	if stats[0].Port != "LOCAL" || stats[0].TxDropped != 0 {
+		t.Errorf("unexpected port stats: %+v", stats[0])
+	}
+
+	expected := PortStats{Port: "1", RxPackets: 10, RxBytes: 840, RxDropped: 1, RxErrors: 2, RxFrameErr: 3, RxOverErr: 4, RxCrcErr: 5,
+		TxPackets: 5, TxBytes: 420, TxDropped: 6, TxErrors: 7, Collisions: 8, Duration: 10500 * time.Millisecond}
+	if stats[1] != expected {
+		t.Errorf("unexpected port stats: %+v", stats[1])
+	}
+}
@@ -0,0 +1,204 @@
+package ovs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// MIRROR_EXPIRES_EXTERNAL_ID is the external_ids key of a mirror where its expiry is kept, in RFC 3339,
+// so it outlives talpa restarts.
+const MIRROR_EXPIRES_EXTERNAL_ID = "talpa-expires"
+
+// MIRROR_EXPIRY_INTERVAL is how often the expired mirrors are looked for and removed.
+const MIRROR_EXPIRY_INTERVAL = 10 * time.Second
+
+// ErrInvalidMirror is returned when a mirror cannot be created as requested, e.g. because it selects no traffic.
+var ErrInvalidMirror = errors.New("invalid mirror")
+
+// ErrMirrorNotFound is returned when deleting a mirror the bridge does not have.
+var ErrMirrorNotFound = errors.New("mirror not found")
+
+// mirrorColumns are the Mirror columns a mirror is read from.
+var mirrorColumns = []string{"name", "select_all", "select_src_port", "select_dst_port", "select_vlan", "output_port", "external_ids"}
+
+// validateMirror checks that the mirror selects some traffic and has somewhere to send it.
+func validateMirror(mirror plsv1.Mirror) error {
+	if mirror.Name == "" {
+		return fmt.Errorf("%w: the mirror has no name", ErrInvalidMirror)
+	}
+	if mirror.OutputPort == "" {
+		return fmt.Errorf("%w: mirror %s has no output port", ErrInvalidMirror, mirror.Name)
+	}
+	if !mirror.SelectAll && len(mirror.SelectSrcPorts) == 0 && len(mirror.SelectDstPorts) == 0 && len(mirror.SelectVlans) == 0 {
+		return fmt.Errorf("%w: mirror %s selects no traffic", ErrInvalidMirror, mirror.Name)
+	}
+	for _, portName := range mirrorSelectedPorts(mirror) {
+		if portName == mirror.OutputPort {
+			return fmt.Errorf("%w: mirror %s cannot select its output port %s", ErrInvalidMirror, mirror.Name, portName)
+		}
+	}
+	for _, vlan := range mirror.SelectVlans {
+		if vlan < 0 || vlan > plsv1.MAX_VLAN_ID {
+			return fmt.Errorf("%w: invalid vlan %d for mirror %s", ErrInvalidMirror, vlan, mirror.Name)
+		}
+	}
+	return nil
+}
+
+// ExpireMirrors removes the mirrors of the switch once they expire, looking for them every
+// MIRROR_EXPIRY_INTERVAL until ctx is done.
+func ExpireMirrors(ctx context.Context, vs VirtualSwitch) {
+	ticker := time.NewTicker(MIRROR_EXPIRY_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := vs.DeleteExpiredMirrors(now)
+			if err != nil {
+				log.Printf("mirror expiry of %s: %v", vs.bridge.Name, err)
+			}
+			if len(deleted) > 0 {
+				log.Printf("removed the expired mirrors %v of %s", deleted, vs.bridge.Name)
+			}
+		}
+	}
+}
+
+// mirrorSelectedPorts returns the ports the mirror selects traffic from, sorted and without duplicates.
+func mirrorSelectedPorts(mirror plsv1.Mirror) []string {
+	selected := make(map[string]bool)
+	for _, portName := range append(append([]string{}, mirror.SelectSrcPorts...), mirror.SelectDstPorts...) {
+		selected[portName] = true
+	}
+	return sortedKeys(selected)
+}
+
+// mirrorPorts returns every port the mirror references, the output port included.
+func mirrorPorts(mirror plsv1.Mirror) []string {
+	return append(mirrorSelectedPorts(mirror), mirror.OutputPort)
+}
+
+// mirrorExpired reports whether the mirror has an expiry and it is not after now.
+func mirrorExpired(mirror plsv1.Mirror, now time.Time) bool {
+	return mirror.ExpiresAt != nil && !mirror.ExpiresAt.After(now)
+}
+
+// mirrorExternalIds returns the external_ids column of the mirror row.
+func mirrorExternalIds(mirror plsv1.Mirror) map[string]string {
+	externalIds := map[string]string{}
+	if mirror.ExpiresAt != nil {
+		externalIds[MIRROR_EXPIRES_EXTERNAL_ID] = mirror.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return externalIds
+}
+
+func sortedVlans(mirror plsv1.Mirror) []int {
+	vlans := append([]int{}, mirror.SelectVlans...)
+	sort.Ints(vlans)
+	return vlans
+}
+
+// createMirrorArgs returns the commands that create the mirror and add it to the bridge. The ports it
+// references are looked up by name with "get" commands, which fail if any of them does not exist.
+func createMirrorArgs(bridgeName string, mirror plsv1.Mirror) [][]string {
+	commands := [][]string{}
+	ids := make(map[string]string)
+	for i, portName := range mirrorPorts(mirror) {
+		if _, ok := ids[portName]; ok {
+			continue
+		}
+		ids[portName] = fmt.Sprintf("@mirrorport%d", i)
+		commands = append(commands, []string{"--id=" + ids[portName], "get", "port", portName})
+	}
+	refs := func(portNames []string) string {
+		values := []string{}
+		for _, portName := range portNames {
+			values = append(values, ids[portName])
+		}
+		sort.Strings(values)
+		return "[" + strings.Join(values, ",") + "]"
+	}
+
+	vlans := []string{}
+	for _, vlan := range sortedVlans(mirror) {
+		vlans = append(vlans, strconv.Itoa(vlan))
+	}
+	create := []string{"--id=@mirror", "create", "mirror", "name=" + mirror.Name,
+		"select_src_port=" + refs(mirror.SelectSrcPorts),
+		"select_dst_port=" + refs(mirror.SelectDstPorts),
+		fmt.Sprintf("select_vlan=[%s]", strings.Join(vlans, ",")),
+		fmt.Sprintf("select_all=%t", mirror.SelectAll),
+		"output_port=" + ids[mirror.OutputPort],
+	}
+	externalIds := mirrorExternalIds(mirror)
+	for _, k := range sortedKeys(externalIds) {
+		create = append(create, fmt.Sprintf("external_ids:%s=%s", k, externalIds[k]))
+	}
+	return append(commands, create, []string{"add", "bridge", bridgeName, "mirrors", "@mirror"})
+}
+
+// mirrorRow returns the Mirror row of the mirror, referencing the ports by the given uuids.
+func mirrorRow(mirror plsv1.Mirror, portUUIDs map[string]string) map[string]any {
+	refs := func(portNames []string) []any {
+		set := []any{}
+		for _, portName := range portNames {
+			set = append(set, uuidAtom(portUUIDs[portName]))
+		}
+		return ovsdbSet(set...)
+	}
+	vlans := []any{}
+	for _, vlan := range sortedVlans(mirror) {
+		vlans = append(vlans, vlan)
+	}
+	return map[string]any{
+		"name":            mirror.Name,
+		"select_src_port": refs(mirror.SelectSrcPorts),
+		"select_dst_port": refs(mirror.SelectDstPorts),
+		"select_vlan":     ovsdbSet(vlans...),
+		"select_all":      mirror.SelectAll,
+		"output_port":     uuidAtom(portUUIDs[mirror.OutputPort]),
+		"external_ids":    ovsdbMap(mirrorExternalIds(mirror)),
+	}
+}
+
+// mirrorFromRow builds a mirror from its Mirror row, naming its ports with portNames, which is keyed by port uuid.
+func mirrorFromRow(row ovsdbRow, portNames map[string]string) plsv1.Mirror {
+	names := func(column string) []string {
+		values := []string{}
+		for _, id := range row.uuids(column) {
+			values = append(values, portNames[id])
+		}
+		sort.Strings(values)
+		return values
+	}
+	mirror := plsv1.Mirror{
+		Name:           row.str("name"),
+		SelectSrcPorts: names("select_src_port"),
+		SelectDstPorts: names("select_dst_port"),
+	}
+	selectAll := row.elems("select_all")
+	mirror.SelectAll = len(selectAll) > 0 && selectAll[0] == true
+	for _, e := range row.elems("select_vlan") {
+		if vlan, ok := atomInt(e); ok {
+			mirror.SelectVlans = append(mirror.SelectVlans, int(vlan))
+		}
+	}
+	sort.Ints(mirror.SelectVlans)
+	if output := row.uuids("output_port"); len(output) > 0 {
+		mirror.OutputPort = portNames[output[0]]
+	}
+	if expires, err := time.Parse(time.RFC3339, row.strMap("external_ids")[MIRROR_EXPIRES_EXTERNAL_ID]); err == nil {
+		mirror.ExpiresAt = &expires
+	}
+	return mirror
+}
@@ -0,0 +1,56 @@
+package ovs
+
+import (
+	"fmt"
+	"net/netip"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// TunnelOverhead returns the bytes the tunnel adds to every frame it carries: the outer IP header, the
+// headers of the encapsulation and the ethernet header of the frame itself, which its MTU does not count.
+func TunnelOverhead(vxlan plsv1.Vxlan) int {
+	overhead := 20 + 14
+	if ip, err := netip.ParseAddr(vxlan.RemoteIp); err == nil && ip.Is6() && !ip.Is4In6() {
+		overhead = 40 + 14
+	}
+	switch tunnelType(vxlan) {
+	case plsv1.TUNNEL_GRE:
+		// GRE with the key talpa always sets, either fixed or from the flow
+		return overhead + 8
+	case plsv1.TUNNEL_STT:
+		// the TCP like header and the STT header
+		return overhead + 20 + 18
+	default:
+		// UDP and the VXLAN or, without options, GENEVE header
+		return overhead + 8 + 8
+	}
+}
+
+// TunnelMtu returns the largest MTU of the tunnel whose frames still fit in the MTU of the underlay.
+func TunnelMtu(underlayMtu int, vxlan plsv1.Vxlan) int {
+	return underlayMtu - TunnelOverhead(vxlan)
+}
+
+// setMtuArgs sets the mtu_request of the interface, or clears it when mtu is 0.
+func setMtuArgs(interfaceName string, mtu int) []string {
+	mtuRequest := "mtu_request=[]"
+	if mtu > 0 {
+		mtuRequest = fmt.Sprintf("mtu_request=%d", mtu)
+	}
+	return []string{"set", "interface", interfaceName, mtuRequest}
+}
+
+// mtuRequest returns the mtu_request column of the interface row, an empty set clearing it.
+func mtuRequest(mtu int) []any {
+	if mtu > 0 {
+		return ovsdbSet(mtu)
+	}
+	return ovsdbSet()
+}
+
+// requestedMtu returns the mtu_request of the interface row, 0 if it has none.
+func requestedMtu(row ovsdbRow) int {
+	mtu, _ := row.integer("mtu_request")
+	return int(mtu)
+}
@@ -0,0 +1,436 @@
+package ovs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the small subset of the OVSDB management protocol (RFC 7047)
+// that talpa needs to talk to ovsdb-server directly, without forking ovs-vsctl.
+
+const (
+	DEFAULT_OVSDB_ADDRESS = "unix:/var/run/openvswitch/db.sock"
+	OVSDB_DATABASE        = "Open_vSwitch"
+	OVSDB_TIMEOUT         = 10 * time.Second
+)
+
+// ovsdbMessage is any JSON-RPC message exchanged with ovsdb-server: a request, a response or a notification.
+type ovsdbMessage struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+	Id     json.RawMessage `json:"id"`
+}
+
+// ovsdbConn is a JSON-RPC connection to ovsdb-server. Responses are matched to calls by id,
+// echo requests are answered automatically and any other server request is handed to notify.
+type ovsdbConn struct {
+	conn    net.Conn
+	enc     *json.Encoder
+	mu      sync.Mutex
+	pending map[string]chan ovsdbMessage
+	nextId  uint64
+	notify  func(method string, params json.RawMessage)
+	done    chan struct{}
+	err     error
+}
+
+// parseOvsdbAddress splits an ovs style remote ("unix:/path" or "tcp:host:port") into a network and an address.
+func parseOvsdbAddress(address string) (string, string, error) {
+	network, addr, ok := strings.Cut(address, ":")
+	if !ok || addr == "" {
+		return "", "", fmt.Errorf("invalid ovsdb address %q, expected unix:<path> or tcp:<host>:<port>", address)
+	}
+	switch network {
+	case "unix", "tcp":
+		return network, addr, nil
+	}
+	return "", "", fmt.Errorf("unsupported ovsdb connection method %q", network)
+}
+
+func dialOvsdb(address string, notify func(method string, params json.RawMessage)) (*ovsdbConn, error) {
+	network, addr, err := parseOvsdbAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, addr, OVSDB_TIMEOUT)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to ovsdb at %s: %v", address, err)
+	}
+	c := &ovsdbConn{
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		pending: make(map[string]chan ovsdbMessage),
+		notify:  notify,
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *ovsdbConn) readLoop() {
+	dec := json.NewDecoder(c.conn)
+	for {
+		var msg ovsdbMessage
+		if err := dec.Decode(&msg); err != nil {
+			c.fail(err)
+			return
+		}
+		switch {
+		case msg.Method == "echo":
+			c.send(map[string]any{"result": msg.Params, "error": nil, "id": msg.Id})
+		case msg.Method != "":
+			if c.notify != nil {
+				c.notify(msg.Method, msg.Params)
+			}
+		default:
+			c.mu.Lock()
+			ch, ok := c.pending[string(msg.Id)]
+			delete(c.pending, string(msg.Id))
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+	}
+}
+
+func (c *ovsdbConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+}
+
+func (c *ovsdbConn) send(msg any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(msg)
+}
+
+// call sends a request to ovsdb-server and waits for its result.
+func (c *ovsdbConn) call(method string, params ...any) (json.RawMessage, error) {
+	if params == nil {
+		params = []any{}
+	}
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("ovsdb connection closed: %v", c.err)
+	}
+	c.nextId++
+	id := strconv.FormatUint(c.nextId, 10)
+	ch := make(chan ovsdbMessage, 1)
+	c.pending[id] = ch
+	err := c.enc.Encode(map[string]any{"method": method, "params": params, "id": c.nextId})
+	c.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("could not send %s request: %v", method, err)
+	}
+
+	timer := time.NewTimer(OVSDB_TIMEOUT)
+	defer timer.Stop()
+	select {
+	case msg := <-ch:
+		if len(msg.Error) > 0 && string(msg.Error) != "null" {
+			return nil, fmt.Errorf("%s error: %s", method, msg.Error)
+		}
+		return msg.Result, nil
+	case <-c.done:
+		return nil, fmt.Errorf("ovsdb connection closed: %v", c.err)
+	case <-timer.C:
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("timed out waiting for %s response", method)
+	}
+}
+
+func (c *ovsdbConn) Close() error {
+	return c.conn.Close()
+}
+
+// transact runs the given operations as a single OVSDB transaction. If any of them fails the
+// whole transaction is aborted by the server and the error of the failing operation is returned.
+func (c *ovsdbConn) transact(ops ...ovsdbOp) ([]ovsdbResult, error) {
+	params := []any{OVSDB_DATABASE}
+	for _, op := range ops {
+		params = append(params, op)
+	}
+	raw, err := c.call("transact", params...)
+	if err != nil {
+		return nil, err
+	}
+	var results []ovsdbResult
+	if err = decodeOvsdb(raw, &results); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transact result: %v\nOutput: %s", err, raw)
+	}
+	for i, result := range results {
+		if result.Error == "" {
+			continue
+		}
+		if i < len(ops) {
+			return results, fmt.Errorf("%s on %s failed: %s: %s", ops[i]["op"], ops[i]["table"], result.Error, result.Details)
+		}
+		return results, fmt.Errorf("transaction commit failed: %s: %s", result.Error, result.Details)
+	}
+	if len(results) < len(ops) {
+		return results, fmt.Errorf("expected %d transact results, got %d", len(ops), len(results))
+	}
+	return results, nil
+}
+
+// decodeOvsdb unmarshals OVSDB JSON keeping integers as json.Number, so 64 bit counters are not rounded.
+func decodeOvsdb(raw []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// ovsdbOp is a single operation of a transact request.
+type ovsdbOp map[string]any
+
+type ovsdbResult struct {
+	UUID    []any      `json:"uuid,omitempty"`
+	Rows    []ovsdbRow `json:"rows,omitempty"`
+	Count   int        `json:"count,omitempty"`
+	Error   string     `json:"error,omitempty"`
+	Details string     `json:"details,omitempty"`
+}
+
+func opInsert(table string, row map[string]any, uuidName string) ovsdbOp {
+	op := ovsdbOp{"op": "insert", "table": table, "row": row}
+	if uuidName != "" {
+		op["uuid-name"] = uuidName
+	}
+	return op
+}
+
+func opSelect(table string, conditions []any, columns ...string) ovsdbOp {
+	op := ovsdbOp{"op": "select", "table": table, "where": nonNil(conditions)}
+	if len(columns) > 0 {
+		op["columns"] = columns
+	}
+	return op
+}
+
+func opUpdate(table string, conditions []any, row map[string]any) ovsdbOp {
+	return ovsdbOp{"op": "update", "table": table, "where": nonNil(conditions), "row": row}
+}
+
+func opMutate(table string, conditions []any, mutations ...any) ovsdbOp {
+	return ovsdbOp{"op": "mutate", "table": table, "where": nonNil(conditions), "mutations": nonNil(mutations)}
+}
+
+func opDelete(table string, conditions []any) ovsdbOp {
+	return ovsdbOp{"op": "delete", "table": table, "where": nonNil(conditions)}
+}
+
+// opAbsent makes the whole transaction fail if a row matching the conditions exists in table.
+func opAbsent(table string, conditions []any) ovsdbOp {
+	return ovsdbOp{"op": "wait", "table": table, "where": nonNil(conditions), "columns": []string{"_uuid"},
+		"until": "==", "rows": []any{}, "timeout": 0}
+}
+
+// opExists makes the whole transaction fail unless a row matching the conditions exists in table.
+func opExists(table string, conditions []any) ovsdbOp {
+	return ovsdbOp{"op": "wait", "table": table, "where": nonNil(conditions), "columns": []string{"_uuid"},
+		"until": "!=", "rows": []any{}, "timeout": 0}
+}
+
+func nonNil(v []any) []any {
+	if v == nil {
+		return []any{}
+	}
+	return v
+}
+
+func where(conditions ...[]any) []any {
+	w := []any{}
+	for _, c := range conditions {
+		w = append(w, c)
+	}
+	return w
+}
+
+func cond(column, function string, value any) []any {
+	return []any{column, function, value}
+}
+
+func mutation(column, mutator string, value any) []any {
+	return []any{column, mutator, value}
+}
+
+func ovsdbSet(elems ...any) []any {
+	return []any{"set", nonNil(elems)}
+}
+
+func ovsdbStringSet(elems []string) []any {
+	set := []any{}
+	for _, e := range elems {
+		set = append(set, e)
+	}
+	return ovsdbSet(set...)
+}
+
+func ovsdbMap(m map[string]string) []any {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := []any{}
+	for _, k := range keys {
+		pairs = append(pairs, []any{k, m[k]})
+	}
+	return []any{"map", pairs}
+}
+
+func namedUUID(name string) []any {
+	return []any{"named-uuid", name}
+}
+
+func uuidAtom(id string) []any {
+	return []any{"uuid", id}
+}
+
+// ovsdbRow is a table row as returned by a select operation, in OVSDB JSON notation.
+type ovsdbRow map[string]any
+
+func (r ovsdbRow) uuid() string {
+	return atomUUID(r["_uuid"])
+}
+
+// elems returns the elements of a set column. Single atoms are treated as a one element set.
+func (r ovsdbRow) elems(column string) []any {
+	return setElems(r[column])
+}
+
+func (r ovsdbRow) str(column string) string {
+	elems := r.elems(column)
+	if len(elems) == 0 {
+		return ""
+	}
+	s, _ := elems[0].(string)
+	return s
+}
+
+// integer returns the value of an integer column, and false if the (optional) column is empty.
+func (r ovsdbRow) integer(column string) (int64, bool) {
+	elems := r.elems(column)
+	if len(elems) == 0 {
+		return 0, false
+	}
+	return atomInt(elems[0])
+}
+
+func (r ovsdbRow) strings(column string) []string {
+	values := []string{}
+	for _, e := range r.elems(column) {
+		if s, ok := e.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func (r ovsdbRow) uuids(column string) []string {
+	ids := []string{}
+	for _, e := range r.elems(column) {
+		if id := atomUUID(e); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (r ovsdbRow) strMap(column string) map[string]string {
+	m := make(map[string]string)
+	value, ok := r[column].([]any)
+	if !ok || len(value) != 2 || value[0] != "map" {
+		return m
+	}
+	pairs, _ := value[1].([]any)
+	for _, p := range pairs {
+		pair, ok := p.([]any)
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		k := atomString(pair[0])
+		m[k] = atomString(pair[1])
+	}
+	return m
+}
+
+// intMap returns a map column with integer values, like the statistics of an interface.
+func (r ovsdbRow) intMap(column string) map[string]int64 {
+	m := make(map[string]int64)
+	for k, v := range r.strMap(column) {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			m[k] = i
+		}
+	}
+	return m
+}
+
+func setElems(value any) []any {
+	if value == nil {
+		return nil
+	}
+	if v, ok := value.([]any); ok && len(v) == 2 {
+		if v[0] == "set" {
+			elems, _ := v[1].([]any)
+			return elems
+		}
+	}
+	return []any{value}
+}
+
+func atomUUID(value any) string {
+	v, ok := value.([]any)
+	if !ok || len(v) != 2 || v[0] != "uuid" {
+		return ""
+	}
+	id, _ := v[1].(string)
+	return id
+}
+
+func atomInt(value any) (int64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	case float64:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func atomString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case []any:
+		if id := atomUUID(v); id != "" {
+			return id
+		}
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,927 @@
+package ovs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// OvsdbService manages the switch by speaking JSON-RPC directly to ovsdb-server,
+// instead of forking an ovs-vsctl process for every operation like OvsService does.
+type OvsdbService struct {
+	address string
+}
+
+func NewOvsdbService(address string) *OvsdbService {
+	if address == "" {
+		address = DEFAULT_OVSDB_ADDRESS
+	}
+	return &OvsdbService{address: address}
+}
+
+// query runs a read only transaction.
+func (ovsdbService *OvsdbService) query(ops ...ovsdbOp) (results []ovsdbResult, err error) {
+	start := time.Now()
+	defer func() { observe("ovsdb", start, err) }()
+	conn, err := dialOvsdb(ovsdbService.address, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.transact(ops...)
+}
+
+// apply runs a transaction that modifies the database and, like ovs-vsctl does, waits until
+// ovs-vswitchd has reconfigured itself with the changes, so interfaces exist when it returns.
+func (ovsdbService *OvsdbService) apply(ops ...ovsdbOp) (results []ovsdbResult, err error) {
+	start := time.Now()
+	defer func() { observe("ovsdb", start, err) }()
+	conn, err := dialOvsdb(ovsdbService.address, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ops = append(ops,
+		opMutate("Open_vSwitch", where(), mutation("next_cfg", "+=", 1)),
+		opSelect("Open_vSwitch", where(), "next_cfg"),
+	)
+	results, err = conn.transact(ops...)
+	if err != nil {
+		return results, err
+	}
+	cfg := results[len(results)-1]
+	results = results[:len(results)-2]
+	if len(cfg.Rows) == 0 {
+		// the database has not been initialized, so there is no ovs-vswitchd to wait for
+		return results, nil
+	}
+	nextCfg, _ := cfg.Rows[0].integer("next_cfg")
+
+	deadline := time.Now().Add(OVSDB_TIMEOUT)
+	for {
+		r, err := conn.transact(opSelect("Open_vSwitch", where(), "cur_cfg"))
+		if err != nil {
+			return results, err
+		}
+		if len(r[0].Rows) > 0 {
+			if curCfg, _ := r[0].Rows[0].integer("cur_cfg"); curCfg >= nextCfg {
+				return results, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return results, fmt.Errorf("timed out waiting for ovs-vswitchd to apply the changes")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (ovsdbService *OvsdbService) BridgeExists(bridgeName string) bool {
+	results, err := ovsdbService.query(opSelect("Bridge", where(cond("name", "==", bridgeName)), "name"))
+	return err == nil && len(results[0].Rows) > 0
+}
+
+func (ovsdbService *OvsdbService) AddBridge(bridgeName string) error {
+	_, err := ovsdbService.apply(
+		opAbsent("Bridge", where(cond("name", "==", bridgeName))),
+		opInsert("Interface", map[string]any{"name": bridgeName, "type": "internal"}, "iface"),
+		opInsert("Port", map[string]any{"name": bridgeName, "interfaces": ovsdbSet(namedUUID("iface"))}, "port"),
+		opInsert("Bridge", map[string]any{"name": bridgeName, "ports": ovsdbSet(namedUUID("port"))}, "bridge"),
+		opMutate("Open_vSwitch", where(), mutation("bridges", "insert", ovsdbSet(namedUUID("bridge")))),
+	)
+	if err != nil {
+		return fmt.Errorf("add-br error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) DeleteBridge(bridgeName string) error {
+	results, err := ovsdbService.query(opSelect("Bridge", where(cond("name", "==", bridgeName)), "_uuid"))
+	if err != nil {
+		return fmt.Errorf("del-br error: %v", err)
+	}
+	if len(results[0].Rows) == 0 {
+		return fmt.Errorf("del-br error: no bridge named %s", bridgeName)
+	}
+
+	// ports, interfaces and controllers are garbage collected by ovsdb-server once the bridge is unreferenced
+	_, err = ovsdbService.apply(
+		opMutate("Open_vSwitch", where(), mutation("bridges", "delete", ovsdbSet(uuidAtom(results[0].Rows[0].uuid())))),
+	)
+	if err != nil {
+		return fmt.Errorf("del-br error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetDatapathID(bridgeName, datapathId string) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetDatapathID(datapathId)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set bridge error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetDatapathType(bridgeName, datapathType string) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetDatapathType(datapathType)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set bridge error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetProtocol(bridgeName, protocol string) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetProtocol(protocol)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set bridge error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetController(bridgeName string, controller ...string) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetController(controller...)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set-controller error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetFailMode(bridgeName, failMode string) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetFailMode(failMode)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set-fail-mode error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetFlowExport(bridgeName string, export plsv1.FlowExport) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetFlowExport(export)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set flow export error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetSpanningTree(bridgeName string, st plsv1.SpanningTree) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetSpanningTree(st)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set spanning tree error: %v", err)
+	}
+	return nil
+}
+
+// GetSpanningTreeStatus returns the state of the spanning tree of the bridge and of its ports.
+func (ovsdbService *OvsdbService) GetSpanningTreeStatus(bridgeName string) (plsv1.SpanningTreeStatus, error) {
+	results, err := ovsdbService.query(opSelect("Bridge", where(cond("name", "==", bridgeName)), spanningTreeBridgeColumns...))
+	if err != nil {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("get spanning tree error: %v", err)
+	}
+	if len(results[0].Rows) == 0 {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("get spanning tree error: no bridge named %s", bridgeName)
+	}
+	ports, err := ovsdbService.bridgeRows(bridgeName, "ports", "Port", spanningTreePortColumns...)
+	if err != nil {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("get spanning tree error: %v", err)
+	}
+	return spanningTreeStatus(results[0].Rows[0], ports), nil
+}
+
+func (ovsdbService *OvsdbService) GetFailMode(bridgeName string) (string, error) {
+	results, err := ovsdbService.query(opSelect("Bridge", where(cond("name", "==", bridgeName)), "fail_mode"))
+	if err != nil {
+		return "", fmt.Errorf("get-fail-mode error: %v", err)
+	}
+	if len(results[0].Rows) == 0 {
+		return "", fmt.Errorf("get-fail-mode error: no bridge named %s", bridgeName)
+	}
+	return results[0].Rows[0].str("fail_mode"), nil
+}
+
+func (ovsdbService *OvsdbService) GetControllerStatus(bridgeName string) ([]ControllerStatus, error) {
+	statuses := []ControllerStatus{}
+	rows, err := ovsdbService.bridgeRows(bridgeName, "controller", "Controller", "target", "is_connected", "role", "status")
+	if err != nil {
+		return statuses, fmt.Errorf("list Controller error: %v", err)
+	}
+	for _, row := range rows {
+		statuses = append(statuses, controllerStatus(row))
+	}
+	return statuses, nil
+}
+
+func (ovsdbService *OvsdbService) CreateVxlan(bridgeName string, vxlan plsv1.Vxlan) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.CreateVxlan(vxlan)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("add-port error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) DeleteVxlan(bridgeName, vxlanId string) error {
+	return ovsdbService.DeletePort(bridgeName, vxlanId)
+}
+
+// CreatePatch adds both patch ports in a single transaction, so neither is left without its peer.
+func (ovsdbService *OvsdbService) CreatePatch(port, peer PatchPort) error {
+	ops := []ovsdbOp{}
+	for i, end := range [][2]PatchPort{{port, peer}, {peer, port}} {
+		ifaceName, portName := fmt.Sprintf("iface%d", i), fmt.Sprintf("port%d", i)
+		ops = append(ops,
+			opExists("Bridge", where(cond("name", "==", end[0].Bridge))),
+			opAbsent("Port", where(cond("name", "==", end[0].Name))),
+			opInsert("Interface", patchInterface(end[0], end[1]), ifaceName),
+			opInsert("Port", map[string]any{
+				"name": end[0].Name, "interfaces": ovsdbSet(namedUUID(ifaceName)), "external_ids": ovsdbMap(end[0].ExternalIds),
+			}, portName),
+			opMutate("Bridge", where(cond("name", "==", end[0].Bridge)), mutation("ports", "insert", ovsdbSet(namedUUID(portName)))),
+		)
+	}
+	if _, err := ovsdbService.apply(ops...); err != nil {
+		return fmt.Errorf("add-port error: %v", err)
+	}
+	return nil
+}
+
+// DeletePatch removes both patch ports in a single transaction, from whichever bridges they are on. The ones that are
+// already gone are skipped.
+func (ovsdbService *OvsdbService) DeletePatch(portName, peerName string) error {
+	results, err := ovsdbService.query(opSelect("Port", where(), "_uuid", "name"), opSelect("Bridge", where(), "name", "ports"))
+	if err != nil {
+		return fmt.Errorf("del-port error: %v", err)
+	}
+	patch := make(map[string]bool)
+	for _, row := range results[0].Rows {
+		if name := row.str("name"); name == portName || name == peerName {
+			patch[row.uuid()] = true
+		}
+	}
+	ops := []ovsdbOp{}
+	for _, bridge := range results[1].Rows {
+		for _, id := range bridge.uuids("ports") {
+			if patch[id] {
+				ops = append(ops, opMutate("Bridge", where(cond("name", "==", bridge.str("name"))), mutation("ports", "delete", ovsdbSet(uuidAtom(id)))))
+			}
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if _, err := ovsdbService.apply(ops...); err != nil {
+		return fmt.Errorf("del-port error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) DeletePort(bridgeName, portName string) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.DeletePort(portName)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("del-port error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) ModifyVxlan(vxlan plsv1.Vxlan) error {
+	_, err := ovsdbService.apply(
+		opExists("Interface", where(cond("name", "==", vxlan.VxlanId))),
+		opUpdate("Interface", where(cond("name", "==", vxlan.VxlanId)), vxlanInterface(vxlan)),
+	)
+	if err != nil {
+		return fmt.Errorf("set interface error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetMtu(interfaceName string, mtu int) error {
+	_, err := ovsdbService.apply(
+		opExists("Interface", where(cond("name", "==", interfaceName))),
+		opUpdate("Interface", where(cond("name", "==", interfaceName)), map[string]any{"mtu_request": mtuRequest(mtu)}),
+	)
+	if err != nil {
+		return fmt.Errorf("set interface error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetPathCost(portName string, cost int) error {
+	if _, err := ovsdbService.apply(pathCostOps(portName, cost)...); err != nil {
+		return fmt.Errorf("set port error: %v", err)
+	}
+	return nil
+}
+
+// SetExternalIds sets the given keys of the external_ids of the named Bridge, Port or Interface.
+func (ovsdbService *OvsdbService) SetExternalIds(table, record string, externalIds map[string]string) error {
+	if _, err := ovsdbService.apply(externalIdsOps(table, record, externalIds)...); err != nil {
+		return fmt.Errorf("set %s error: %v", table, err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) AddPort(bridgeName, portName string, netIndex int, internal bool) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.AddPort(portName, netIndex, internal)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("add-port error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) AddBond(bridgeName string, port plsv1.Port) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.AddBond(port)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("add-bond error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetPortQos(bridgeName, portName string, qos *plsv1.PortQos) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetPortQos(portName, qos)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set port qos error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) SetPortVlan(bridgeName string, port plsv1.Port) error {
+	txn := ovsdbService.NewTxn(bridgeName)
+	txn.SetPortVlan(port)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set port error: %v", err)
+	}
+	return nil
+}
+
+func (ovsdbService *OvsdbService) GetPortNumber(portName string) (int64, error) {
+	results, err := ovsdbService.query(opSelect("Interface", where(cond("name", "==", portName)), "ofport"))
+	if err != nil {
+		return 0, fmt.Errorf("get Interface error: %v", err)
+	}
+	if len(results[0].Rows) == 0 {
+		return 0, fmt.Errorf("get Interface error: no interface named %s", portName)
+	}
+
+	ofport, ok := results[0].Rows[0].integer("ofport")
+	if !ok {
+		return 0, fmt.Errorf("interface %s has no ofport assigned yet", portName)
+	}
+	return ofport, nil
+}
+
+// bridgeRows returns the rows of table referenced by the given column of the bridge.
+func (ovsdbService *OvsdbService) bridgeRows(bridgeName, column, table string, columns ...string) ([]ovsdbRow, error) {
+	results, err := ovsdbService.query(
+		opSelect("Bridge", where(cond("name", "==", bridgeName)), column),
+		opSelect(table, where(), append([]string{"_uuid"}, columns...)...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(results[0].Rows) == 0 {
+		return nil, fmt.Errorf("no bridge named %s", bridgeName)
+	}
+
+	referenced := make(map[string]bool)
+	for _, id := range results[0].Rows[0].uuids(column) {
+		referenced[id] = true
+	}
+	rows := []ovsdbRow{}
+	for _, row := range results[1].Rows {
+		if referenced[row.uuid()] {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// GetPorts returns the ports of the bridge with the state of their interfaces.
+func (ovsdbService *OvsdbService) GetPorts(bridgeName string) (map[string]plsv1.Port, error) {
+	columns := append(append([]string{"name", "interfaces", "external_ids", "qos"}, vlanColumns...), bondColumns...)
+	rows, err := ovsdbService.bridgeRows(bridgeName, "ports", "Port", columns...)
+	if err != nil {
+		return map[string]plsv1.Port{}, fmt.Errorf("list-ports error: %v", err)
+	}
+	ifaceColumns := append(append(append([]string{"_uuid"}, portColumns...), bondMemberColumns...), qosInterfaceColumns...)
+	results, err := ovsdbService.query(
+		opSelect("Interface", where(), ifaceColumns...),
+		opSelect("QoS", where(), qosColumns...),
+		opSelect("Queue", where(), queueColumns...),
+	)
+	if err != nil {
+		return map[string]plsv1.Port{}, fmt.Errorf("list Interface error: %v", err)
+	}
+	tables := []map[string]ovsdbRow{}
+	for _, result := range results {
+		table := make(map[string]ovsdbRow)
+		for _, row := range result.Rows {
+			table[row.uuid()] = row
+		}
+		tables = append(tables, table)
+	}
+	return portsFromRows(bridgeName, rows, tables[0], tables[1], tables[2]), nil
+}
+
+func (ovsdbService *OvsdbService) GetNewPortID(bridgeName string) (int, error) {
+	ports, err := ovsdbService.GetPorts(bridgeName)
+	if err != nil {
+		return 0, err
+	}
+	return nextPortID(ports)
+}
+
+func (ovsdbService *OvsdbService) GetController(bridgeName string) ([]string, error) {
+	controllers := []string{}
+	rows, err := ovsdbService.bridgeRows(bridgeName, "controller", "Controller", "target")
+	if err != nil {
+		return controllers, fmt.Errorf("get-controller error: %v", err)
+	}
+
+	for _, row := range rows {
+		controllers = append(controllers, row.str("target"))
+	}
+	sort.Strings(controllers)
+	return controllers, nil
+}
+
+// GetVxlans returns the tunnels of every type attached to the bridge, with their status, keyed by name.
+func (ovsdbService *OvsdbService) GetVxlans(bridgeName string) (map[string]plsv1.Vxlan, error) {
+	vxlansMap := map[string]plsv1.Vxlan{}
+	ports, err := ovsdbService.bridgeRows(bridgeName, "ports", "Port", "interfaces")
+	if err != nil {
+		return vxlansMap, fmt.Errorf("list-ifaces error: %v", err)
+	}
+	ifaces := make(map[string]bool)
+	for _, port := range ports {
+		for _, id := range port.uuids("interfaces") {
+			ifaces[id] = true
+		}
+	}
+
+	results, err := ovsdbService.query(opSelect("Interface", where(), append([]string{"_uuid"}, tunnelColumns...)...))
+	if err != nil {
+		return vxlansMap, fmt.Errorf("list Interface error: %v", err)
+	}
+	for _, row := range results[0].Rows {
+		if !ifaces[row.uuid()] {
+			continue
+		}
+		if vxlan, ok := tunnelFromRow(row); ok {
+			vxlansMap[vxlan.VxlanId] = vxlan
+		}
+	}
+	return vxlansMap, nil
+}
+
+// CreateMirror creates the mirror on the bridge, replacing the mirror with the same name if there is one.
+func (ovsdbService *OvsdbService) CreateMirror(bridgeName string, mirror plsv1.Mirror) error {
+	mirrors, err := ovsdbService.bridgeRows(bridgeName, "mirrors", "Mirror", "name")
+	if err != nil {
+		return fmt.Errorf("create mirror error: %v", err)
+	}
+	results, err := ovsdbService.query(opSelect("Port", where(), "_uuid", "name"))
+	if err != nil {
+		return fmt.Errorf("list Port error: %v", err)
+	}
+	portUUIDs := make(map[string]string)
+	for _, row := range results[0].Rows {
+		portUUIDs[row.str("name")] = row.uuid()
+	}
+
+	bridge := where(cond("name", "==", bridgeName))
+	ops := []ovsdbOp{opExists("Bridge", bridge)}
+	for _, portName := range mirrorPorts(mirror) {
+		id, ok := portUUIDs[portName]
+		if !ok {
+			return fmt.Errorf("create mirror error: %w: no port named %s", ErrInvalidMirror, portName)
+		}
+		// the port could be deleted before the mirror is created
+		ops = append(ops, opExists("Port", where(cond("_uuid", "==", uuidAtom(id)))))
+	}
+	for _, row := range mirrors {
+		if row.str("name") == mirror.Name {
+			ops = append(ops, opMutate("Bridge", bridge, mutation("mirrors", "delete", ovsdbSet(uuidAtom(row.uuid())))))
+		}
+	}
+	ops = append(ops,
+		opInsert("Mirror", mirrorRow(mirror, portUUIDs), "mirror"),
+		opMutate("Bridge", bridge, mutation("mirrors", "insert", ovsdbSet(namedUUID("mirror")))),
+	)
+	if _, err = ovsdbService.apply(ops...); err != nil {
+		return fmt.Errorf("create mirror error: %v", err)
+	}
+	return nil
+}
+
+// DeleteMirror removes the mirror from the bridge, which lets ovsdb-server garbage collect it.
+func (ovsdbService *OvsdbService) DeleteMirror(bridgeName, mirrorName string) error {
+	mirrors, err := ovsdbService.bridgeRows(bridgeName, "mirrors", "Mirror", "name")
+	if err != nil {
+		return fmt.Errorf("delete mirror error: %v", err)
+	}
+	for _, row := range mirrors {
+		if row.str("name") != mirrorName {
+			continue
+		}
+		_, err = ovsdbService.apply(
+			opMutate("Bridge", where(cond("name", "==", bridgeName)), mutation("mirrors", "delete", ovsdbSet(uuidAtom(row.uuid())))),
+		)
+		if err != nil {
+			return fmt.Errorf("delete mirror error: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("delete mirror error: %w: %s", ErrMirrorNotFound, mirrorName)
+}
+
+// GetMirrors returns the mirrors of the bridge keyed by name.
+func (ovsdbService *OvsdbService) GetMirrors(bridgeName string) (map[string]plsv1.Mirror, error) {
+	mirrors := make(map[string]plsv1.Mirror)
+	rows, err := ovsdbService.bridgeRows(bridgeName, "mirrors", "Mirror", mirrorColumns...)
+	if err != nil {
+		return mirrors, fmt.Errorf("list Mirror error: %v", err)
+	}
+	if len(rows) == 0 {
+		return mirrors, nil
+	}
+	results, err := ovsdbService.query(opSelect("Port", where(), "_uuid", "name"))
+	if err != nil {
+		return mirrors, fmt.Errorf("list Port error: %v", err)
+	}
+	portNames := make(map[string]string)
+	for _, row := range results[0].Rows {
+		portNames[row.uuid()] = row.str("name")
+	}
+	for _, row := range rows {
+		mirror := mirrorFromRow(row, portNames)
+		mirrors[mirror.Name] = mirror
+	}
+	return mirrors, nil
+}
+
+// ovsdbTxn builds every change as operations of a single OVSDB transaction.
+type ovsdbTxn struct {
+	service    *OvsdbService
+	bridgeName string
+	ops        []ovsdbOp
+	deletes    []string
+	names      int
+	// qosPorts are the ports whose previous QoS is deleted on commit, added the ones created by the transaction
+	qosPorts []string
+	added    map[string]bool
+	// removedMembers are the bond and member pairs whose interface is removed from the bond on commit
+	removedMembers [][2]string
+}
+
+func (ovsdbService *OvsdbService) NewTxn(bridgeName string) Txn {
+	return &ovsdbTxn{service: ovsdbService, bridgeName: bridgeName}
+}
+
+// uuidName returns a name for a row inserted in the transaction that no other insert uses.
+func (txn *ovsdbTxn) uuidName(prefix string) string {
+	txn.names++
+	return fmt.Sprintf("%s%d", prefix, txn.names)
+}
+
+func (txn *ovsdbTxn) bridge() []any {
+	return where(cond("name", "==", txn.bridgeName))
+}
+
+func (txn *ovsdbTxn) SetController(controller ...string) {
+	controllers := []any{}
+	for _, target := range controller {
+		uuidName := txn.uuidName("controller")
+		txn.ops = append(txn.ops, opInsert("Controller", map[string]any{"target": target}, uuidName))
+		controllers = append(controllers, namedUUID(uuidName))
+	}
+	// previous controllers are garbage collected once the bridge stops referencing them
+	txn.ops = append(txn.ops, opUpdate("Bridge", txn.bridge(), map[string]any{"controller": ovsdbSet(controllers...)}))
+}
+
+func (txn *ovsdbTxn) SetProtocol(protocol string) {
+	txn.ops = append(txn.ops, opUpdate("Bridge", txn.bridge(), map[string]any{
+		"protocols": ovsdbStringSet(strings.Split(protocol, ",")),
+	}))
+}
+
+func (txn *ovsdbTxn) SetDatapathID(datapathId string) {
+	txn.ops = append(txn.ops, opMutate("Bridge", txn.bridge(),
+		mutation("other_config", "delete", ovsdbSet("datapath-id")),
+		mutation("other_config", "insert", ovsdbMap(map[string]string{"datapath-id": datapathId})),
+	))
+}
+
+// SetDatapathType sets the datapath of the bridge, an empty type being the OVS default.
+func (txn *ovsdbTxn) SetDatapathType(datapathType string) {
+	txn.ops = append(txn.ops, opUpdate("Bridge", txn.bridge(), map[string]any{"datapath_type": datapathType}))
+}
+
+// SetFailMode sets the fail mode of the bridge, an empty mode clearing it.
+func (txn *ovsdbTxn) SetFailMode(failMode string) {
+	mode := ovsdbSet()
+	if failMode != "" {
+		mode = ovsdbSet(failMode)
+	}
+	txn.ops = append(txn.ops, opUpdate("Bridge", txn.bridge(), map[string]any{"fail_mode": mode}))
+}
+
+func (txn *ovsdbTxn) SetFlowExport(export plsv1.FlowExport) {
+	row := map[string]any{}
+	for _, collector := range flowCollectors(export) {
+		if !collector.enabled {
+			row[collector.column] = ovsdbSet()
+			continue
+		}
+		uuidName := txn.uuidName(collector.column)
+		txn.ops = append(txn.ops, opInsert(collector.table, flowCollectorRow(collector), uuidName))
+		row[collector.column] = namedUUID(uuidName)
+	}
+	// previous collectors are garbage collected once the bridge stops referencing them
+	txn.ops = append(txn.ops, opUpdate("Bridge", txn.bridge(), row))
+}
+
+// addPort creates an interface, its port, and attaches the port to the bridge.
+func (txn *ovsdbTxn) addPort(portName string, iface map[string]any) {
+	iface["name"] = portName
+	txn.insertPort(map[string]any{"name": portName}, iface)
+}
+
+// insertPort creates the interfaces, the port with them, and attaches the port to the bridge.
+func (txn *ovsdbTxn) insertPort(port map[string]any, ifaces ...map[string]any) {
+	portName := port["name"].(string)
+	if txn.added == nil {
+		txn.added = make(map[string]bool)
+	}
+	txn.added[portName] = true
+	txn.ops = append(txn.ops, opAbsent("Port", where(cond("name", "==", portName))))
+	interfaces := []any{}
+	for _, iface := range ifaces {
+		ifaceName := txn.uuidName("iface")
+		txn.ops = append(txn.ops, opInsert("Interface", iface, ifaceName))
+		interfaces = append(interfaces, namedUUID(ifaceName))
+	}
+	port["interfaces"] = ovsdbSet(interfaces...)
+	portUUIDName := txn.uuidName("port")
+	txn.ops = append(txn.ops,
+		opInsert("Port", port, portUUIDName),
+		opMutate("Bridge", txn.bridge(), mutation("ports", "insert", ovsdbSet(namedUUID(portUUIDName)))),
+	)
+}
+
+// AddBond creates the bond with an interface for each of its members.
+func (txn *ovsdbTxn) AddBond(port plsv1.Port) {
+	ifaces := []map[string]any{}
+	for _, member := range sortedMembers(port.Bond) {
+		ifaces = append(ifaces, map[string]any{"name": member})
+	}
+	row := bondColumnValues(port.Bond)
+	row["name"] = port.Name
+	txn.insertPort(row, ifaces...)
+}
+
+func (txn *ovsdbTxn) SetBond(port plsv1.Port) {
+	txn.ops = append(txn.ops,
+		opExists("Port", where(cond("name", "==", port.Name))),
+		opUpdate("Port", where(cond("name", "==", port.Name)), bondColumnValues(port.Bond)),
+	)
+}
+
+func (txn *ovsdbTxn) AddBondMember(portName, member string) {
+	ifaceName := txn.uuidName("iface")
+	txn.ops = append(txn.ops,
+		opExists("Port", where(cond("name", "==", portName))),
+		opInsert("Interface", map[string]any{"name": member}, ifaceName),
+		opMutate("Port", where(cond("name", "==", portName)), mutation("interfaces", "insert", ovsdbSet(namedUUID(ifaceName)))),
+	)
+}
+
+// RemoveBondMember detaches the member from the bond. Like removing a port, it takes the uuid of its
+// interface, which is looked up on commit.
+func (txn *ovsdbTxn) RemoveBondMember(portName, member string) {
+	txn.removedMembers = append(txn.removedMembers, [2]string{portName, member})
+}
+
+func (txn *ovsdbTxn) AddPort(portName string, netIndex int, internal bool) {
+	iface := map[string]any{}
+	if netIndex != NO_DEFAULT_ID {
+		iface["ofport_request"] = netIndex
+	}
+	if internal {
+		iface["type"] = "internal"
+	}
+	txn.addPort(portName, iface)
+}
+
+func vxlanInterface(vxlan plsv1.Vxlan) map[string]any {
+	return map[string]any{
+		"type":    tunnelType(vxlan),
+		"options": ovsdbMap(tunnelOptions(vxlan)),
+	}
+}
+
+func (txn *ovsdbTxn) CreateVxlan(vxlan plsv1.Vxlan) {
+	txn.addPort(vxlan.VxlanId, vxlanInterface(vxlan))
+}
+
+func (txn *ovsdbTxn) ModifyVxlan(vxlan plsv1.Vxlan) {
+	txn.ops = append(txn.ops,
+		opExists("Interface", where(cond("name", "==", vxlan.VxlanId))),
+		opUpdate("Interface", where(cond("name", "==", vxlan.VxlanId)), vxlanInterface(vxlan)),
+	)
+}
+
+func (txn *ovsdbTxn) SetSpanningTree(st plsv1.SpanningTree) {
+	txn.ops = append(txn.ops,
+		opUpdate("Bridge", txn.bridge(), map[string]any{
+			"stp_enable":  st.Protocol == plsv1.STP_PROTOCOL_STP,
+			"rstp_enable": st.Protocol == plsv1.STP_PROTOCOL_RSTP,
+		}),
+		opMutate("Bridge", txn.bridge(),
+			mutation("other_config", "delete", ovsdbSet("stp-priority", "rstp-priority")),
+			mutation("other_config", "insert", ovsdbMap(spanningTreeOtherConfig(st))),
+		),
+	)
+}
+
+func (txn *ovsdbTxn) SetPathCost(portName string, cost int) {
+	txn.ops = append(txn.ops, pathCostOps(portName, cost)...)
+}
+
+// pathCostOps replaces the path cost of the port, failing if there is no port with that name.
+func pathCostOps(portName string, cost int) []ovsdbOp {
+	otherConfig := pathCostOtherConfig(cost)
+	keys := []any{}
+	for _, k := range sortedKeys(otherConfig) {
+		keys = append(keys, k)
+	}
+	return []ovsdbOp{
+		opExists("Port", where(cond("name", "==", portName))),
+		opMutate("Port", where(cond("name", "==", portName)),
+			mutation("other_config", "delete", ovsdbSet(keys...)),
+			mutation("other_config", "insert", ovsdbMap(otherConfig)),
+		),
+	}
+}
+
+func (txn *ovsdbTxn) SetMtu(interfaceName string, mtu int) {
+	txn.ops = append(txn.ops,
+		opExists("Interface", where(cond("name", "==", interfaceName))),
+		opUpdate("Interface", where(cond("name", "==", interfaceName)), map[string]any{"mtu_request": mtuRequest(mtu)}),
+	)
+}
+
+// SetPortQos sets the ingress policing of the port interface and replaces its egress QoS and queues,
+// which are cleared when qos is nil.
+func (txn *ovsdbTxn) SetPortQos(portName string, qos *plsv1.PortQos) {
+	rate, burst := ingressPolicing(qos)
+	externalIds := []any{mutation("external_ids", "delete", ovsdbSet(QOS_EXTERNAL_ID))}
+	if fingerprint := qosFingerprint(qos); fingerprint != "" {
+		externalIds = append(externalIds, mutation("external_ids", "insert", ovsdbMap(map[string]string{QOS_EXTERNAL_ID: fingerprint})))
+	}
+	txn.ops = append(txn.ops,
+		opExists("Port", where(cond("name", "==", portName))),
+		opUpdate("Interface", where(cond("name", "==", portName)), map[string]any{
+			"ingress_policing_rate": rate, "ingress_policing_burst": burst,
+		}),
+		opMutate("Interface", where(cond("name", "==", portName)), externalIds...),
+	)
+	if !txn.added[portName] {
+		txn.qosPorts = append(txn.qosPorts, portName)
+	}
+	if !hasEgressQos(qos) {
+		txn.ops = append(txn.ops, opUpdate("Port", where(cond("name", "==", portName)), map[string]any{"qos": ovsdbSet()}))
+		return
+	}
+
+	queues := []any{}
+	for _, queue := range sortedQueues(qos) {
+		queueName := txn.uuidName("queue")
+		txn.ops = append(txn.ops, opInsert("Queue", map[string]any{"other_config": ovsdbMap(queueOtherConfig(queue))}, queueName))
+		queues = append(queues, []any{queue.Id, namedUUID(queueName)})
+	}
+	qosName := txn.uuidName("qos")
+	txn.ops = append(txn.ops,
+		opInsert("QoS", map[string]any{
+			"type":         qosType(qos),
+			"other_config": ovsdbMap(qosOtherConfig(qos)),
+			"queues":       []any{"map", queues},
+		}, qosName),
+		opUpdate("Port", where(cond("name", "==", portName)), map[string]any{"qos": namedUUID(qosName)}),
+	)
+}
+
+func (txn *ovsdbTxn) SetPortVlan(port plsv1.Port) {
+	txn.ops = append(txn.ops,
+		opExists("Port", where(cond("name", "==", port.Name))),
+		opUpdate("Port", where(cond("name", "==", port.Name)), portVlanColumns(port)),
+	)
+}
+
+// deletePreviousQos returns the operations that delete the QoS and queues the ports had before the transaction.
+func (txn *ovsdbTxn) deletePreviousQos() ([]ovsdbOp, error) {
+	results, err := txn.service.query(
+		opSelect("Port", where(), "name", "qos"),
+		opSelect("QoS", where(), "_uuid", "queues"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ops := []ovsdbOp{}
+	qos, queues := previousQos(results[0].Rows, results[1].Rows, txn.qosPorts)
+	for _, id := range qos {
+		ops = append(ops, opDelete("QoS", where(cond("_uuid", "==", uuidAtom(id)))))
+	}
+	for _, id := range queues {
+		ops = append(ops, opDelete("Queue", where(cond("_uuid", "==", uuidAtom(id)))))
+	}
+	return ops, nil
+}
+
+func (txn *ovsdbTxn) SetExternalIds(table, record string, externalIds map[string]string) {
+	txn.ops = append(txn.ops, externalIdsOps(table, record, externalIds)...)
+}
+
+// DeletePort detaches the port from the bridge. Removing a port takes its uuid, which is looked up on commit.
+func (txn *ovsdbTxn) DeletePort(portName string) {
+	txn.deletes = append(txn.deletes, portName)
+}
+
+func (txn *ovsdbTxn) DeleteVxlan(vxlanId string) {
+	txn.DeletePort(vxlanId)
+}
+
+func (txn *ovsdbTxn) Commit() error {
+	if len(txn.ops) == 0 && len(txn.deletes) == 0 && len(txn.removedMembers) == 0 {
+		return nil
+	}
+	ops := []ovsdbOp{opExists("Bridge", txn.bridge())}
+	ops = append(ops, txn.ops...)
+
+	if len(txn.qosPorts) > 0 {
+		deletes, err := txn.deletePreviousQos()
+		if err != nil {
+			return err
+		}
+		ops = append(ops, deletes...)
+	}
+
+	if len(txn.deletes) > 0 {
+		results, err := txn.service.query(opSelect("Port", where(), "_uuid", "name"))
+		if err != nil {
+			return err
+		}
+		portUUIDs := make(map[string]string)
+		for _, row := range results[0].Rows {
+			portUUIDs[row.str("name")] = row.uuid()
+		}
+		for _, portName := range txn.deletes {
+			id, ok := portUUIDs[portName]
+			if !ok {
+				return fmt.Errorf("no port named %s", portName)
+			}
+			// removing the reference lets ovsdb-server garbage collect the port and its interface
+			ops = append(ops, opMutate("Bridge", txn.bridge(), mutation("ports", "delete", ovsdbSet(uuidAtom(id)))))
+		}
+	}
+
+	if len(txn.removedMembers) > 0 {
+		results, err := txn.service.query(opSelect("Interface", where(), "_uuid", "name"))
+		if err != nil {
+			return err
+		}
+		ifaceUUIDs := make(map[string]string)
+		for _, row := range results[0].Rows {
+			ifaceUUIDs[row.str("name")] = row.uuid()
+		}
+		for _, removed := range txn.removedMembers {
+			id, ok := ifaceUUIDs[removed[1]]
+			if !ok {
+				return fmt.Errorf("no interface named %s", removed[1])
+			}
+			// the interface is garbage collected once the bond stops referencing it
+			ops = append(ops,
+				opExists("Port", where(cond("name", "==", removed[0]))),
+				opMutate("Port", where(cond("name", "==", removed[0])), mutation("interfaces", "delete", ovsdbSet(uuidAtom(id)))),
+			)
+		}
+	}
+
+	results, err := txn.service.apply(ops...)
+	if err != nil && len(results) > 0 && results[0].Error != "" {
+		return fmt.Errorf("no bridge named %s", txn.bridgeName)
+	}
+	return err
+}
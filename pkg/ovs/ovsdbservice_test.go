@@ -0,0 +1,456 @@
+package ovs
+
+import (
+	"testing"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+func newTestOvsdbService(t *testing.T) (*OvsdbService, *fakeOvsdb) {
+	t.Helper()
+	fake, address := newFakeOvsdb(t)
+	return NewOvsdbService(address), fake
+}
+
+func TestOvsdbAddDeleteBridge(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !svc.BridgeExists("br0") {
+		t.Fatalf("expected bridge br0 to exist")
+	}
+	if err := svc.AddBridge("br0"); err == nil {
+		t.Fatalf("expected an error adding an existing bridge")
+	}
+	if n := len(fake.rows("Interface", nil)); n != 1 {
+		t.Fatalf("expected 1 interface after a failed transaction, got: %d", n)
+	}
+
+	if err := svc.DeleteBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if svc.BridgeExists("br0") {
+		t.Fatalf("expected bridge br0 to be deleted")
+	}
+	if n := len(fake.rows("Port", nil)); n != 0 {
+		t.Fatalf("expected ports to be garbage collected, got: %d", n)
+	}
+	if err := svc.DeleteBridge("br0"); err == nil {
+		t.Fatalf("expected an error deleting a missing bridge")
+	}
+}
+
+func TestOvsdbBridgeSettings(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := svc.SetDatapathID("br0", "1234"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetDatapathID("br0", "5678"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetProtocol("br0", "OpenFlow13"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	bridge := fake.rows("Bridge", map[string]any{"name": "br0"})[0]
+	if dpid := bridge.strMap("other_config")["datapath-id"]; dpid != "5678" {
+		t.Errorf("unexpected datapath-id: %s", dpid)
+	}
+	if protocols := bridge.strings("protocols"); len(protocols) != 1 || protocols[0] != "OpenFlow13" {
+		t.Errorf("unexpected protocols: %v", protocols)
+	}
+
+	if err := svc.SetProtocol("missing", "OpenFlow13"); err == nil {
+		t.Errorf("expected an error on a missing bridge")
+	}
+}
+
+func TestOvsdbController(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := svc.SetController("br0", "tcp:127.0.0.1:6633", "tcp:127.0.0.2:6633"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetController("br0", "tcp:127.0.0.1:6633"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ctrl, err := svc.GetController("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(ctrl) != 1 || ctrl[0] != "tcp:127.0.0.1:6633" {
+		t.Errorf("unexpected controller output: %v", ctrl)
+	}
+	if n := len(fake.rows("Controller", nil)); n != 1 {
+		t.Errorf("expected replaced controllers to be garbage collected, got %d rows", n)
+	}
+
+	fake.update("Controller", map[string]any{"target": "tcp:127.0.0.1:6633"}, map[string]any{
+		"is_connected": true, "role": "master", "status": ovsdbMap(map[string]string{"state": "ACTIVE"}),
+	})
+	statuses, err := svc.GetControllerStatus("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(statuses) != 1 || !statuses[0].IsConnected || statuses[0].Role != "master" || statuses[0].Status["state"] != "ACTIVE" {
+		t.Errorf("unexpected controller status: %+v", statuses)
+	}
+}
+
+func TestOvsdbPorts(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcdep1999", 1999, true); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err == nil {
+		t.Fatalf("expected an error adding an existing port")
+	}
+
+	ports, err := svc.GetPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(ports) != 2 {
+		t.Fatalf("expected 2 ports, got: %v", ports)
+	}
+	if state := ports["lsabcdep1999"].State; state == nil || state.Ofport != 1999 || state.Type != "internal" {
+		t.Errorf("unexpected port state: %+v", state)
+	}
+
+	ofport, err := svc.GetPortNumber("lsabcdep1999")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if ofport != 1999 {
+		t.Errorf("expected ofport 1999, got: %d", ofport)
+	}
+	if probe := fake.rows("Interface", map[string]any{"name": "lsabcdep1999"})[0]; probe.str("type") != "internal" {
+		t.Errorf("expected probe port to be internal, got: %s", probe.str("type"))
+	}
+
+	id, err := svc.GetNewPortID("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if id != 2 {
+		t.Errorf("expected new port id 2, got: %d", id)
+	}
+}
+
+func TestOvsdbVxlans(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	vx := plsv1.Vxlan{VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "4789"}
+	if err := svc.CreateVxlan("br0", vx); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	vx.RemoteIp = "10.0.0.3"
+	vx.Key = "5000"
+	vx.Ttl = "64"
+	if err := svc.ModifyVxlan(vx); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	vxlans, err := svc.GetVxlans("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	got, ok := vxlans["vx0"]
+	if !ok {
+		t.Fatalf("expected vxlan 'vx0' to be present")
+	}
+	if got.RemoteIp != "10.0.0.3" || got.LocalIp != "10.0.0.1" || got.UdpPort != "4789" {
+		t.Errorf("unexpected vxlan fields: %+v", got)
+	}
+	if got.Key != "5000" || got.Ttl != "64" {
+		t.Errorf("expected a fixed key and ttl, got: %+v", got)
+	}
+
+	vx.Key, vx.Ttl = "", ""
+	if err := svc.ModifyVxlan(vx); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	options := fake.rows("Interface", map[string]any{"name": "vx0"})[0].strMap("options")
+	if _, ok := options["ttl"]; ok || options["key"] != "flow" {
+		t.Errorf("expected key=flow and no ttl, got: %v", options)
+	}
+
+	gre := plsv1.Vxlan{VxlanId: "gre0", Type: plsv1.TUNNEL_GRE, LocalIp: "10.0.0.1", RemoteIp: "10.0.0.4", UdpPort: "7000"}
+	if err := svc.CreateVxlan("br0", gre); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	vxlans, err = svc.GetVxlans("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := vxlans["gre0"]; got.Type != plsv1.TUNNEL_GRE || got.RemoteIp != "10.0.0.4" || got.UdpPort != "" {
+		t.Errorf("unexpected gre fields: %+v", got)
+	}
+	if vxlans["vx0"].Type != plsv1.TUNNEL_VXLAN {
+		t.Errorf("expected vx0 to be a vxlan, got: %s", vxlans["vx0"].Type)
+	}
+
+	// tunnels of other bridges are left out
+	if err := svc.AddBridge("br1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.CreateVxlan("br1", plsv1.Vxlan{VxlanId: "vx9", RemoteIp: "10.0.0.9"}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	vxlans, err = svc.GetVxlans("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, ok := vxlans["vx9"]; ok || len(vxlans) != 2 {
+		t.Errorf("expected only the tunnels of br0, got: %v", vxlans)
+	}
+	if status := vxlans["vx0"].Status; status == nil || status.Ofport == 0 {
+		t.Errorf("expected vx0 to have an ofport, got: %+v", status)
+	}
+
+	if err := svc.DeleteVxlan("br0", "vx0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("Interface", map[string]any{"name": "vx0"})); n != 0 {
+		t.Errorf("expected vx0 interface to be removed, got %d rows", n)
+	}
+	if err := svc.DeleteVxlan("br0", "vx0"); err == nil {
+		t.Errorf("expected an error deleting a missing vxlan")
+	}
+}
+
+func TestParseOvsdbAddress(t *testing.T) {
+	network, addr, err := parseOvsdbAddress("unix:/var/run/openvswitch/db.sock")
+	if err != nil || network != "unix" || addr != "/var/run/openvswitch/db.sock" {
+		t.Errorf("unexpected result: %s %s %v", network, addr, err)
+	}
+	network, addr, err = parseOvsdbAddress("tcp:127.0.0.1:6640")
+	if err != nil || network != "tcp" || addr != "127.0.0.1:6640" {
+		t.Errorf("unexpected result: %s %s %v", network, addr, err)
+	}
+	if _, _, err = parseOvsdbAddress("ssl:127.0.0.1:6640"); err == nil {
+		t.Errorf("expected an error for an unsupported method")
+	}
+}
+
+func TestOvsdbTxnAtomic(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// the second port already exists, so none of the changes may be applied
+	txn := svc.NewTxn("br0")
+	txn.SetDatapathID("1234")
+	txn.CreateVxlan(plsv1.Vxlan{VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "4789"})
+	txn.AddPort("lsabcde1", 1, false)
+	if err := txn.Commit(); err == nil {
+		t.Fatalf("expected an error adding an existing port")
+	}
+	bridge := fake.rows("Bridge", map[string]any{"name": "br0"})[0]
+	if _, ok := bridge.strMap("other_config")["datapath-id"]; ok {
+		t.Errorf("expected datapath-id not to be set by a failed transaction")
+	}
+	if n := len(fake.rows("Interface", map[string]any{"name": "vx0"})); n != 0 {
+		t.Errorf("expected vx0 not to be created by a failed transaction")
+	}
+
+	txn = svc.NewTxn("br0")
+	txn.SetDatapathID("1234")
+	txn.CreateVxlan(plsv1.Vxlan{VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "4789"})
+	txn.CreateVxlan(plsv1.Vxlan{VxlanId: "vx1", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.3", UdpPort: "4789"})
+	txn.DeleteVxlan("lsabcde1")
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	ports, err := svc.GetPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, ok := ports["lsabcde1"]; ok || len(ports) != 2 {
+		t.Errorf("unexpected ports after transaction: %v", ports)
+	}
+
+	if err := svc.NewTxn("missing").Commit(); err != nil {
+		t.Errorf("expected an empty transaction to succeed, got: %v", err)
+	}
+	txn = svc.NewTxn("missing")
+	txn.SetProtocol("OpenFlow13")
+	if err := txn.Commit(); err == nil {
+		t.Errorf("expected an error on a missing bridge")
+	}
+}
+
+func TestOvsdbPortQos(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	qos := &plsv1.PortQos{
+		IngressPolicingRate: 10000, IngressPolicingBurst: 1000, MaxRate: 100000000,
+		Queues: []plsv1.Queue{{Id: 1, MinRate: 1000000, MaxRate: 50000000}, {Id: 0, Priority: 1}},
+	}
+	if err := svc.SetPortQos("br0", "lsabcde1", qos); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	iface := fake.rows("Interface", map[string]any{"name": "lsabcde1"})[0]
+	if rate, _ := iface.integer("ingress_policing_rate"); rate != 10000 {
+		t.Errorf("expected ingress policing rate 10000, got: %d", rate)
+	}
+	qosRows := fake.rows("QoS", nil)
+	if len(qosRows) != 1 || qosRows[0].str("type") != plsv1.QOS_LINUX_HTB || qosRows[0].strMap("other_config")["max-rate"] != "100000000" {
+		t.Fatalf("unexpected qos rows: %v", qosRows)
+	}
+	if queues := qosRows[0].strMap("queues"); len(queues) != 2 {
+		t.Errorf("expected 2 queues, got: %v", queues)
+	}
+	if port := fake.rows("Port", map[string]any{"name": "lsabcde1"})[0]; port.uuids("qos")[0] != qosRows[0].uuid() {
+		t.Errorf("expected the port to reference its qos")
+	}
+
+	// replacing the qos destroys the previous one and its queues
+	qos = &plsv1.PortQos{Type: plsv1.QOS_LINUX_HFSC, Queues: []plsv1.Queue{{Id: 0, MaxRate: 1000000}}}
+	if err := svc.SetPortQos("br0", "lsabcde1", qos); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("QoS", nil)); n != 1 {
+		t.Errorf("expected the previous qos to be destroyed, got %d qos rows", n)
+	}
+	if n := len(fake.rows("Queue", nil)); n != 1 {
+		t.Errorf("expected the previous queues to be destroyed, got %d queue rows", n)
+	}
+	iface = fake.rows("Interface", map[string]any{"name": "lsabcde1"})[0]
+	if rate, _ := iface.integer("ingress_policing_rate"); rate != 0 {
+		t.Errorf("expected ingress policing to be disabled, got: %d", rate)
+	}
+
+	// the qos goes away with the port
+	txn := svc.NewTxn("br0")
+	txn.SetPortQos("lsabcde1", nil)
+	txn.DeletePort("lsabcde1")
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("QoS", nil)) + len(fake.rows("Queue", nil)); n != 0 {
+		t.Errorf("expected no qos rows left, got %d", n)
+	}
+
+	if err := svc.SetPortQos("br0", "missing", qos); err == nil {
+		t.Errorf("expected an error on a missing port")
+	}
+}
+
+func TestOvsdbMirror(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for i, portName := range []string{"lsabcde1", "lsabcde2", "lsabcdep7"} {
+		if err := svc.AddPort("br0", portName, i+1, false); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	mirror := plsv1.Mirror{Name: "m1", SelectSrcPorts: []string{"lsabcde1"}, SelectVlans: []int{20, 10}, OutputPort: "lsabcdep7"}
+	if err := svc.CreateMirror("br0", mirror); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// creating it again replaces it
+	mirror.SelectDstPorts = []string{"lsabcde2"}
+	if err := svc.CreateMirror("br0", mirror); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("Mirror", nil)); n != 1 {
+		t.Fatalf("expected the previous mirror to be replaced, got %d mirrors", n)
+	}
+	mirrors, err := svc.GetMirrors("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	got := mirrors["m1"]
+	if got.OutputPort != "lsabcdep7" || len(got.SelectSrcPorts) != 1 || len(got.SelectDstPorts) != 1 || got.SelectDstPorts[0] != "lsabcde2" ||
+		len(got.SelectVlans) != 2 || got.SelectVlans[0] != 10 || got.ExpiresAt != nil {
+		t.Errorf("unexpected mirror: %+v", got)
+	}
+
+	if err := svc.CreateMirror("br0", plsv1.Mirror{Name: "m2", SelectAll: true, OutputPort: "missing"}); err == nil {
+		t.Errorf("expected an error on a missing output port")
+	}
+
+	if err := svc.DeleteMirror("br0", "m1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("Mirror", nil)); n != 0 {
+		t.Errorf("expected the mirror to be garbage collected, got %d mirrors", n)
+	}
+	if err := svc.DeleteMirror("br0", "m1"); err == nil {
+		t.Errorf("expected an error on a missing mirror")
+	}
+}
+
+func TestOvsdbCreatePatch(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("ned"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	port := PatchPort{Bridge: "ned", Name: "lsned003", Ofport: 3}
+	peer := PatchPort{Bridge: "sps", Name: "lssps007", Ofport: 7}
+	if err := svc.CreatePatch(port, peer); err == nil {
+		t.Fatalf("expected an error patching to a missing bridge")
+	}
+	if n := len(fake.rows("Interface", map[string]any{"type": "patch"})); n != 0 {
+		t.Fatalf("expected no patch ports after a failed transaction, got: %d", n)
+	}
+
+	if err := svc.AddBridge("sps"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.CreatePatch(port, peer); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, end := range [][2]PatchPort{{port, peer}, {peer, port}} {
+		ports, err := svc.GetPorts(end[0].Bridge)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if _, ok := ports[end[0].Name]; !ok {
+			t.Errorf("expected %s on %s, got: %v", end[0].Name, end[0].Bridge, ports)
+		}
+		iface := fake.rows("Interface", map[string]any{"name": end[0].Name})[0]
+		if iface.str("type") != "patch" || iface.strMap("options")["peer"] != end[1].Name {
+			t.Errorf("expected %s to be the patch to %s, got: %v", end[0].Name, end[1].Name, iface)
+		}
+		if ofport, _ := iface.integer("ofport_request"); ofport != int64(end[0].Ofport) {
+			t.Errorf("expected %s to request ofport %d, got: %d", end[0].Name, end[0].Ofport, ofport)
+		}
+	}
+}
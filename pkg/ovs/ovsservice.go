@@ -1,8 +1,10 @@
 package ovs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"strings"
@@ -25,6 +27,12 @@ func NewSudoOvsService() OvsService {
 	return OvsService{exec: NewSudoClient(OvsVsctlClient)}
 }
 
+func (ovsService *OvsService) BridgeExists(bridgeName string) bool {
+	err := ovsService.exec.Run("br-exists", bridgeName)
+
+	return err == nil
+}
+
 func (ovsService *OvsService) AddBridge(bridgeName string) error {
 	output, err := ovsService.exec.CombinedOutput("add-br", bridgeName)
 	if err != nil {
@@ -43,26 +51,210 @@ func (ovsService *OvsService) DeleteBridge(bridgeName string) error {
 	return nil
 }
 
+func setDatapathIDArgs(bridgeName, datapathId string) []string {
+	return []string{"set", "bridge", bridgeName, fmt.Sprintf("other-config:datapath-id=%s", datapathId)}
+}
+
 func (ovsService *OvsService) SetDatapathID(bridgeName, datapathId string) error {
-	output, err := ovsService.exec.CombinedOutput("set", "bridge", bridgeName, fmt.Sprintf("other-config:datapath-id=%s", datapathId))
+	output, err := ovsService.exec.CombinedOutput(setDatapathIDArgs(bridgeName, datapathId)...)
 	if err != nil {
 		return fmt.Errorf("set bridge error: %v\nOutput: %s", err, output)
 	}
 	return nil
 }
 
-func (ovsService *OvsService) SetProtocol(bridgeName, protocol string) error {
-	protocolString := fmt.Sprintf("protocols=%s", protocol)
+func setDatapathTypeArgs(bridgeName, datapathType string) []string {
+	if datapathType == "" {
+		datapathType = `""`
+	}
+	return []string{"set", "bridge", bridgeName, fmt.Sprintf("datapath_type=%s", datapathType)}
+}
 
-	output, err := ovsService.exec.CombinedOutput("set", "bridge", bridgeName, protocolString)
+func (ovsService *OvsService) SetDatapathType(bridgeName, datapathType string) error {
+	output, err := ovsService.exec.CombinedOutput(setDatapathTypeArgs(bridgeName, datapathType)...)
 	if err != nil {
 		return fmt.Errorf("set bridge error: %v\nOutput: %s", err, output)
 	}
 	return nil
 }
+
+func setProtocolArgs(bridgeName, protocol string) []string {
+	return []string{"set", "bridge", bridgeName, fmt.Sprintf("protocols=%s", protocol)}
+}
+
+func (ovsService *OvsService) SetProtocol(bridgeName, protocol string) error {
+	output, err := ovsService.exec.CombinedOutput(setProtocolArgs(bridgeName, protocol)...)
+	if err != nil {
+		return fmt.Errorf("set bridge error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+func setControllerArgs(bridgeName string, controller []string) []string {
+	if len(controller) == 0 {
+		return []string{"del-controller", bridgeName}
+	}
+	return append([]string{"set-controller", bridgeName}, controller...)
+}
+
+func setFailModeArgs(bridgeName, failMode string) []string {
+	if failMode == "" {
+		return []string{"del-fail-mode", bridgeName}
+	}
+	return []string{"set-fail-mode", bridgeName, failMode}
+}
+
+func (ovsService *OvsService) SetFailMode(bridgeName, failMode string) error {
+	output, err := ovsService.exec.CombinedOutput(setFailModeArgs(bridgeName, failMode)...)
+	if err != nil {
+		return fmt.Errorf("set-fail-mode error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// SetFlowExport replaces the sFlow, IPFIX and NetFlow collectors of the bridge.
+func (ovsService *OvsService) SetFlowExport(bridgeName string, export plsv1.FlowExport) error {
+	txn := ovsService.NewTxn(bridgeName)
+	txn.SetFlowExport(export)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set flow export error: %v", err)
+	}
+	return nil
+}
+
+// SetSpanningTree enables the protocol of the spanning tree on the bridge, disabling it if the protocol is empty.
+func (ovsService *OvsService) SetSpanningTree(bridgeName string, st plsv1.SpanningTree) error {
+	txn := ovsService.NewTxn(bridgeName)
+	txn.SetSpanningTree(st)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set spanning tree error: %v", err)
+	}
+	return nil
+}
+
+// GetSpanningTreeStatus returns the state of the spanning tree of the bridge and of its ports.
+func (ovsService *OvsService) GetSpanningTreeStatus(bridgeName string) (plsv1.SpanningTreeStatus, error) {
+	columns := strings.Join(append(append([]string{}, spanningTreeBridgeColumns...), "ports"), ",")
+	output, err := ovsService.exec.CombinedOutput("--columns="+columns, "--format=json", "--data=json", "list", "Bridge", bridgeName)
+	if err != nil {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("list Bridge error: %v\nOutput: %s", err, output)
+	}
+	bridges, err := vsctlRows(output)
+	if err != nil {
+		return plsv1.SpanningTreeStatus{}, err
+	}
+	if len(bridges) == 0 {
+		return plsv1.SpanningTreeStatus{}, fmt.Errorf("list Bridge error: no bridge named %s", bridgeName)
+	}
+
+	ports := []ovsdbRow{}
+	if ids := bridges[0].uuids("ports"); len(ids) > 0 {
+		output, err = ovsService.exec.CombinedOutput(append([]string{"--columns=" + strings.Join(spanningTreePortColumns, ","), "--format=json", "--data=json", "list", "Port"}, ids...)...)
+		if err != nil {
+			return plsv1.SpanningTreeStatus{}, fmt.Errorf("list Port error: %v\nOutput: %s", err, output)
+		}
+		if ports, err = vsctlRows(output); err != nil {
+			return plsv1.SpanningTreeStatus{}, err
+		}
+	}
+	return spanningTreeStatus(bridges[0], ports), nil
+}
+
+func (ovsService *OvsService) GetFailMode(bridgeName string) (string, error) {
+	output, err := ovsService.exec.CombinedOutput("get-fail-mode", bridgeName)
+	if err != nil {
+		return "", fmt.Errorf("get-fail-mode error: %v\nOutput: %s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (ovsService *OvsService) GetControllerStatus(bridgeName string) ([]ControllerStatus, error) {
+	statuses := []ControllerStatus{}
+	output, err := ovsService.exec.CombinedOutput("get", "Bridge", bridgeName, "controller")
+	if err != nil {
+		return statuses, fmt.Errorf("get Bridge error: %v\nOutput: %s", err, output)
+	}
+
+	ids := vsctlUUIDs(output)
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+
+	output, err = ovsService.exec.CombinedOutput(append([]string{"--columns=target,is_connected,role,status", "--format=json", "--data=json", "list", "Controller"}, ids...)...)
+	if err != nil {
+		return statuses, fmt.Errorf("list Controller error: %v\nOutput: %s", err, output)
+	}
+	rows, err := vsctlRows(output)
+	if err != nil {
+		return statuses, err
+	}
+	for _, row := range rows {
+		statuses = append(statuses, controllerStatus(row))
+	}
+	return statuses, nil
+}
+
+// vsctlUUIDs parses a set of references printed by "get", e.g. [uuid1, uuid2].
+func vsctlUUIDs(output []byte) []string {
+	ids := []string{}
+	for _, id := range strings.Split(strings.Trim(strings.TrimSpace(string(output)), "[]"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// vsctlRows converts the --format=json --data=json output of ovs-vsctl into rows keyed by column,
+// whose values are in the same OVSDB JSON notation as the ones read from ovsdb-server.
+func vsctlRows(output []byte) ([]ovsdbRow, error) {
+	if len(strings.TrimSpace(string(output))) == 0 {
+		return []ovsdbRow{}, nil
+	}
+	var table OVSVxlanOutput
+	if err := decodeOvsdb(output, &table); err != nil {
+		return []ovsdbRow{}, fmt.Errorf("failed to unmarshal ovs-vsctl JSON output: %v\nOutput: %s", err, output)
+	}
+	return tableRows(table), nil
+}
+
+// vsctlTables converts the --format=json --data=json output of ovs-vsctl commands chained with --, one table per
+// command that lists rows, into their rows.
+func vsctlTables(output []byte) ([][]ovsdbRow, error) {
+	tables := [][]ovsdbRow{}
+	dec := json.NewDecoder(bytes.NewReader(output))
+	dec.UseNumber()
+	for {
+		var table OVSVxlanOutput
+		err := dec.Decode(&table)
+		if err == io.EOF {
+			return tables, nil
+		}
+		if err != nil {
+			return tables, fmt.Errorf("failed to unmarshal ovs-vsctl JSON output: %v\nOutput: %s", err, output)
+		}
+		tables = append(tables, tableRows(table))
+	}
+}
+
+// tableRows returns the rows of a table printed by ovs-vsctl, keyed by column.
+func tableRows(table OVSVxlanOutput) []ovsdbRow {
+	rows := []ovsdbRow{}
+	for _, data := range table.Data {
+		row := ovsdbRow{}
+		for i, heading := range table.Headings {
+			if i < len(data) {
+				row[heading] = data[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
 func (ovsService *OvsService) SetController(bridgeName string, controller ...string) error {
 
-	output, err := ovsService.exec.CombinedOutput("set-controller", bridgeName, strings.Join(controller, " "))
+	output, err := ovsService.exec.CombinedOutput(setControllerArgs(bridgeName, controller)...)
 	if err != nil {
 		return fmt.Errorf("set-controller error: %v\nOutput: %s", err, output)
 	}
@@ -70,21 +262,21 @@ func (ovsService *OvsService) SetController(bridgeName string, controller ...str
 	return nil
 }
 
+func vxlanArgs(vxlan plsv1.Vxlan) []string {
+	args := []string{"set", "interface", vxlan.VxlanId, fmt.Sprintf("type=%s", tunnelType(vxlan))}
+	options := tunnelOptions(vxlan)
+	for _, k := range tunnelOptionKeys(options) {
+		args = append(args, fmt.Sprintf("options:%s=%s", k, options[k]))
+	}
+	return args
+}
+
+func createVxlanArgs(bridgeName string, vxlan plsv1.Vxlan) []string {
+	return append([]string{"add-port", bridgeName, vxlan.VxlanId, "--"}, vxlanArgs(vxlan)...)
+}
+
 func (ovsService *OvsService) CreateVxlan(bridgeName string, vxlan plsv1.Vxlan) error {
-	commandArgs := []string{
-		"add-port",
-		bridgeName,
-		vxlan.VxlanId,
-		"--",
-		"set", "interface",
-		vxlan.VxlanId,
-		"type=vxlan",
-		"options:key=flow",
-		fmt.Sprintf("options:remote_ip=%s", vxlan.RemoteIp),
-		fmt.Sprintf("options:local_ip=%s", vxlan.LocalIp),
-		fmt.Sprintf("options:dst_port=%s", vxlan.UdpPort),
-	}
-	output, err := ovsService.exec.CombinedOutput(commandArgs...)
+	output, err := ovsService.exec.CombinedOutput(createVxlanArgs(bridgeName, vxlan)...)
 
 	if err != nil {
 		return fmt.Errorf("add-port error: %v\nOutput: %s", err, output)
@@ -94,39 +286,51 @@ func (ovsService *OvsService) CreateVxlan(bridgeName string, vxlan plsv1.Vxlan)
 }
 
 func (ovsService *OvsService) DeleteVxlan(bridgeName, vxlanId string) error {
-	commandArgs := []string{
-		"del-port",
-		bridgeName,
-		vxlanId,
-	}
+	return ovsService.DeletePort(bridgeName, vxlanId)
+}
+
+// modifyVxlanArgs clears the options before setting them again, so options no longer in the tunnel are removed.
+func modifyVxlanArgs(vxlan plsv1.Vxlan) []string {
+	return append([]string{"clear", "interface", vxlan.VxlanId, "options", "--"}, vxlanArgs(vxlan)...)
+}
+
+func (ovsService *OvsService) ModifyVxlan(vxlan plsv1.Vxlan) error {
+	output, err := ovsService.exec.CombinedOutput(modifyVxlanArgs(vxlan)...)
 
-	output, err := ovsService.exec.CombinedOutput(commandArgs...)
 	if err != nil {
-		return fmt.Errorf("del-port error: %v\nOutput: %s", err, output)
+		return fmt.Errorf("set interface error: %v\nOutput: %s", err, output)
 	}
 	return nil
+
 }
 
-func (ovsService *OvsService) ModifyVxlan(vxlan plsv1.Vxlan) error {
-	commandArgs := []string{
-		"set", "interface",
-		vxlan.VxlanId,
-		"type=vxlan",
-		"options:key=flow",
-		fmt.Sprintf("options:remote_ip=%s", vxlan.RemoteIp),
-		fmt.Sprintf("options:local_ip=%s", vxlan.LocalIp),
-		fmt.Sprintf("options:dst_port=%s", vxlan.UdpPort),
+// SetMtu requests the MTU of the interface to ovs-vswitchd. 0 clears the request.
+func (ovsService *OvsService) SetMtu(interfaceName string, mtu int) error {
+	output, err := ovsService.exec.CombinedOutput(setMtuArgs(interfaceName, mtu)...)
+	if err != nil {
+		return fmt.Errorf("set interface error: %v\nOutput: %s", err, output)
 	}
-	output, err := ovsService.exec.CombinedOutput(commandArgs...)
+	return nil
+}
 
+func (ovsService *OvsService) SetPathCost(portName string, cost int) error {
+	output, err := ovsService.exec.CombinedOutput(setPathCostArgs(portName, cost)...)
 	if err != nil {
-		return fmt.Errorf("set interface error: %v\nOutput: %s", err, output)
+		return fmt.Errorf("set port error: %v\nOutput: %s", err, output)
 	}
 	return nil
+}
 
+// SetExternalIds sets the given keys of the external_ids of the record, which is a Bridge, Port or Interface name.
+func (ovsService *OvsService) SetExternalIds(table, record string, externalIds map[string]string) error {
+	output, err := ovsService.exec.CombinedOutput(setExternalIdsArgs(table, record, externalIds)...)
+	if err != nil {
+		return fmt.Errorf("set %s error: %v\nOutput: %s", table, err, output)
+	}
+	return nil
 }
 
-func (ovsService *OvsService) AddPort(bridgeName, portName string, netIndex int, internal bool) error {
+func addPortArgs(bridgeName, portName string, netIndex int, internal bool) []string {
 	args := []string{"add-port", bridgeName, portName}
 
 	if netIndex != NO_DEFAULT_ID {
@@ -140,8 +344,11 @@ func (ovsService *OvsService) AddPort(bridgeName, portName string, netIndex int,
 			"--",
 			"set", "interface", portName, "type=internal")
 	}
+	return args
+}
 
-	output, err := ovsService.exec.CombinedOutput(args...)
+func (ovsService *OvsService) AddPort(bridgeName, portName string, netIndex int, internal bool) error {
+	output, err := ovsService.exec.CombinedOutput(addPortArgs(bridgeName, portName, netIndex, internal)...)
 	if err != nil {
 		return fmt.Errorf("add-port error: %v\nOutput: %s", err, output)
 	}
@@ -149,6 +356,59 @@ func (ovsService *OvsService) AddPort(bridgeName, portName string, netIndex int,
 	return nil
 }
 
+func (ovsService *OvsService) AddBond(bridgeName string, port plsv1.Port) error {
+	output, err := ovsService.exec.CombinedOutput(addBondArgs(bridgeName, port)...)
+	if err != nil {
+		return fmt.Errorf("add-bond error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// CreatePatch adds both patch ports in a single ovs-vsctl call, so neither is left without its peer.
+func (ovsService *OvsService) CreatePatch(port, peer PatchPort) error {
+	args := append(addPatchPortArgs(port, peer), "--")
+	output, err := ovsService.exec.CombinedOutput(append(args, addPatchPortArgs(peer, port)...)...)
+	if err != nil {
+		return fmt.Errorf("add-port error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// DeletePatch removes both patch ports in a single ovs-vsctl call, from whichever bridges they are on. The ones that
+// are already gone are skipped.
+func (ovsService *OvsService) DeletePatch(portName, peerName string) error {
+	output, err := ovsService.exec.CombinedOutput("--if-exists", "del-port", portName, "--", "--if-exists", "del-port", peerName)
+	if err != nil {
+		return fmt.Errorf("del-port error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+func (ovsService *OvsService) SetPortQos(bridgeName, portName string, qos *plsv1.PortQos) error {
+	txn := ovsService.NewTxn(bridgeName)
+	txn.SetPortQos(portName, qos)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("set port qos error: %v", err)
+	}
+	return nil
+}
+
+func (ovsService *OvsService) SetPortVlan(bridgeName string, port plsv1.Port) error {
+	output, err := ovsService.exec.CombinedOutput(setPortVlanArgs(port)...)
+	if err != nil {
+		return fmt.Errorf("set port error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+func (ovsService *OvsService) DeletePort(bridgeName, portName string) error {
+	output, err := ovsService.exec.CombinedOutput("del-port", bridgeName, portName)
+	if err != nil {
+		return fmt.Errorf("del-port error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
+
 // TODO: correct formats. Be careful because i dont remember what the outut of get interface was, so i need to check
 // and pass it to integer or string depending on the situation
 func (ovsService *OvsService) GetPortNumber(portName string) (int64, error) {
@@ -162,28 +422,68 @@ func (ovsService *OvsService) GetPortNumber(portName string) (int64, error) {
 	return ofport, err
 }
 
+// GetPorts returns the ports of the bridge with the state of their interfaces.
 func (ovsService *OvsService) GetPorts(bridgeName string) (map[string]plsv1.Port, error) {
 	portMap := make(map[string]plsv1.Port)
-	output, err := ovsService.exec.CombinedOutput("list-ports", bridgeName)
+	// the ports of the bridge are in the Bridge table, their VLAN and bond configuration in the Port table, their state
+	// in the Interface table and their egress traffic controls in the QoS and Queue rows they reference. The tables are
+	// listed in a single invocation, so they are read from the same snapshot of the database.
+	portColumnNames := append(append([]string{"_uuid", "name", "interfaces", "external_ids", "qos"}, vlanColumns...), bondColumns...)
+	ifaceColumns := append(append(append([]string{"_uuid"}, portColumns...), bondMemberColumns...), qosInterfaceColumns...)
+	commands := [][]string{
+		{"--columns=ports", "list", "Bridge", bridgeName},
+		{"--columns=" + strings.Join(portColumnNames, ","), "list", "Port"},
+		{"--columns=" + strings.Join(ifaceColumns, ","), "list", "Interface"},
+		{"--columns=" + strings.Join(qosColumns, ","), "list", "QoS"},
+		{"--columns=" + strings.Join(queueColumns, ","), "list", "Queue"},
+	}
+	args := []string{"--format=json", "--data=json"}
+	for i, command := range commands {
+		if i > 0 {
+			args = append(args, "--")
+		}
+		args = append(args, command...)
+	}
+	output, err := ovsService.exec.CombinedOutput(args...)
 	if err != nil {
-		return portMap, fmt.Errorf("list-ports error: %v\nOutput: %s", err, output)
+		return portMap, fmt.Errorf("list ports error: %v\nOutput: %s", err, output)
+	}
+	tables, err := vsctlTables(output)
+	if err != nil {
+		return portMap, err
+	}
+	if len(tables) != len(commands) {
+		return portMap, fmt.Errorf("expected %d tables listing the ports of %s, got %d\nOutput: %s", len(commands), bridgeName, len(tables), output)
 	}
 
-	portNames := strings.Split(string(output), "\n") //TODO: Check
-	for _, portName := range portNames {
-		if portName == "" {
-			continue
+	portIds := make(map[string]bool)
+	for _, bridge := range tables[0] {
+		for _, id := range bridge.uuids("ports") {
+			portIds[id] = true
 		}
-		// TODO:, retrieve more details for each port; here we just set the name
-		portMap[portName] = plsv1.Port{Name: portName}
-		// Retrieve status
-		// cmd = exec.Command("ovs-vsctl", "get", "Interface", portName, "status")
-
 	}
-
+	rows := []ovsdbRow{}
+	for _, row := range tables[1] {
+		if portIds[row.uuid()] {
+			rows = append(rows, row)
+		}
+	}
+	ifaces, qos, queues := rowsByUUID(tables[2]), rowsByUUID(tables[3]), rowsByUUID(tables[4])
+	for portName, port := range portsFromRows(bridgeName, rows, ifaces, qos, queues) {
+		portMap[portName] = port
+	}
 	return portMap, nil
 }
 
+// rowsByUUID keys the rows by their uuid.
+func rowsByUUID(rows []ovsdbRow) map[string]ovsdbRow {
+	byUUID := make(map[string]ovsdbRow, len(rows))
+	for _, row := range rows {
+		byUUID[row.uuid()] = row
+	}
+	return byUUID
+}
+
 // GetNewPortID returns the next available Talpa port ID for the provided switch token.
 // It derives IDs from currently attached OVS ports matching datapath naming.
 func (ovsService *OvsService) GetNewPortID(bridgeName string) (int, error) {
@@ -191,7 +491,11 @@ func (ovsService *OvsService) GetNewPortID(bridgeName string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	return nextPortID(ports)
+}
 
+// nextPortID returns the id that follows the highest talpa port id among the given ports.
+func nextPortID(ports map[string]plsv1.Port) (int, error) {
 	maxID := 0
 	// iterate through the ports and get the maximum port. new id will be the next one
 	for portName := range ports {
@@ -242,84 +546,382 @@ type OVSVxlanOutput struct {
 	Headings []string `json:"headings"`
 }
 
+// GetVxlans returns the tunnels of every type attached to the bridge, with their status, keyed by name.
 func (ovsService *OvsService) GetVxlans(bridgeName string) (map[string]plsv1.Vxlan, error) {
+	vxlansMap := map[string]plsv1.Vxlan{}
 
-	vxlans := []plsv1.Vxlan{}
+	output, err := ovsService.exec.CombinedOutput("list-ifaces", bridgeName)
+	if err != nil {
+		return vxlansMap, fmt.Errorf("list-ifaces error: %v\nOutput: %s", err, output)
+	}
+	args := []string{"--columns=" + strings.Join(tunnelColumns, ","), "--format=json", "--data=json", "list", "Interface"}
+	ifaces := 0
+	for _, iface := range strings.Split(string(output), "\n") {
+		if iface = strings.TrimSpace(iface); iface != "" {
+			args = append(args, iface)
+			ifaces++
+		}
+	}
+	if ifaces == 0 {
+		return vxlansMap, nil
+	}
 
-	output, err := ovsService.exec.CombinedOutput("--column=name,options", "--format=json", "--data=json", "find", "Interface", "type=vxlan")
+	output, err = ovsService.exec.CombinedOutput(args...)
+	if err != nil {
+		return vxlansMap, fmt.Errorf("list Interface error: %v\nOutput: %s", err, output)
+	}
+	rows, err := vsctlRows(output)
+	if err != nil {
+		return vxlansMap, err
+	}
+	for _, row := range rows {
+		if vxlan, ok := tunnelFromRow(row); ok {
+			vxlansMap[vxlan.VxlanId] = vxlan
+		}
+	}
+	return vxlansMap, nil
+}
 
+// mirrorRows returns the given columns of the mirrors of the bridge.
+func (ovsService *OvsService) mirrorRows(bridgeName string, columns ...string) ([]ovsdbRow, error) {
+	output, err := ovsService.exec.CombinedOutput("get", "bridge", bridgeName, "mirrors")
+	if err != nil {
+		return nil, fmt.Errorf("get Bridge error: %v\nOutput: %s", err, output)
+	}
+	ids := vsctlUUIDs(output)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	output, err = ovsService.exec.CombinedOutput(append([]string{"--columns=_uuid," + strings.Join(columns, ","), "--format=json", "--data=json", "list", "Mirror"}, ids...)...)
 	if err != nil {
-		return map[string]plsv1.Vxlan{}, fmt.Errorf("find Interface type=vxlan error: %v\nOutput: %s", err, output)
+		return nil, fmt.Errorf("list Mirror error: %v\nOutput: %s", err, output)
 	}
+	return vsctlRows(output)
+}
 
-	outputStr := string(output)
-	if outputStr == "" {
-		// No VXLANs found, which is not necessarily an error.
-		return map[string]plsv1.Vxlan{}, nil
+// mirrorUUID returns the uuid of the mirror of the bridge with the given name, empty if there is none.
+func (ovsService *OvsService) mirrorUUID(bridgeName, mirrorName string) (string, error) {
+	rows, err := ovsService.mirrorRows(bridgeName, "name")
+	if err != nil {
+		return "", err
 	}
+	for _, row := range rows {
+		if row.str("name") == mirrorName {
+			return row.uuid(), nil
+		}
+	}
+	return "", nil
+}
 
-	var ovsOutput OVSVxlanOutput
-	err = json.Unmarshal([]byte(outputStr), &ovsOutput)
+// CreateMirror creates the mirror on the bridge, replacing the mirror with the same name if there is one.
+func (ovsService *OvsService) CreateMirror(bridgeName string, mirror plsv1.Mirror) error {
+	id, err := ovsService.mirrorUUID(bridgeName, mirror.Name)
+	if err != nil {
+		return fmt.Errorf("create mirror error: %v", err)
+	}
+	output, err := ovsService.exec.CombinedOutput("--columns=_uuid,name", "--format=json", "--data=json", "list", "Port")
 	if err != nil {
-		return map[string]plsv1.Vxlan{}, fmt.Errorf("failed to unmarshal ovs-vsctl JSON output: %v\nOutput: %s", err, outputStr)
+		return fmt.Errorf("list Port error: %v\nOutput: %s", err, output)
 	}
+	ports, err := vsctlRows(output)
+	if err != nil {
+		return err
+	}
+	portNames := make(map[string]bool)
+	for _, port := range ports {
+		portNames[port.str("name")] = true
+	}
+	for _, portName := range mirrorPorts(mirror) {
+		if !portNames[portName] {
+			return fmt.Errorf("create mirror error: %w: no port named %s", ErrInvalidMirror, portName)
+		}
+	}
+	txn := &vsctlTxn{exec: ovsService.exec, bridgeName: bridgeName}
+	if id != "" {
+		txn.commands = append(txn.commands, []string{"remove", "bridge", bridgeName, "mirrors", id})
+	}
+	txn.commands = append(txn.commands, createMirrorArgs(bridgeName, mirror)...)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("create mirror error: %v", err)
+	}
+	return nil
+}
 
-	// Ensure data is present and has the expected structure
-	if len(ovsOutput.Data) == 0 {
-		return map[string]plsv1.Vxlan{}, nil // No VXLAN interfaces found
+// DeleteMirror removes the mirror from the bridge, which lets ovsdb-server garbage collect it.
+func (ovsService *OvsService) DeleteMirror(bridgeName, mirrorName string) error {
+	id, err := ovsService.mirrorUUID(bridgeName, mirrorName)
+	if err != nil {
+		return fmt.Errorf("delete mirror error: %v", err)
+	}
+	if id == "" {
+		return fmt.Errorf("delete mirror error: %w: %s", ErrMirrorNotFound, mirrorName)
 	}
+	output, err := ovsService.exec.CombinedOutput("remove", "bridge", bridgeName, "mirrors", id)
+	if err != nil {
+		return fmt.Errorf("delete mirror error: %v\nOutput: %s", err, output)
+	}
+	return nil
+}
 
-	for _, item := range ovsOutput.Data {
-		if len(item) < 2 {
-			// log.Printf("Skipping malformed item: %v", item) // Or handle error
-			continue
-		}
+// GetMirrors returns the mirrors of the bridge keyed by name.
+func (ovsService *OvsService) GetMirrors(bridgeName string) (map[string]plsv1.Mirror, error) {
+	mirrors := make(map[string]plsv1.Mirror)
+	rows, err := ovsService.mirrorRows(bridgeName, mirrorColumns...)
+	if err != nil || len(rows) == 0 {
+		return mirrors, err
+	}
+	output, err := ovsService.exec.CombinedOutput("--columns=_uuid,name", "--format=json", "--data=json", "list", "Port")
+	if err != nil {
+		return mirrors, fmt.Errorf("list Port error: %v\nOutput: %s", err, output)
+	}
+	ports, err := vsctlRows(output)
+	if err != nil {
+		return mirrors, err
+	}
+	portNames := make(map[string]string)
+	for _, port := range ports {
+		portNames[port.uuid()] = port.str("name")
+	}
+	for _, row := range rows {
+		mirror := mirrorFromRow(row, portNames)
+		mirrors[mirror.Name] = mirror
+	}
+	return mirrors, nil
+}
 
-		vxlanName, ok := item[0].(string)
-		if !ok {
-			// log.Printf("Skipping item with non-string name: %v", item[0])
-			continue
-		}
+// vsctlTxn chains every change as a "--" separated command of a single ovs-vsctl invocation,
+// which ovs-vsctl applies in one OVSDB transaction.
+type vsctlTxn struct {
+	exec       Client
+	bridgeName string
+	commands   [][]string
+	// qosPorts are the ports whose previous QoS is destroyed on commit, added the ones created by the transaction
+	qosPorts []string
+	added    map[string]bool
+	ids      int
+}
 
-		optionsList, ok := item[1].([]any)
-		if !ok || len(optionsList) < 2 || optionsList[0] != "map" {
-			// log.Printf("Skipping item with malformed options: %v", item[1])
-			continue
-		}
+func (ovsService *OvsService) NewTxn(bridgeName string) Txn {
+	return &vsctlTxn{exec: ovsService.exec, bridgeName: bridgeName}
+}
 
-		optionsMapData, ok := optionsList[1].([]any)
-		if !ok {
-			// log.Printf("Skipping item with malformed options map data: %v", optionsList[1])
+func (txn *vsctlTxn) SetController(controller ...string) {
+	txn.commands = append(txn.commands, setControllerArgs(txn.bridgeName, controller))
+}
+
+func (txn *vsctlTxn) SetProtocol(protocol string) {
+	txn.commands = append(txn.commands, setProtocolArgs(txn.bridgeName, protocol))
+}
+
+func (txn *vsctlTxn) SetDatapathID(datapathId string) {
+	txn.commands = append(txn.commands, setDatapathIDArgs(txn.bridgeName, datapathId))
+}
+
+func (txn *vsctlTxn) SetDatapathType(datapathType string) {
+	txn.commands = append(txn.commands, setDatapathTypeArgs(txn.bridgeName, datapathType))
+}
+
+func (txn *vsctlTxn) SetFailMode(failMode string) {
+	txn.commands = append(txn.commands, setFailModeArgs(txn.bridgeName, failMode))
+}
+
+// SetFlowExport replaces the collectors of the bridge. Like controllers, the previous rows are garbage
+// collected once the bridge stops referencing them.
+func (txn *vsctlTxn) SetFlowExport(export plsv1.FlowExport) {
+	for _, collector := range flowCollectors(export) {
+		if !collector.enabled {
+			txn.commands = append(txn.commands, []string{"clear", "bridge", txn.bridgeName, collector.column})
 			continue
 		}
+		id := txn.id(collector.column)
+		txn.commands = append(txn.commands,
+			[]string{"set", "bridge", txn.bridgeName, collector.column + "=" + id},
+			createFlowCollectorArgs(collector, id),
+		)
+	}
+}
+
+func (txn *vsctlTxn) AddPort(portName string, netIndex int, internal bool) {
+	txn.commands = append(txn.commands, addPortArgs(txn.bridgeName, portName, netIndex, internal))
+	txn.addedPort(portName)
+}
 
-		vxlan := plsv1.Vxlan{VxlanId: vxlanName}
-		options := make(map[string]string)
+func (txn *vsctlTxn) addedPort(portName string) {
+	if txn.added == nil {
+		txn.added = make(map[string]bool)
+	}
+	txn.added[portName] = true
+}
 
-		for _, optionPair := range optionsMapData {
-			pair, ok := optionPair.([]any)
-			if !ok || len(pair) < 2 {
-				// log.Printf("Skipping malformed option pair: %v", optionPair)
-				continue
-			}
-			key, keyOk := pair[0].(string)
-			value, valueOk := pair[1].(string)
-			if keyOk && valueOk {
-				options[key] = value
-			}
+// id returns a record name for a row created in the transaction that no other command uses.
+func (txn *vsctlTxn) id(prefix string) string {
+	txn.ids++
+	return fmt.Sprintf("@%s%d", prefix, txn.ids)
+}
+
+// SetPortQos sets the ingress policing of the port interface and replaces its egress QoS and queues,
+// which are cleared when qos is nil.
+func (txn *vsctlTxn) SetPortQos(portName string, qos *plsv1.PortQos) {
+	rate, burst := ingressPolicing(qos)
+	setInterface := []string{"set", "interface", portName,
+		fmt.Sprintf("ingress_policing_rate=%d", rate), fmt.Sprintf("ingress_policing_burst=%d", burst)}
+	if fingerprint := qosFingerprint(qos); fingerprint != "" {
+		txn.commands = append(txn.commands, append(setInterface, fmt.Sprintf("external_ids:%s=%s", QOS_EXTERNAL_ID, fingerprint)))
+	} else {
+		txn.commands = append(txn.commands, setInterface, []string{"remove", "interface", portName, "external_ids", QOS_EXTERNAL_ID})
+	}
+	if !txn.added[portName] {
+		txn.qosPorts = append(txn.qosPorts, portName)
+	}
+	if !hasEgressQos(qos) {
+		txn.commands = append(txn.commands, []string{"clear", "port", portName, "qos"})
+		return
+	}
+
+	qosId := txn.id("qos")
+	createQos := []string{"--id=" + qosId, "create", "qos", "type=" + qosType(qos)}
+	createQueues := [][]string{}
+	qosConfig := qosOtherConfig(qos)
+	for _, k := range sortedKeys(qosConfig) {
+		createQos = append(createQos, fmt.Sprintf("other-config:%s=%s", k, qosConfig[k]))
+	}
+	for _, queue := range sortedQueues(qos) {
+		queueId := txn.id("queue")
+		createQos = append(createQos, fmt.Sprintf("queues:%d=%s", queue.Id, queueId))
+		createQueue := []string{"--id=" + queueId, "create", "queue"}
+		queueConfig := queueOtherConfig(queue)
+		for _, k := range sortedKeys(queueConfig) {
+			createQueue = append(createQueue, fmt.Sprintf("other-config:%s=%s", k, queueConfig[k]))
 		}
+		createQueues = append(createQueues, createQueue)
+	}
+	txn.commands = append(txn.commands, []string{"set", "port", portName, "qos=" + qosId}, createQos)
+	txn.commands = append(txn.commands, createQueues...)
+}
 
-		vxlan.LocalIp = options["local_ip"]
-		vxlan.RemoteIp = options["remote_ip"]
-		vxlan.UdpPort = options["dst_port"]
-		// The "key" option is usually "flow", you can store it if needed:
-		// vxlan.Key = options["key"]
+// destroyPreviousQos returns the commands that destroy the QoS and queues the ports had before the transaction.
+func (txn *vsctlTxn) destroyPreviousQos() ([][]string, error) {
+	output, err := txn.exec.CombinedOutput(append([]string{"--if-exists", "--columns=name,qos", "--format=json", "--data=json", "list", "Port"}, txn.qosPorts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("list Port error: %v\nOutput: %s", err, output)
+	}
+	ports, err := vsctlRows(output)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"--columns=_uuid,queues", "--format=json", "--data=json", "list", "QoS"}
+	for _, port := range ports {
+		args = append(args, port.uuids("qos")...)
+	}
+	if len(args) == 5 {
+		return nil, nil
+	}
+	output, err = txn.exec.CombinedOutput(args...)
+	if err != nil {
+		return nil, fmt.Errorf("list QoS error: %v\nOutput: %s", err, output)
+	}
+	qosRows, err := vsctlRows(output)
+	if err != nil {
+		return nil, err
+	}
 
-		vxlans = append(vxlans, vxlan)
+	qos, queues := previousQos(ports, qosRows, txn.qosPorts)
+	commands := [][]string{}
+	if len(qos) > 0 {
+		commands = append(commands, append([]string{"destroy", "qos"}, qos...))
 	}
-	vxlansMap := map[string]plsv1.Vxlan{}
-	for _, v := range vxlans {
-		vxlansMap[v.VxlanId] = v
+	if len(queues) > 0 {
+		commands = append(commands, append([]string{"destroy", "queue"}, queues...))
 	}
-	return vxlansMap, nil
+	return commands, nil
+}
+
+func (txn *vsctlTxn) AddBond(port plsv1.Port) {
+	txn.commands = append(txn.commands, addBondArgs(txn.bridgeName, port))
+	txn.addedPort(port.Name)
+}
+
+func (txn *vsctlTxn) SetBond(port plsv1.Port) {
+	txn.commands = append(txn.commands, setBondArgs(port))
+}
+
+func (txn *vsctlTxn) AddBondMember(portName, member string) {
+	id := txn.id("member")
+	txn.commands = append(txn.commands,
+		[]string{"--id=" + id, "create", "interface", "name=" + member},
+		[]string{"add", "port", portName, "interfaces", id},
+	)
+}
+
+// RemoveBondMember detaches the member from the bond, which lets ovsdb-server garbage collect its interface.
+func (txn *vsctlTxn) RemoveBondMember(portName, member string) {
+	id := txn.id("member")
+	txn.commands = append(txn.commands,
+		[]string{"--id=" + id, "get", "interface", member},
+		[]string{"remove", "port", portName, "interfaces", id},
+	)
+}
+
+func (txn *vsctlTxn) SetPortVlan(port plsv1.Port) {
+	txn.commands = append(txn.commands, setPortVlanArgs(port))
+}
+
+func (txn *vsctlTxn) CreateVxlan(vxlan plsv1.Vxlan) {
+	txn.commands = append(txn.commands, createVxlanArgs(txn.bridgeName, vxlan))
+	txn.addedPort(vxlan.VxlanId)
+}
+
+func (txn *vsctlTxn) ModifyVxlan(vxlan plsv1.Vxlan) {
+	txn.commands = append(txn.commands, modifyVxlanArgs(vxlan))
+}
+
+func (txn *vsctlTxn) SetMtu(interfaceName string, mtu int) {
+	txn.commands = append(txn.commands, setMtuArgs(interfaceName, mtu))
+}
+
+func (txn *vsctlTxn) SetSpanningTree(st plsv1.SpanningTree) {
+	txn.commands = append(txn.commands, setSpanningTreeArgs(txn.bridgeName, st)...)
+}
+
+func (txn *vsctlTxn) SetPathCost(portName string, cost int) {
+	txn.commands = append(txn.commands, setPathCostArgs(portName, cost))
+}
+
+func (txn *vsctlTxn) SetExternalIds(table, record string, externalIds map[string]string) {
+	txn.commands = append(txn.commands, setExternalIdsArgs(table, record, externalIds))
+}
+
+func (txn *vsctlTxn) DeletePort(portName string) {
+	txn.commands = append(txn.commands, []string{"del-port", txn.bridgeName, portName})
+}
+
+func (txn *vsctlTxn) DeleteVxlan(vxlanId string) {
+	txn.DeletePort(vxlanId)
+}
+
+func (txn *vsctlTxn) Commit() error {
+	if len(txn.commands) == 0 {
+		return nil
+	}
+	commands := txn.commands
+	if len(txn.qosPorts) > 0 {
+		destroy, err := txn.destroyPreviousQos()
+		if err != nil {
+			return err
+		}
+		commands = append(commands, destroy...)
+	}
+	args := []string{}
+	for i, command := range commands {
+		if i > 0 {
+			args = append(args, "--")
+		}
+		args = append(args, command...)
+	}
+
+	output, err := txn.exec.CombinedOutput(args...)
+	if err != nil {
+		return fmt.Errorf("transaction error: %v\nOutput: %s", err, output)
+	}
+	return nil
 }
@@ -3,8 +3,10 @@ package ovs
 import (
 	"bytes"
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
 )
@@ -136,9 +138,28 @@ func TestAddPort(t *testing.T) {
 	}
 }
 
+// listPortsArgs are the arguments of the single ovs-vsctl invocation GetPorts lists the ports of br0 with.
+const listPortsArgs = "--format=json --data=json --columns=ports list Bridge br0 -- " +
+	"--columns=_uuid,name,interfaces,external_ids,qos,tag,trunks,vlan_mode,bond_mode,lacp,bond_active_slave list Port -- " +
+	"--columns=_uuid,name,ofport,type,admin_state,link_state,mac_in_use,mtu,mtu_request,external_ids,statistics,lacp_current,ingress_policing_rate,ingress_policing_burst list Interface -- " +
+	"--columns=_uuid,type,other_config,queues list QoS -- " +
+	"--columns=_uuid,other_config list Queue"
+
 func TestGetPorts(t *testing.T) {
+	// br0 holds eth1, eth2 and its local port, which is left out, but not eth3 of another bridge
+	output := `{"data": [[["set", [["uuid", "p0"], ["uuid", "p1"], ["uuid", "p2"]]]]], "headings": ["ports"]}
+{"data": [
+	[["uuid", "p0"], "br0", ["set", []], ["map", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []]],
+	[["uuid", "p1"], "eth1", ["set", []], ["map", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []]],
+	[["uuid", "p2"], "eth2", ["set", []], ["map", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []]],
+	[["uuid", "p3"], "eth3", ["set", []], ["map", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []]]
+], "headings": ["_uuid", "name", "interfaces", "external_ids", "qos", "tag", "trunks", "vlan_mode", "bond_mode", "lacp", "bond_active_slave"]}
+{"data": [], "headings": ["_uuid", "name"]}
+{"data": [], "headings": ["_uuid", "type", "other_config", "queues"]}
+{"data": [], "headings": ["_uuid", "other_config"]}
+`
 	mock := &MockClient{
-		Commands: map[string][]byte{"list-ports br0": []byte("eth1\neth2")},
+		Commands: map[string][]byte{listPortsArgs: []byte(output)},
 		Errors:   map[string]error{},
 	}
 	svc := OvsService{exec: mock}
@@ -169,16 +190,21 @@ func TestGetController(t *testing.T) {
 func TestGetVxlans(t *testing.T) {
 	raw := `{
 		"data": [
-			["vx0", ["map", [["remote_ip", "10.0.0.2"], ["local_ip", "10.0.0.1"], ["dst_port", "4789"]]]]
+			["vx0", "vxlan", ["map", [["remote_ip", "10.0.0.2"], ["local_ip", "10.0.0.1"], ["dst_port", "4789"]]],
+				3, "up", "up", ["set", []], ["map", [["state", "up"]]], ["map", [["rx_packets", 10], ["tx_packets", 12]]]],
+			["eth1", "", ["map", []], 1, "up", "down", ["set", []], ["map", []], ["map", []]]
 		],
-		"headings": ["name", "options"]
+		"headings": ["name", "type", "options", "ofport", "admin_state", "link_state", "error", "bfd_status", "statistics"]
 	}`
 
-	key := "--column=name,options --format=json --data=json find Interface type=vxlan"
+	key := "--columns=name,type,options,ofport,admin_state,link_state,error,bfd_status,mtu_request,external_ids,statistics --format=json --data=json list Interface vx0 eth1"
 
 	mock := &MockClient{
-		Commands: map[string][]byte{key: []byte(raw)},
-		Errors:   map[string]error{},
+		Commands: map[string][]byte{
+			"list-ifaces br0": []byte("vx0\neth1\n"),
+			key:               []byte(raw),
+		},
+		Errors: map[string]error{},
 	}
 
 	svc := OvsService{exec: mock}
@@ -199,4 +225,512 @@ func TestGetVxlans(t *testing.T) {
 	if vx.RemoteIp != "10.0.0.2" || vx.LocalIp != "10.0.0.1" || vx.UdpPort != "4789" {
 		t.Errorf("unexpected vxlan fields: %+v", vx)
 	}
+	if vx.Status == nil || vx.Status.Ofport != 3 || vx.Status.LinkState != "up" || vx.Status.Error != "" ||
+		vx.Status.BfdStatus["state"] != "up" || vx.Status.Statistics["tx_packets"] != 12 {
+		t.Errorf("unexpected vxlan status: %+v", vx.Status)
+	}
+}
+
+func TestTxnCommit(t *testing.T) {
+	vx := plsv1.Vxlan{
+		VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "4789",
+	}
+	key := "set-controller br0 tcp:127.0.0.1:6633 -- set bridge br0 other-config:datapath-id=1234 -- " +
+		"add-port br0 vx0 -- set interface vx0 type=vxlan options:key=flow options:remote_ip=10.0.0.2 options:local_ip=10.0.0.1 options:dst_port=4789 -- " +
+		"del-port br0 vx1"
+	mock := &MockClient{
+		Commands: map[string][]byte{key: []byte("")},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+
+	txn := svc.NewTxn("br0")
+	txn.SetController("tcp:127.0.0.1:6633")
+	txn.SetDatapathID("1234")
+	txn.CreateVxlan(vx)
+	txn.DeleteVxlan("vx1")
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mock.Called) != 1 || mock.Called[0] != key {
+		t.Fatalf("expected a single chained ovs-vsctl call, got: %v", mock.Called)
+	}
+}
+
+func TestSetFailMode(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	if err := svc.SetFailMode("br0", "secure"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetFailMode("br0", ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mock.Called[0] != "set-fail-mode br0 secure" || mock.Called[1] != "del-fail-mode br0" {
+		t.Errorf("unexpected commands: %v", mock.Called)
+	}
+}
+
+func TestSetDatapathType(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	if err := svc.SetDatapathType("br0", plsv1.DATAPATH_NETDEV); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetDatapathType("br0", ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mock.Called[0] != "set bridge br0 datapath_type=netdev" || mock.Called[1] != `set bridge br0 datapath_type=""` {
+		t.Errorf("unexpected commands: %v", mock.Called)
+	}
+}
+
+func TestCreatePatch(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	port := PatchPort{Bridge: "ned", Name: "lsned003", Ofport: 3}
+	peer := PatchPort{Bridge: "sps", Name: "lssps007", Ofport: NO_DEFAULT_ID}
+	if err := svc.CreatePatch(port, peer); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := "add-port ned lsned003 -- set interface lsned003 type=patch options:peer=lssps007 ofport_request=3 -- " +
+		"add-port sps lssps007 -- set interface lssps007 type=patch options:peer=lsned003"
+	if len(mock.Called) != 1 || mock.Called[0] != expected {
+		t.Errorf("expected a single chained ovs-vsctl call, got: %v", mock.Called)
+	}
+}
+
+func TestDeletePatch(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	if err := svc.DeletePatch("lsned003", "lssps007"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := "--if-exists del-port lsned003 -- --if-exists del-port lssps007"
+	if len(mock.Called) != 1 || mock.Called[0] != expected {
+		t.Errorf("expected a single chained ovs-vsctl call, got: %v", mock.Called)
+	}
+}
+
+func TestSetSpanningTree(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	priority := 8192
+	if err := svc.SetSpanningTree("br0", plsv1.SpanningTree{Protocol: plsv1.STP_PROTOCOL_STP, Priority: &priority}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetPathCost("vxlan-a", 100); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// an RSTP cost beyond the range of STP is capped for STP
+	if err := svc.SetPathCost("vxlan-b", 2000000); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []string{
+		"remove bridge br0 other_config stp-priority rstp-priority -- set bridge br0 stp_enable=true rstp_enable=false other_config:stp-priority=8192",
+		"set port vxlan-a other_config:rstp-path-cost=100 other_config:stp-path-cost=100",
+		"set port vxlan-b other_config:rstp-path-cost=2000000 other_config:stp-path-cost=65535",
+	}
+	if !reflect.DeepEqual(mock.Called, expected) {
+		t.Errorf("unexpected commands: %v", mock.Called)
+	}
+}
+
+func TestGetControllerStatus(t *testing.T) {
+	raw := `{"data":[["tcp:10.0.0.1:6633",true,"master",["map",[["sec_since_connect","12"],["state","ACTIVE"]]]],` +
+		`["tcp:10.0.0.2:6633",false,["set",[]],["map",[["last_error","Connection refused"],["state","BACKOFF"]]]]],` +
+		`"headings":["target","is_connected","role","status"]}`
+	mock := &MockClient{
+		Commands: map[string][]byte{
+			"get Bridge br0 controller": []byte("[1b2c, 3d4e]\n"),
+			"--columns=target,is_connected,role,status --format=json --data=json list Controller 1b2c 3d4e": []byte(raw),
+		},
+		Errors: map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	statuses, err := svc.GetControllerStatus("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 controllers, got: %v", statuses)
+	}
+	if s := statuses[0]; !s.IsConnected || s.Role != "master" || s.Status["state"] != "ACTIVE" || s.LastError != "" {
+		t.Errorf("unexpected status: %+v", s)
+	}
+	if s := statuses[1]; s.IsConnected || s.Role != "" || s.LastError != "Connection refused" {
+		t.Errorf("unexpected status: %+v", s)
+	}
+
+	// the switch lists them by target, like the ovsdb backend
+	mock.Commands["get Bridge br0 controller"] = []byte("[3d4e, 1b2c]\n")
+	mock.Commands["--columns=target,is_connected,role,status --format=json --data=json list Controller 3d4e 1b2c"] = []byte(
+		`{"data":[["tcp:10.0.0.2:6633",false,["set",[]],["map",[]]],["tcp:10.0.0.1:6633",true,"master",["map",[]]]],` +
+			`"headings":["target","is_connected","role","status"]}`)
+	vs := VirtualSwitch{bridge: plsv1.Bridge{Name: "br0"}, ovsService: &svc}
+	statuses, err = vs.GetControllerStatus()
+	if err != nil || len(statuses) != 2 || statuses[0].Target != "tcp:10.0.0.1:6633" || statuses[1].Target != "tcp:10.0.0.2:6633" {
+		t.Errorf("expected the controllers sorted by target, got: %v %v", statuses, err)
+	}
+
+	mock.Commands["get Bridge br0 controller"] = []byte("[]\n")
+	statuses, err = svc.GetControllerStatus("br0")
+	if err != nil || len(statuses) != 0 {
+		t.Errorf("expected no controllers, got: %v %v", statuses, err)
+	}
+}
+
+func TestCreateTunnel(t *testing.T) {
+	gre := plsv1.Vxlan{VxlanId: "gre0", Type: plsv1.TUNNEL_GRE, LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "7000",
+		Options: map[string]string{"packet_type": "legacy_l2"}}
+	geneve := plsv1.Vxlan{VxlanId: "gnv0", Type: plsv1.TUNNEL_GENEVE, RemoteIp: "10.0.0.3", UdpPort: "6081"}
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	if err := svc.CreateVxlan("br0", gre); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.CreateVxlan("br0", geneve); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := []string{
+		"add-port br0 gre0 -- set interface gre0 type=gre options:key=flow options:remote_ip=10.0.0.2 options:local_ip=10.0.0.1 options:packet_type=legacy_l2",
+		"add-port br0 gnv0 -- set interface gnv0 type=geneve options:key=flow options:remote_ip=10.0.0.3 options:dst_port=6081",
+	}
+	for i, command := range expected {
+		if mock.Called[i] != command {
+			t.Errorf("unexpected command: %s", mock.Called[i])
+		}
+	}
+}
+
+func TestVxlanOptions(t *testing.T) {
+	df, csum := true, false
+	vx := plsv1.Vxlan{VxlanId: "vx0", RemoteIp: "10.0.0.2", LocalIp: "10.0.0.1", UdpPort: "4789", Key: "5000",
+		Tos: "inherit", Ttl: "64", DfDefault: &df, Csum: &csum, EgressPktMark: "7"}
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	if err := svc.ModifyVxlan(vx); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := "clear interface vx0 options -- set interface vx0 type=vxlan options:key=5000 options:remote_ip=10.0.0.2 " +
+		"options:local_ip=10.0.0.1 options:dst_port=4789 options:tos=inherit options:ttl=64 options:df_default=true " +
+		"options:csum=false options:egress_pkt_mark=7"
+	if mock.Called[0] != expected {
+		t.Errorf("unexpected command: %s", mock.Called[0])
+	}
+
+	parsed, ok := tunnelFromInterface("vx0", "vxlan", tunnelOptions(vx))
+	if !ok {
+		t.Fatalf("expected vx0 to be a tunnel")
+	}
+	if parsed.Key != "5000" || parsed.Tos != "inherit" || parsed.Ttl != "64" || parsed.EgressPktMark != "7" || parsed.Options != nil {
+		t.Errorf("unexpected tunnel: %+v", parsed)
+	}
+	if parsed.DfDefault == nil || !*parsed.DfDefault || parsed.Csum == nil || *parsed.Csum {
+		t.Errorf("unexpected df_default or csum: %v %v", parsed.DfDefault, parsed.Csum)
+	}
+}
+
+func TestGetPortsState(t *testing.T) {
+	portsRaw := `{
+		"data": [
+			[["uuid", "p1"], "lsabcde1", ["uuid", "i1"], ["map", [["managed-by", "talpa"], ["talpa-role", "port"]]], ["uuid", "q1"], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []]],
+			[["uuid", "p2"], "vx0", ["uuid", "i2"], ["map", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []], ["set", []]],
+			[["uuid", "p3"], "bond0", ["set", [["uuid", "i3"], ["uuid", "i4"]]], ["map", []], ["set", []], ["set", []], ["set", []], ["set", []], "active-backup", "active", "aa:bb:cc:dd:ee:04"]
+		],
+		"headings": ["_uuid", "name", "interfaces", "external_ids", "qos", "tag", "trunks", "vlan_mode", "bond_mode", "lacp", "bond_active_slave"]
+	}`
+	ifacesRaw := `{
+		"data": [
+			[["uuid", "i1"], "lsabcde1", 3, "", "up", "up", "aa:bb:cc:dd:ee:01", 1500, ["map", [["pod", "default/nginx"]]], ["map", [["rx_packets", 5]]], ["set", []], 1000, 100],
+			[["uuid", "i2"], "vx0", 4, "vxlan", "up", "up", "aa:bb:cc:dd:ee:02", ["set", []], ["map", []], ["map", []], ["set", []], 0, 0],
+			[["uuid", "i3"], "eth2", 5, "", "up", "down", "aa:bb:cc:dd:ee:03", 1500, ["map", []], ["map", []], false, 0, 0],
+			[["uuid", "i4"], "eth1", 5, "", "up", "up", "aa:bb:cc:dd:ee:04", 1500, ["map", []], ["map", []], true, 0, 0]
+		],
+		"headings": ["_uuid", "name", "ofport", "type", "admin_state", "link_state", "mac_in_use", "mtu", "external_ids", "statistics", "lacp_current", "ingress_policing_rate", "ingress_policing_burst"]
+	}`
+	qosRaw := `{
+		"data": [[["uuid", "q1"], "linux-htb", ["map", [["max-rate", "10000000"]]], ["map", [[1, ["uuid", "u1"]]]]]],
+		"headings": ["_uuid", "type", "other_config", "queues"]
+	}`
+	queuesRaw := `{
+		"data": [[["uuid", "u1"], ["map", [["max-rate", "5000000"], ["priority", "2"]]]]],
+		"headings": ["_uuid", "other_config"]
+	}`
+	bridgeRaw := `{"data": [[["set", [["uuid", "p1"], ["uuid", "p2"], ["uuid", "p3"]]]]], "headings": ["ports"]}`
+	mock := &MockClient{
+		Commands: map[string][]byte{
+			listPortsArgs: []byte(strings.Join([]string{bridgeRaw, portsRaw, ifacesRaw, qosRaw, queuesRaw}, "\n")),
+		},
+		Errors: map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	ports, err := svc.GetPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if role := ports["lsabcde1"].Role; role != plsv1.PORT_ROLE_PORT {
+		t.Errorf("expected lsabcde1 to be a talpa port, got role: %q", role)
+	}
+	state := ports["lsabcde1"].State
+	if state == nil || state.Ofport != 3 || state.LinkState != "up" || state.Mtu != 1500 ||
+		state.ExternalIds["pod"] != "default/nginx" || state.Statistics["rx_packets"] != 5 {
+		t.Errorf("unexpected port state: %+v", state)
+	}
+	if state := ports["vx0"].State; state == nil || state.Type != "vxlan" || state.Mtu != 0 {
+		t.Errorf("unexpected port state: %+v", state)
+	}
+	// the traffic controls are read back from the interface and the QoS and Queue rows of the port
+	qos := &plsv1.PortQos{
+		IngressPolicingRate:  1000,
+		IngressPolicingBurst: 100,
+		Type:                 plsv1.QOS_LINUX_HTB,
+		MaxRate:              10000000,
+		Queues:               []plsv1.Queue{{Id: 1, MaxRate: 5000000, Priority: 2}},
+	}
+	if port := ports["lsabcde1"]; !reflect.DeepEqual(port.Qos, qos) {
+		t.Errorf("unexpected qos: %+v", port.Qos)
+	}
+	if port := ports["vx0"]; port.Qos != nil {
+		t.Errorf("expected vx0 to have no qos, got: %+v", port.Qos)
+	}
+
+	// a bond has no interface with its name, its members are reported instead
+	expected := &plsv1.Bond{
+		Members: []string{"eth1", "eth2"},
+		Mode:    plsv1.BOND_MODE_ACTIVE_BACKUP,
+		Lacp:    plsv1.LACP_ACTIVE,
+		Status: []plsv1.BondMemberStatus{
+			{Name: "eth1", LinkState: "up", Active: true, LacpCurrent: true},
+			{Name: "eth2", LinkState: "down"},
+		},
+	}
+	if bond := ports["bond0"]; bond.State != nil || !reflect.DeepEqual(bond.Bond, expected) {
+		t.Errorf("unexpected bond: %+v", bond.Bond)
+	}
+}
+
+func TestAddBond(t *testing.T) {
+	mock := &MockClient{
+		Commands: map[string][]byte{},
+		Errors:   map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	bond := plsv1.Port{Name: "bond0", Bond: &plsv1.Bond{Members: []string{"eth2", "eth1"}, Mode: plsv1.BOND_MODE_BALANCE_TCP, Lacp: plsv1.LACP_ACTIVE}}
+	if err := svc.AddBond("br0", bond); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	txn := svc.NewTxn("br0")
+	bond.Bond.Mode, bond.Bond.Lacp = "", ""
+	txn.SetBond(bond)
+	txn.AddBondMember("bond0", "eth3")
+	txn.RemoveBondMember("bond0", "eth1")
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []string{
+		"add-bond br0 bond0 eth1 eth2 bond_mode=balance-tcp lacp=active",
+		"set port bond0 bond_mode=[] lacp=[] -- " +
+			"--id=@member1 create interface name=eth3 -- add port bond0 interfaces @member1 -- " +
+			"--id=@member2 get interface eth1 -- remove port bond0 interfaces @member2",
+	}
+	if !reflect.DeepEqual(mock.Called, expected) {
+		t.Errorf("unexpected commands: %v", mock.Called)
+	}
+}
+
+func TestSetPortQos(t *testing.T) {
+	lookup := "--if-exists --columns=name,qos --format=json --data=json list Port eth1"
+	qosLookup := "--columns=_uuid,queues --format=json --data=json list QoS 1111"
+	qos := &plsv1.PortQos{
+		IngressPolicingRate: 10000, IngressPolicingBurst: 1000, MaxRate: 100000000,
+		Queues: []plsv1.Queue{{Id: 1, MinRate: 1000000, MaxRate: 50000000}, {Id: 0, Priority: 1}},
+	}
+	key := "set interface eth1 ingress_policing_rate=10000 ingress_policing_burst=1000 external_ids:talpa-qos=" + qosFingerprint(qos) + " -- " +
+		"set port eth1 qos=@qos1 -- " +
+		"--id=@qos1 create qos type=linux-htb other-config:max-rate=100000000 queues:0=@queue2 queues:1=@queue3 -- " +
+		"--id=@queue2 create queue other-config:priority=1 -- " +
+		"--id=@queue3 create queue other-config:max-rate=50000000 other-config:min-rate=1000000 -- " +
+		"destroy qos 1111 -- destroy queue 2222 3333"
+	mock := &MockClient{
+		Commands: map[string][]byte{
+			lookup:    []byte(`{"data":[["eth1",["uuid","1111"]]],"headings":["name","qos"]}`),
+			qosLookup: []byte(`{"data":[[["uuid","1111"],["map",[[0,["uuid","2222"]],[1,["uuid","3333"]]]]]],"headings":["_uuid","queues"]}`),
+			key:       []byte(""),
+		},
+		Errors: map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	if err := svc.SetPortQos("br0", "eth1", qos); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mock.Called) != 3 || mock.Called[2] != key {
+		t.Fatalf("unexpected ovs-vsctl calls: %v", mock.Called)
+	}
+
+	// a port created in the same transaction has no previous qos to look up
+	key = "add-port br0 eth2 -- set interface eth2 ingress_policing_rate=0 ingress_policing_burst=0 external_ids:talpa-qos=" + qosFingerprint(&plsv1.PortQos{}) +
+		" -- clear port eth2 qos"
+	mock = &MockClient{Commands: map[string][]byte{key: []byte("")}, Errors: map[string]error{}}
+	svc = OvsService{exec: mock}
+	txn := svc.NewTxn("br0")
+	txn.AddPort("eth2", NO_DEFAULT_ID, false)
+	txn.SetPortQos("eth2", &plsv1.PortQos{})
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mock.Called) != 1 || mock.Called[0] != key {
+		t.Fatalf("expected a single chained ovs-vsctl call, got: %v", mock.Called)
+	}
+}
+
+func TestSetPortVlan(t *testing.T) {
+	tag := 10
+	tests := []struct {
+		port plsv1.Port
+		key  string
+	}{
+		{plsv1.Port{Name: "eth1", Tag: &tag, VlanMode: plsv1.VLAN_MODE_ACCESS}, "set port eth1 tag=10 trunks=[] vlan_mode=access"},
+		{plsv1.Port{Name: "eth1", Tag: &tag, Trunks: []int{30, 20}, VlanMode: plsv1.VLAN_MODE_NATIVE_UNTAGGED}, "set port eth1 tag=10 trunks=[20,30] vlan_mode=native-untagged"},
+		{plsv1.Port{Name: "eth1"}, "set port eth1 tag=[] trunks=[] vlan_mode=[]"},
+	}
+	for _, tt := range tests {
+		mock := &MockClient{Commands: map[string][]byte{}, Errors: map[string]error{}}
+		svc := OvsService{exec: mock}
+		if err := svc.SetPortVlan("br0", tt.port); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(mock.Called) != 1 || mock.Called[0] != tt.key {
+			t.Errorf("unexpected ovs-vsctl call: got %v, want %s", mock.Called, tt.key)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	mirrors := "get bridge br0 mirrors"
+	lookup := "--columns=_uuid,name --format=json --data=json list Mirror 1111"
+	expires := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	mirror := plsv1.Mirror{Name: "m1", SelectSrcPorts: []string{"eth2", "eth1"}, SelectDstPorts: []string{"eth1"}, OutputPort: "probe", ExpiresAt: &expires}
+	key := "remove bridge br0 mirrors 1111 -- " +
+		"--id=@mirrorport0 get port eth1 -- --id=@mirrorport1 get port eth2 -- --id=@mirrorport2 get port probe -- " +
+		"--id=@mirror create mirror name=m1 select_src_port=[@mirrorport0,@mirrorport1] select_dst_port=[@mirrorport0] select_vlan=[] select_all=false " +
+		"output_port=@mirrorport2 external_ids:talpa-expires=2026-10-18T12:00:00Z -- " +
+		"add bridge br0 mirrors @mirror"
+	mock := &MockClient{
+		Commands: map[string][]byte{
+			mirrors: []byte("[1111]\n"),
+			lookup:  []byte(`{"data":[[["uuid","1111"],"m1"]],"headings":["_uuid","name"]}`),
+			"--columns=_uuid,name --format=json --data=json list Port": []byte(
+				`{"data":[[["uuid","aaaa"],"eth1"],[["uuid","bbbb"],"eth2"],[["uuid","cccc"],"probe"]],"headings":["_uuid","name"]}`),
+		},
+		Errors: map[string]error{},
+	}
+	svc := OvsService{exec: mock}
+	// the mirror with the same name is replaced in the same transaction
+	if err := svc.CreateMirror("br0", mirror); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mock.Called) != 4 || mock.Called[3] != key {
+		t.Fatalf("unexpected ovs-vsctl calls: %v", mock.Called)
+	}
+
+	// a mirror of a port that does not exist is invalid, as with the ovsdb backend
+	missing := plsv1.Mirror{Name: "m2", SelectSrcPorts: []string{"eth9"}, OutputPort: "probe"}
+	if err := svc.CreateMirror("br0", missing); !errors.Is(err, ErrInvalidMirror) {
+		t.Errorf("expected an invalid mirror error, got: %v", err)
+	}
+
+	mock.Commands["--columns=_uuid,name,select_all,select_src_port,select_dst_port,select_vlan,output_port,external_ids --format=json --data=json list Mirror 1111"] = []byte(
+		`{"data":[[["uuid","1111"],"m1",false,["set",[["uuid","aaaa"],["uuid","bbbb"]]],["uuid","aaaa"],["set",[]],["uuid","cccc"],["map",[["talpa-expires","2026-10-18T12:00:00Z"]]]]],` +
+			`"headings":["_uuid","name","select_all","select_src_port","select_dst_port","select_vlan","output_port","external_ids"]}`)
+	got, err := svc.GetMirrors("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	mirror.SelectSrcPorts = []string{"eth1", "eth2"}
+	if !reflect.DeepEqual(got["m1"], mirror) {
+		t.Errorf("unexpected mirror: got %+v, want %+v", got["m1"], mirror)
+	}
+
+	if err := svc.DeleteMirror("br0", "m1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if last := mock.Called[len(mock.Called)-1]; last != "remove bridge br0 mirrors 1111" {
+		t.Errorf("unexpected ovs-vsctl call: %s", last)
+	}
+	if err := svc.DeleteMirror("br0", "missing"); err == nil {
+		t.Errorf("expected an error on a missing mirror")
+	}
+}
+
+func TestSetFlowExport(t *testing.T) {
+	export := plsv1.FlowExport{
+		Sflow:   &plsv1.Sflow{Targets: []string{"10.0.0.1:6343"}, Sampling: 64, Polling: 10, Agent: "eth0"},
+		Netflow: &plsv1.Netflow{Targets: []string{"10.0.0.1:2055", "10.0.0.2:2055"}},
+	}
+	key := `set bridge br0 sflow=@sflow1 -- --id=@sflow1 create sflow targets=["10.0.0.1:6343"] agent="eth0" polling=10 sampling=64 -- ` +
+		`clear bridge br0 ipfix -- ` +
+		`set bridge br0 netflow=@netflow2 -- --id=@netflow2 create netflow targets=["10.0.0.1:2055","10.0.0.2:2055"]`
+	mock := &MockClient{Commands: map[string][]byte{}, Errors: map[string]error{}}
+	svc := OvsService{exec: mock}
+	if err := svc.SetFlowExport("br0", export); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mock.Called) != 1 || mock.Called[0] != key {
+		t.Errorf("unexpected ovs-vsctl call: got %v, want %s", mock.Called, key)
+	}
+}
+
+func TestTunnelMtu(t *testing.T) {
+	tests := []struct {
+		vxlan plsv1.Vxlan
+		want  int
+	}{
+		{plsv1.Vxlan{RemoteIp: "10.0.0.2"}, 1450},
+		{plsv1.Vxlan{Type: plsv1.TUNNEL_GENEVE, RemoteIp: "10.0.0.2"}, 1450},
+		{plsv1.Vxlan{Type: plsv1.TUNNEL_GRE, RemoteIp: "10.0.0.2"}, 1458},
+		{plsv1.Vxlan{Type: plsv1.TUNNEL_STT, RemoteIp: "10.0.0.2"}, 1428},
+		{plsv1.Vxlan{RemoteIp: "fd00::2"}, 1430},
+	}
+	for _, tt := range tests {
+		if got := TunnelMtu(1500, tt.vxlan); got != tt.want {
+			t.Errorf("TunnelMtu(%s to %s): got %d, want %d", tt.vxlan.Type, tt.vxlan.RemoteIp, got, tt.want)
+		}
+	}
+
+	mock := &MockClient{Commands: map[string][]byte{}, Errors: map[string]error{}}
+	svc := OvsService{exec: mock}
+	if err := svc.SetMtu("vx0", 1450); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.SetMtu("vx0", 0); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	want := []string{"set interface vx0 mtu_request=1450", "set interface vx0 mtu_request=[]"}
+	if !reflect.DeepEqual(mock.Called, want) {
+		t.Errorf("unexpected ovs-vsctl calls: got %v, want %v", mock.Called, want)
+	}
 }
@@ -0,0 +1,131 @@
+package ovs
+
+import (
+	"fmt"
+	"strings"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// External ids talpa labels the bridges, ports and interfaces it creates with, so it tells them apart from the ones
+// of other tools without relying on their names. Only rows with MANAGED_BY_EXTERNAL_ID set to MANAGED_BY_TALPA are
+// talpa's, and only those are ever pruned, along with the unlabelled tunnels talpa created before it labelled them.
+const (
+	MANAGED_BY_EXTERNAL_ID = "managed-by"
+	MANAGED_BY_TALPA       = "talpa"
+	SWITCH_EXTERNAL_ID     = "talpa-switch"
+	NODE_EXTERNAL_ID       = "talpa-node"
+	PROVIDER_EXTERNAL_ID   = "talpa-provider"
+	NEIGHBOR_EXTERNAL_ID   = "talpa-neighbor"
+	ROLE_EXTERNAL_ID       = "talpa-role"
+	// PATCH_PEER_EXTERNAL_ID records the port at the other end of a patch port talpa created
+	PATCH_PEER_EXTERNAL_ID = "talpa-patch-peer"
+)
+
+// ownerExternalIds returns the labels of a row talpa creates on the bridge. Empty fields are left out.
+func ownerExternalIds(bridgeName string, owner plsv1.Owner, role string) map[string]string {
+	externalIds := map[string]string{
+		MANAGED_BY_EXTERNAL_ID: MANAGED_BY_TALPA,
+		SWITCH_EXTERNAL_ID:     bridgeName,
+	}
+	for k, v := range map[string]string{NODE_EXTERNAL_ID: owner.Node, PROVIDER_EXTERNAL_ID: owner.Provider, ROLE_EXTERNAL_ID: role} {
+		if v != "" {
+			externalIds[k] = v
+		}
+	}
+	return externalIds
+}
+
+// tunnelExternalIds returns the labels of the tunnel, which also record the neighbor at its other end.
+func tunnelExternalIds(bridgeName string, owner plsv1.Owner, vxlan plsv1.Vxlan) map[string]string {
+	externalIds := ownerExternalIds(bridgeName, owner, plsv1.PORT_ROLE_TUNNEL)
+	if vxlan.Neighbor != "" {
+		externalIds[NEIGHBOR_EXTERNAL_ID] = vxlan.Neighbor
+	}
+	return externalIds
+}
+
+// isManaged reports whether the external ids are the ones of a row talpa created.
+func isManaged(externalIds map[string]string) bool {
+	return externalIds[MANAGED_BY_EXTERNAL_ID] == MANAGED_BY_TALPA
+}
+
+// setExternalIdsArgs returns the command that sets the external ids of the record, keeping its other keys.
+func setExternalIdsArgs(table, record string, externalIds map[string]string) []string {
+	args := []string{"set", table, record}
+	for _, k := range sortedKeys(externalIds) {
+		args = append(args, fmt.Sprintf("external_ids:%s=%s", k, externalIds[k]))
+	}
+	return args
+}
+
+// externalIdsOps returns the operations that set the external ids of the named row, keeping its other keys.
+func externalIdsOps(table, name string, externalIds map[string]string) []ovsdbOp {
+	keys := []any{}
+	for _, k := range sortedKeys(externalIds) {
+		keys = append(keys, k)
+	}
+	return []ovsdbOp{
+		opExists(table, where(cond("name", "==", name))),
+		opMutate(table, where(cond("name", "==", name)),
+			mutation("external_ids", "delete", ovsdbSet(keys...)),
+			mutation("external_ids", "insert", ovsdbMap(externalIds)),
+		),
+	}
+}
+
+// managedTunnel reports whether talpa created the tunnel.
+func managedTunnel(vxlan plsv1.Vxlan) bool {
+	return vxlan.Status != nil && isManaged(vxlan.Status.ExternalIds)
+}
+
+// legacyTunnel reports whether the tunnel is unlabelled but named as talpa names its tunnels, <type>-<5 hex digits>,
+// which is how the tunnels talpa created before it labelled them are told apart from the ones of other tools.
+func legacyTunnel(vxlan plsv1.Vxlan) bool {
+	if vxlan.Status != nil && vxlan.Status.ExternalIds[MANAGED_BY_EXTERNAL_ID] != "" {
+		return false
+	}
+	tunnelType, digest, ok := strings.Cut(vxlan.VxlanId, "-")
+	if !ok || !plsv1.IsTunnelType(tunnelType) || len(digest) != 5 {
+		return false
+	}
+	for _, c := range digest {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// rowLabels are the external ids talpa sets on a row of a table.
+type rowLabels struct {
+	table       string
+	record      string
+	externalIds map[string]string
+}
+
+// portLabels returns the labels of the port and of its interfaces, the members of a bond.
+func portLabels(bridgeName string, owner plsv1.Owner, port plsv1.Port) []rowLabels {
+	externalIds := ownerExternalIds(bridgeName, owner, port.Role)
+	labels := []rowLabels{{table: "Port", record: port.Name, externalIds: externalIds}}
+	for _, iface := range portInterfaces(port) {
+		labels = append(labels, rowLabels{table: "Interface", record: iface, externalIds: externalIds})
+	}
+	return labels
+}
+
+// tunnelLabels returns the labels of the port of the tunnel and of its interface.
+func tunnelLabels(bridgeName string, owner plsv1.Owner, vxlan plsv1.Vxlan) []rowLabels {
+	externalIds := tunnelExternalIds(bridgeName, owner, vxlan)
+	return []rowLabels{
+		{table: "Port", record: vxlan.VxlanId, externalIds: externalIds},
+		{table: "Interface", record: vxlan.VxlanId, externalIds: externalIds},
+	}
+}
+
+// setLabels adds the labels to the transaction.
+func setLabels(txn Txn, labels []rowLabels) {
+	for _, l := range labels {
+		txn.SetExternalIds(l.table, l.record, l.externalIds)
+	}
+}
@@ -0,0 +1,54 @@
+package ovs
+
+import (
+	"fmt"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// PatchPort is one end of a pair of patch ports, which link two bridges of the same OVS instance
+// without going through the kernel.
+type PatchPort struct {
+	Bridge string
+	Name   string
+	// Ofport is the OpenFlow port number requested for the port, NO_DEFAULT_ID to let OVS choose it
+	Ofport int
+	// ExternalIds are set on both the port and its interface
+	ExternalIds map[string]string
+}
+
+// patchPeer returns the name of the port at the other end of a patch port talpa created, or empty if the port is not one.
+func patchPeer(port plsv1.Port) string {
+	if port.State == nil || port.State.Type != "patch" {
+		return ""
+	}
+	return port.State.ExternalIds[PATCH_PEER_EXTERNAL_ID]
+}
+
+// addPatchPortArgs returns the command that adds the port to its bridge as the patch to peer.
+func addPatchPortArgs(port, peer PatchPort) []string {
+	externalIds := []string{}
+	for _, k := range sortedKeys(port.ExternalIds) {
+		externalIds = append(externalIds, fmt.Sprintf("external_ids:%s=%s", k, port.ExternalIds[k]))
+	}
+	args := append([]string{"add-port", port.Bridge, port.Name}, externalIds...)
+	args = append(args, "--", "set", "interface", port.Name, "type=patch", "options:peer="+peer.Name)
+	if port.Ofport != NO_DEFAULT_ID {
+		args = append(args, fmt.Sprintf("ofport_request=%d", port.Ofport))
+	}
+	return append(args, externalIds...)
+}
+
+// patchInterface returns the Interface row of the port, the patch to peer.
+func patchInterface(port, peer PatchPort) map[string]any {
+	iface := map[string]any{
+		"name":         port.Name,
+		"type":         "patch",
+		"options":      ovsdbMap(map[string]string{"peer": peer.Name}),
+		"external_ids": ovsdbMap(port.ExternalIds),
+	}
+	if port.Ofport != NO_DEFAULT_ID {
+		iface["ofport_request"] = port.Ofport
+	}
+	return iface
+}
@@ -0,0 +1,79 @@
+package ovs
+
+import (
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+	"github.com/Networks-it-uc3m/l2sm-switch/pkg/linuxif"
+)
+
+// portColumns are the Interface columns the state of a port is read from.
+var portColumns = []string{"name", "ofport", "type", "admin_state", "link_state", "mac_in_use", "mtu", "mtu_request", "external_ids", "statistics"}
+
+// portFromRow builds the port from the row of its interface, merging the kernel state of the link when it is visible.
+func portFromRow(row ovsdbRow) plsv1.Port {
+	ofport, _ := row.integer("ofport")
+	mtu, _ := row.integer("mtu")
+	port := plsv1.Port{
+		Name: row.str("name"),
+		Mtu:  requestedMtu(row),
+		State: &plsv1.PortState{
+			Ofport:      ofport,
+			Type:        row.str("type"),
+			AdminState:  row.str("admin_state"),
+			LinkState:   row.str("link_state"),
+			Mac:         row.str("mac_in_use"),
+			Mtu:         mtu,
+			ExternalIds: row.strMap("external_ids"),
+			Statistics:  row.intMap("statistics"),
+		},
+	}
+	// interfaces moved to other namespaces, like the ones of pods, are not visible and keep an empty kernel state
+	if link, err := linuxif.GetLinkState(port.Name); err == nil {
+		port.State.OperState = link.OperState
+		port.State.Carrier = link.Carrier
+		port.State.PeerIfindex = link.PeerIfindex
+	}
+	return port
+}
+
+// portsFromRows builds the ports of the bridge from their Port rows, with the VLAN, bond, QoS and external_ids columns, and
+// the Interface, QoS and Queue rows keyed by uuid. talpa ports have a single interface with the name of the port, bonds several.
+func portsFromRows(bridgeName string, rows []ovsdbRow, ifaces, qos, queues map[string]ovsdbRow) map[string]plsv1.Port {
+	portMap := make(map[string]plsv1.Port)
+	for _, row := range rows {
+		portName := row.str("name")
+		// like list-ports, leave out the bridge local port
+		if portName == bridgeName {
+			continue
+		}
+		port := plsv1.Port{Name: portName}
+		members := []ovsdbRow{}
+		bond := false
+		for _, id := range row.uuids("interfaces") {
+			iface, ok := ifaces[id]
+			if !ok {
+				continue
+			}
+			if iface.str("name") == portName {
+				port = portFromRow(iface)
+				var qosRow ovsdbRow
+				if ids := row.uuids("qos"); len(ids) > 0 {
+					qosRow = qos[ids[0]]
+				}
+				port.Qos = qosFromRows(iface, qosRow, queues)
+			} else {
+				bond = true
+			}
+			members = append(members, iface)
+		}
+		// a bond left with a single member is still told apart by the name of its interface
+		if bond {
+			port.Bond = bondFromRow(row, members)
+		}
+		if externalIds := row.strMap("external_ids"); isManaged(externalIds) {
+			port.Role = externalIds[ROLE_EXTERNAL_ID]
+		}
+		setVlanFromRow(&port, row)
+		portMap[portName] = port
+	}
+	return portMap
+}
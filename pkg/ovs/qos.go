@@ -0,0 +1,167 @@
+package ovs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// QOS_EXTERNAL_ID is the external_ids key of the interface where the fingerprint of the traffic controls
+// talpa applied to it is kept, so they are only replaced when they change.
+const QOS_EXTERNAL_ID = "talpa-qos"
+
+// qosFingerprint returns a short digest of the traffic controls, empty if there are none.
+func qosFingerprint(qos *plsv1.PortQos) string {
+	if qos == nil {
+		return ""
+	}
+	normalized := *qos
+	normalized.Type = qosType(qos)
+	normalized.Queues = sortedQueues(qos)
+	b, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ingressPolicing returns the ingress_policing_rate and ingress_policing_burst of the port interface,
+// both 0, which disables policing, when the port has no traffic controls.
+func ingressPolicing(qos *plsv1.PortQos) (int64, int64) {
+	if qos == nil {
+		return 0, 0
+	}
+	return qos.IngressPolicingRate, qos.IngressPolicingBurst
+}
+
+// hasEgressQos reports whether the port needs a QoS row, which is only the case if it limits its egress rate or has queues.
+func hasEgressQos(qos *plsv1.PortQos) bool {
+	return qos != nil && (qos.MaxRate > 0 || len(qos.Queues) > 0)
+}
+
+// qosType returns the type of the QoS row of the port, linux-htb if it is not set.
+func qosType(qos *plsv1.PortQos) string {
+	if qos.Type == "" {
+		return plsv1.QOS_LINUX_HTB
+	}
+	return qos.Type
+}
+
+// qosOtherConfig returns the other_config column of the QoS row of the port.
+func qosOtherConfig(qos *plsv1.PortQos) map[string]string {
+	config := map[string]string{}
+	if qos.MaxRate > 0 {
+		config["max-rate"] = strconv.FormatInt(qos.MaxRate, 10)
+	}
+	return config
+}
+
+// queueOtherConfig returns the other_config column of the Queue row. Unset values are left to the OVS defaults.
+func queueOtherConfig(queue plsv1.Queue) map[string]string {
+	config := map[string]string{}
+	if queue.MinRate > 0 {
+		config["min-rate"] = strconv.FormatInt(queue.MinRate, 10)
+	}
+	if queue.MaxRate > 0 {
+		config["max-rate"] = strconv.FormatInt(queue.MaxRate, 10)
+	}
+	if queue.Burst > 0 {
+		config["burst"] = strconv.FormatInt(queue.Burst, 10)
+	}
+	if queue.Priority > 0 {
+		config["priority"] = strconv.FormatInt(queue.Priority, 10)
+	}
+	return config
+}
+
+// sortedQueues returns the queues of the port ordered by id.
+func sortedQueues(qos *plsv1.PortQos) []plsv1.Queue {
+	queues := append([]plsv1.Queue{}, qos.Queues...)
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Id < queues[j].Id })
+	return queues
+}
+
+// previousQos returns the uuids of the QoS rows of the given ports and of their queues, which are
+// root rows that outlive the ports referencing them and have to be destroyed when they are replaced.
+func previousQos(ports []ovsdbRow, qosRows []ovsdbRow, portNames []string) ([]string, []string) {
+	wanted := make(map[string]bool)
+	for _, portName := range portNames {
+		wanted[portName] = true
+	}
+	qosIds := make(map[string]bool)
+	for _, port := range ports {
+		if !wanted[port.str("name")] {
+			continue
+		}
+		for _, id := range port.uuids("qos") {
+			qosIds[id] = true
+		}
+	}
+
+	qos, queues := []string{}, []string{}
+	for _, row := range qosRows {
+		if !qosIds[row.uuid()] {
+			continue
+		}
+		qos = append(qos, row.uuid())
+		for _, id := range row.strMap("queues") {
+			queues = append(queues, id)
+		}
+	}
+	sort.Strings(qos)
+	sort.Strings(queues)
+	return qos, queues
+}
+
+// qosInterfaceColumns are the Interface columns of the ingress policing of a port, qosColumns and queueColumns the
+// QoS and Queue columns of its egress traffic controls.
+var qosInterfaceColumns = []string{"ingress_policing_rate", "ingress_policing_burst"}
+var qosColumns = []string{"_uuid", "type", "other_config", "queues"}
+var queueColumns = []string{"_uuid", "other_config"}
+
+// portQosChanged reports whether the traffic controls requested for the port are not the ones talpa last applied to
+// current, which is also the case when the port had some and none are requested anymore.
+func portQosChanged(current, port plsv1.Port) bool {
+	applied := ""
+	if current.State != nil {
+		applied = current.State.ExternalIds[QOS_EXTERNAL_ID]
+	}
+	return applied != qosFingerprint(port.Qos)
+}
+
+// qosFromRows builds the traffic controls of a port from its interface, the QoS row the port references, nil if
+// none, and the Queue rows keyed by uuid. It returns nil if the port has none.
+func qosFromRows(iface ovsdbRow, qosRow ovsdbRow, queues map[string]ovsdbRow) *plsv1.PortQos {
+	qos := &plsv1.PortQos{}
+	qos.IngressPolicingRate, _ = iface.integer("ingress_policing_rate")
+	qos.IngressPolicingBurst, _ = iface.integer("ingress_policing_burst")
+	if qosRow == nil {
+		if qos.IngressPolicingRate == 0 && qos.IngressPolicingBurst == 0 {
+			return nil
+		}
+		return qos
+	}
+	qos.Type = qosRow.str("type")
+	qos.MaxRate, _ = strconv.ParseInt(qosRow.strMap("other_config")["max-rate"], 10, 64)
+	for id, queueId := range qosRow.strMap("queues") {
+		queueRow, ok := queues[queueId]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			continue
+		}
+		config := queueRow.strMap("other_config")
+		queue := plsv1.Queue{Id: uint32(n)}
+		queue.MinRate, _ = strconv.ParseInt(config["min-rate"], 10, 64)
+		queue.MaxRate, _ = strconv.ParseInt(config["max-rate"], 10, 64)
+		queue.Burst, _ = strconv.ParseInt(config["burst"], 10, 64)
+		queue.Priority, _ = strconv.ParseInt(config["priority"], 10, 64)
+		qos.Queues = append(qos.Queues, queue)
+	}
+	qos.Queues = sortedQueues(qos)
+	return qos
+}
@@ -0,0 +1,149 @@
+package ovs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// spanningTreeKeys are the other_config keys of the bridge priority and of the port path cost, per protocol.
+var spanningTreeKeys = map[string]struct{ priority, pathCost string }{
+	plsv1.STP_PROTOCOL_STP:  {priority: "stp-priority", pathCost: "stp-path-cost"},
+	plsv1.STP_PROTOCOL_RSTP: {priority: "rstp-priority", pathCost: "rstp-path-cost"},
+}
+
+// Largest path costs of each protocol: STP ones are 16 bits wide, RSTP ones 32 bits but limited by IEEE 802.1D to 200000000.
+const (
+	MAX_STP_PATH_COST  = 65535
+	MAX_RSTP_PATH_COST = 200000000
+)
+
+// spanningTreeBridgeColumns and spanningTreePortColumns are the columns the spanning tree status is read from.
+var spanningTreeBridgeColumns = []string{"stp_enable", "rstp_enable", "status", "rstp_status"}
+var spanningTreePortColumns = []string{"name", "status", "rstp_status", "other_config"}
+
+// ValidatePathCost checks that the path cost is in the range of the protocol. 0 keeps the OVS default and is always valid.
+func ValidatePathCost(protocol string, cost int) error {
+	maxCost := MAX_RSTP_PATH_COST
+	if protocol == plsv1.STP_PROTOCOL_STP {
+		maxCost = MAX_STP_PATH_COST
+	}
+	if cost < 0 || cost > maxCost {
+		return fmt.Errorf("path cost %d is out of range [0, %d], 0 keeping the default", cost, maxCost)
+	}
+	return nil
+}
+
+// validateSpanningTree checks that the protocol is supported and its priority and path cost in range.
+func validateSpanningTree(st plsv1.SpanningTree) error {
+	if st.Protocol == "" {
+		return nil
+	}
+	if !plsv1.IsSpanningTreeProtocol(st.Protocol) {
+		return fmt.Errorf("unsupported spanning tree protocol %s", st.Protocol)
+	}
+	if st.Priority != nil {
+		priority := *st.Priority
+		switch {
+		case priority < 0 || priority > 65535:
+			return fmt.Errorf("spanning tree priority %d is out of range [0, 65535]", priority)
+		case st.Protocol == plsv1.STP_PROTOCOL_RSTP && (priority > 61440 || priority%4096 != 0):
+			return fmt.Errorf("rstp priority %d must be a multiple of 4096 up to 61440", priority)
+		}
+	}
+	return ValidatePathCost(st.Protocol, st.PathCost)
+}
+
+// spanningTreeOtherConfig returns the other_config keys of the bridge for the spanning tree, empty if it keeps the defaults.
+func spanningTreeOtherConfig(st plsv1.SpanningTree) map[string]string {
+	otherConfig := map[string]string{}
+	if keys, ok := spanningTreeKeys[st.Protocol]; ok && st.Priority != nil {
+		otherConfig[keys.priority] = strconv.Itoa(*st.Priority)
+	}
+	return otherConfig
+}
+
+// setSpanningTreeArgs returns the commands that enable the protocol of the spanning tree, disabling the other one,
+// and replace the priority of the bridge.
+func setSpanningTreeArgs(bridgeName string, st plsv1.SpanningTree) [][]string {
+	set := []string{"set", "bridge", bridgeName,
+		fmt.Sprintf("stp_enable=%t", st.Protocol == plsv1.STP_PROTOCOL_STP),
+		fmt.Sprintf("rstp_enable=%t", st.Protocol == plsv1.STP_PROTOCOL_RSTP),
+	}
+	otherConfig := spanningTreeOtherConfig(st)
+	for _, k := range sortedKeys(otherConfig) {
+		set = append(set, fmt.Sprintf("other_config:%s=%s", k, otherConfig[k]))
+	}
+	return [][]string{
+		{"remove", "bridge", bridgeName, "other_config", "stp-priority", "rstp-priority"},
+		set,
+	}
+}
+
+// pathCostOtherConfig returns the other_config keys of the port path cost, set for both protocols so it
+// outlives a change of protocol. An RSTP cost beyond the range of STP is capped to the largest STP one.
+func pathCostOtherConfig(cost int) map[string]string {
+	return map[string]string{
+		spanningTreeKeys[plsv1.STP_PROTOCOL_STP].pathCost:  strconv.Itoa(min(cost, MAX_STP_PATH_COST)),
+		spanningTreeKeys[plsv1.STP_PROTOCOL_RSTP].pathCost: strconv.Itoa(cost),
+	}
+}
+
+func setPathCostArgs(portName string, cost int) []string {
+	args := []string{"set", "port", portName}
+	otherConfig := pathCostOtherConfig(cost)
+	for _, k := range sortedKeys(otherConfig) {
+		args = append(args, fmt.Sprintf("other_config:%s=%s", k, otherConfig[k]))
+	}
+	return args
+}
+
+// spanningTreeStatus builds the status of the spanning tree from the row of the bridge and the rows of its ports.
+func spanningTreeStatus(bridge ovsdbRow, ports []ovsdbRow) plsv1.SpanningTreeStatus {
+	enabled := func(column string) bool {
+		e := bridge.elems(column)
+		return len(e) > 0 && e[0] == true
+	}
+	st := plsv1.SpanningTreeStatus{Ports: []plsv1.SpanningTreePortStatus{}}
+	var status map[string]string
+	switch {
+	case enabled("rstp_enable"):
+		st.Protocol = plsv1.STP_PROTOCOL_RSTP
+		status = bridge.strMap("rstp_status")
+		st.BridgeId, st.RootId = status["rstp_bridge_id"], status["rstp_root_id"]
+		st.RootPathCost, _ = strconv.ParseInt(status["rstp_root_path_cost"], 10, 64)
+	case enabled("stp_enable"):
+		st.Protocol = plsv1.STP_PROTOCOL_STP
+		status = bridge.strMap("status")
+		st.BridgeId, st.RootId = status["stp_bridge_id"], status["stp_designated_root"]
+		st.RootPathCost, _ = strconv.ParseInt(status["stp_root_path_cost"], 10, 64)
+	default:
+		return st
+	}
+	st.IsRoot = st.BridgeId != "" && st.BridgeId == st.RootId
+
+	keys := spanningTreeKeys[st.Protocol]
+	for _, row := range ports {
+		port := plsv1.SpanningTreePortStatus{Name: row.str("name")}
+		if st.Protocol == plsv1.STP_PROTOCOL_RSTP {
+			status = row.strMap("rstp_status")
+			port.Role, port.State = status["rstp_port_role"], status["rstp_port_state"]
+		} else {
+			status = row.strMap("status")
+			port.Role, port.State = status["stp_role"], status["stp_state"]
+		}
+		// the bridge interface and the ports ovs-vswitchd has not brought into the tree yet have no state
+		if port.State == "" {
+			continue
+		}
+		port.Role, port.State = strings.ToLower(port.Role), strings.ToLower(port.State)
+		port.Blocked = port.State == "blocking" || port.State == "discarding"
+		port.PathCost, _ = strconv.Atoi(row.strMap("other_config")[keys.pathCost])
+		st.Ports = append(st.Ports, port)
+	}
+	sort.Slice(st.Ports, func(i, j int) bool { return st.Ports[i].Name < st.Ports[j].Name })
+	return st
+}
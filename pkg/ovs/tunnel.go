@@ -0,0 +1,163 @@
+package ovs
+
+import (
+	"maps"
+	"sort"
+	"strconv"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// tunnelOptionOrder is the order in which the well known tunnel options are set, before any other.
+var tunnelOptionOrder = []string{"key", "remote_ip", "local_ip", "dst_port", "tos", "ttl", "df_default", "csum", "egress_pkt_mark"}
+
+// tunnelColumns are the Interface columns a tunnel and its status are read from.
+var tunnelColumns = []string{"name", "type", "options", "ofport", "admin_state", "link_state", "error", "bfd_status", "mtu_request", "external_ids", "statistics"}
+
+// tunnelFromRow rebuilds the tunnel and its status from its Interface row, or returns false if it is not a tunnel.
+func tunnelFromRow(row ovsdbRow) (plsv1.Vxlan, bool) {
+	vxlan, ok := tunnelFromInterface(row.str("name"), row.str("type"), row.strMap("options"))
+	if !ok {
+		return vxlan, false
+	}
+	vxlan.Mtu = requestedMtu(row)
+	ofport, _ := row.integer("ofport")
+	vxlan.Status = &plsv1.TunnelStatus{
+		Ofport:      ofport,
+		AdminState:  row.str("admin_state"),
+		LinkState:   row.str("link_state"),
+		Error:       row.str("error"),
+		BfdStatus:   row.strMap("bfd_status"),
+		ExternalIds: row.strMap("external_ids"),
+		Statistics:  row.intMap("statistics"),
+	}
+	if isManaged(vxlan.Status.ExternalIds) {
+		vxlan.Neighbor = vxlan.Status.ExternalIds[NEIGHBOR_EXTERNAL_ID]
+	}
+	return vxlan, true
+}
+
+// tunnelType returns the interface type of the tunnel, vxlan if it is not set.
+func tunnelType(vxlan plsv1.Vxlan) string {
+	if vxlan.Type == "" {
+		return plsv1.TUNNEL_VXLAN
+	}
+	return vxlan.Type
+}
+
+// tunnelOptions returns the options column of the tunnel interface. Unless the tunnel has a fixed key,
+// the tunnel id is left to the controller through key=flow. Empty fields are not set at all.
+func tunnelOptions(vxlan plsv1.Vxlan) map[string]string {
+	options := map[string]string{}
+	for k, v := range vxlan.Options {
+		options[k] = v
+	}
+	options["key"] = "flow"
+	if vxlan.Key != "" {
+		options["key"] = vxlan.Key
+	}
+	options["remote_ip"] = vxlan.RemoteIp
+	if vxlan.LocalIp != "" {
+		options["local_ip"] = vxlan.LocalIp
+	}
+	// GRE is not carried over UDP
+	if vxlan.UdpPort != "" && tunnelType(vxlan) != plsv1.TUNNEL_GRE {
+		options["dst_port"] = vxlan.UdpPort
+	}
+	if vxlan.Tos != "" {
+		options["tos"] = vxlan.Tos
+	}
+	if vxlan.Ttl != "" {
+		options["ttl"] = vxlan.Ttl
+	}
+	if vxlan.DfDefault != nil {
+		options["df_default"] = strconv.FormatBool(*vxlan.DfDefault)
+	}
+	if vxlan.Csum != nil {
+		options["csum"] = strconv.FormatBool(*vxlan.Csum)
+	}
+	if vxlan.EgressPktMark != "" {
+		options["egress_pkt_mark"] = vxlan.EgressPktMark
+	}
+	return options
+}
+
+// tunnelsEqual reports whether both tunnels result in the same interface configuration.
+func tunnelsEqual(a, b plsv1.Vxlan) bool {
+	return tunnelType(a) == tunnelType(b) && maps.Equal(tunnelOptions(a), tunnelOptions(b))
+}
+
+// tunnelQosChanged reports whether the traffic controls of the tunnel are not the ones talpa last applied to current.
+func tunnelQosChanged(current, vxlan plsv1.Vxlan) bool {
+	applied := ""
+	if current.Status != nil {
+		applied = current.Status.ExternalIds[QOS_EXTERNAL_ID]
+	}
+	return applied != qosFingerprint(vxlan.Qos)
+}
+
+// boolOption parses a boolean option, which is nil if it is not set or not a boolean.
+func boolOption(options map[string]string, key string) *bool {
+	b, err := strconv.ParseBool(options[key])
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+// tunnelOptionKeys returns the keys of the options in the order they are set.
+func tunnelOptionKeys(options map[string]string) []string {
+	keys := []string{}
+	known := map[string]bool{}
+	for _, k := range tunnelOptionOrder {
+		known[k] = true
+		if _, ok := options[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	rest := []string{}
+	for k := range options {
+		if !known[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// tunnelFromInterface rebuilds the tunnel from its interface, or returns false if the interface is not a tunnel.
+func tunnelFromInterface(name, ifaceType string, options map[string]string) (plsv1.Vxlan, bool) {
+	if !plsv1.IsTunnelType(ifaceType) {
+		return plsv1.Vxlan{}, false
+	}
+	vxlan := plsv1.Vxlan{
+		VxlanId:       name,
+		Type:          ifaceType,
+		LocalIp:       options["local_ip"],
+		RemoteIp:      options["remote_ip"],
+		UdpPort:       options["dst_port"],
+		Tos:           options["tos"],
+		Ttl:           options["ttl"],
+		DfDefault:     boolOption(options, "df_default"),
+		Csum:          boolOption(options, "csum"),
+		EgressPktMark: options["egress_pkt_mark"],
+	}
+	if key := options["key"]; key != "flow" {
+		vxlan.Key = key
+	}
+	for k, v := range options {
+		switch k {
+		case "key", "local_ip", "remote_ip", "dst_port", "tos", "ttl", "egress_pkt_mark":
+			continue
+		case "df_default", "csum":
+			if boolOption(options, k) != nil {
+				continue
+			}
+		}
+		if vxlan.Options == nil {
+			vxlan.Options = map[string]string{}
+		}
+		vxlan.Options[k] = v
+	}
+	return vxlan, true
+}
@@ -2,14 +2,29 @@ package ovs
 
 import (
 	"fmt"
+	"log"
+	"sort"
+	"time"
 
 	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
 )
 
 type VirtualSwitch struct {
-	bridge     plsv1.Bridge
-	ovsService OvsService
-	ipService  IpService
+	bridge        plsv1.Bridge
+	ovsService    Backend
+	ipService     IpService
+	tunnelChanges TunnelChanges
+}
+
+// TunnelChanges lists, by name, the tunnels an update of the switch created, modified in place and removed.
+type TunnelChanges struct {
+	Created  []string
+	Modified []string
+	Removed  []string
+}
+
+func (c TunnelChanges) String() string {
+	return fmt.Sprintf("created: %v, modified: %v, removed: %v", c.Created, c.Modified, c.Removed)
 }
 
 func (vs *VirtualSwitch) GetNewPortId() (int, error) {
@@ -28,17 +43,16 @@ func GetVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 
 	}
 
-	ovsService := NewOvsService()
+	ovsService := newBackend(bridgeConf)
 
-	if bridgeConf.setFields[FieldSudo] {
-		ovsService = NewSudoOvsService()
-	}
 	ipService := NewIpService()
 
 	if bridgeConf.setFields[FieldSudo] {
 		ipService = NewSudoIpService()
 	}
-	vs := VirtualSwitch{ovsService: ovsService, ipService: ipService, bridge: plsv1.Bridge{Name: bridgeConf.bridge.Name}}
+	vs := VirtualSwitch{ovsService: ovsService, ipService: ipService,
+		bridge: plsv1.Bridge{Name: bridgeConf.bridge.Name, Owner: bridgeConf.bridge.Owner},
+	}
 
 	if !bridgeConf.setFields[FieldName] || bridgeConf.bridge.Name == "" {
 		return vs, fmt.Errorf("bridge name must be set using WithName")
@@ -51,6 +65,8 @@ func GetVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 
 	vs.getController()
 
+	vs.getFailMode()
+
 	vs.GetVxlans()
 
 	return vs, nil
@@ -72,7 +88,8 @@ func UpdateVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, err
 	}
 
 	// Attempt to retrieve the existing bridge
-	vs, err := GetVirtualSwitch(WithName(bridgeConf.bridge.Name), WithSudo(bridgeConf.setFields[FieldSudo]))
+	vs, err := GetVirtualSwitch(WithName(bridgeConf.bridge.Name), WithSudo(bridgeConf.setFields[FieldSudo]), WithOvsdb(bridgeConf.ovsdb), WithBackend(bridgeConf.backend),
+		WithOwner(bridgeConf.bridge.Owner))
 	if err != nil {
 		// Bridge does not exist, fallback to creation
 		fmt.Println("si que entra")
@@ -82,48 +99,131 @@ func UpdateVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, err
 	ovs := vs.ovsService
 	ip := vs.ipService
 	name := bridgeConf.bridge.Name
+	owner := bridgeConf.bridge.Owner
+
+	// Update only the explicitly provided fields. Every change goes into a single transaction, so the
+	// bridge either ends up with all of them or keeps its previous configuration.
+	txn := ovs.NewTxn(name)
 
-	// Update only the explicitly provided fields
+	if bridgeConf.setFields[FieldOwner] {
+		txn.SetExternalIds("Bridge", name, ownerExternalIds(name, owner, ""))
+	}
 
 	if bridgeConf.setFields[FieldController] {
-		if err := ovs.SetController(name, bridgeConf.bridge.Controller...); err != nil {
-			return vs, fmt.Errorf("failed to update controller: %v", err)
-		}
-		vs.bridge.Controller = bridgeConf.bridge.Controller
+		txn.SetController(bridgeConf.bridge.Controller...)
 	}
 
 	if bridgeConf.setFields[FieldProtocol] {
-		if err := ovs.SetProtocol(name, bridgeConf.bridge.Protocol); err != nil {
-			return vs, fmt.Errorf("failed to update protocol: %v", err)
-		}
-		vs.bridge.Protocol = bridgeConf.bridge.Protocol
+		txn.SetProtocol(bridgeConf.bridge.Protocol)
 	}
 
 	if bridgeConf.setFields[FieldDatapathId] {
-		if err := ovs.SetDatapathID(name, bridgeConf.bridge.DatapathId); err != nil {
-			return vs, fmt.Errorf("failed to update datapath ID: %v", err)
+		txn.SetDatapathID(bridgeConf.bridge.DatapathId)
+	}
+
+	if bridgeConf.setFields[FieldDatapathType] {
+		if err = validateDatapathType(bridgeConf.bridge.DatapathType); err != nil {
+			return vs, err
 		}
-		vs.bridge.DatapathId = bridgeConf.bridge.DatapathId
+		txn.SetDatapathType(bridgeConf.bridge.DatapathType)
+	}
+
+	if bridgeConf.setFields[FieldFailMode] {
+		if err = validateFailMode(bridgeConf.bridge.FailMode); err != nil {
+			return vs, err
+		}
+		txn.SetFailMode(bridgeConf.bridge.FailMode)
+	}
+
+	if bridgeConf.setFields[FieldFlowExport] {
+		if err = validateFlowExport(bridgeConf.bridge.FlowExport); err != nil {
+			return vs, err
+		}
+		txn.SetFlowExport(bridgeConf.bridge.FlowExport)
+	}
+
+	if bridgeConf.setFields[FieldSpanningTree] {
+		if err = validateSpanningTree(bridgeConf.bridge.SpanningTree); err != nil {
+			return vs, err
+		}
+		txn.SetSpanningTree(bridgeConf.bridge.SpanningTree)
 	}
 
+	newPorts := []plsv1.Port{}
+	// newMembers are the interfaces added to existing bonds, which are set up like the ones of new ports
+	newMembers := []string{}
+	tunnelChanges := TunnelChanges{}
 	if bridgeConf.setFields[FieldPorts] {
-		for id, port := range bridgeConf.bridge.Ports {
-			if _, exists := vs.bridge.Ports[id]; !exists {
-				i := NO_DEFAULT_ID
-				if port.Id != nil {
-					i = *port.Id
+		for _, id := range sortedKeys(bridgeConf.bridge.Ports) {
+			port := bridgeConf.bridge.Ports[id]
+			if err = validateVlan(port); err != nil {
+				return vs, err
+			}
+			if err = validateBond(port); err != nil {
+				return vs, err
+			}
+			current, exists := vs.bridge.Ports[id]
+			if !exists {
+				if port.Bond != nil {
+					txn.AddBond(port)
+				} else {
+					i := NO_DEFAULT_ID
+					if port.Id != nil {
+						i = *port.Id
+					}
+					txn.AddPort(port.Name, i, port.Internal)
 				}
-				if err = ovs.AddPort(name, port.Name, i, port.Internal); err != nil {
-					return vs, fmt.Errorf("failed to add port %s: %v", port.Name, err)
+				setLabels(txn, portLabels(name, owner, port))
+				if hasVlan(port) {
+					txn.SetPortVlan(port)
 				}
-				if err = ip.SetInterfaceUp(port.Name); err != nil {
-					return vs, fmt.Errorf("failed to set interface %s up: %v", port.Name, err)
+				if port.Mtu > 0 {
+					for _, iface := range portInterfaces(port) {
+						txn.SetMtu(iface, port.Mtu)
+					}
 				}
-				if port.Internal {
-					ip.AddIpAddress(port.Name, *port.IpAddress)
+				newPorts = append(newPorts, port)
+			} else {
+				// ports talpa created before they were labelled are adopted once their role is known
+				if port.Role != "" && port.Role != current.Role {
+					setLabels(txn, portLabels(name, owner, port))
 				}
-
-				vs.bridge.Ports[id] = bridgeConf.bridge.Ports[id]
+				// a port cannot be turned into a bond in place, as the interface with its name goes away
+				if (port.Bond == nil) != (current.Bond == nil) {
+					return vs, fmt.Errorf("port %s cannot change between a bond and a single interface port", port.Name)
+				}
+				// ports requested without any VLAN field, e.g. to change their MTU, keep the VLAN configuration they have
+				if hasVlan(port) && !vlanEqual(current, port) {
+					txn.SetPortVlan(port)
+				}
+				if port.Bond == nil {
+					if port.Mtu > 0 && port.Mtu != current.Mtu {
+						txn.SetMtu(port.Name, port.Mtu)
+					}
+				} else {
+					if !bondSettingsEqual(current.Bond, port.Bond) {
+						txn.SetBond(port)
+					}
+					added, removed := bondMembersDiff(current.Bond, port.Bond)
+					for _, member := range added {
+						txn.AddBondMember(port.Name, member)
+					}
+					for _, member := range removed {
+						txn.RemoveBondMember(port.Name, member)
+					}
+					newMembers = append(newMembers, added...)
+					// the MTU of a bond is requested on its members and not read back, so it is set every time
+					if port.Mtu > 0 {
+						for _, member := range portInterfaces(port) {
+							txn.SetMtu(member, port.Mtu)
+						}
+					}
+				}
+			}
+			// the traffic controls of existing ports are only replaced when they are not the ones talpa last applied,
+			// which also clears the ones of a port that has none anymore
+			if (!exists && port.Qos != nil) || (exists && portQosChanged(current, port)) {
+				txn.SetPortQos(port.Name, port.Qos)
 			}
 		}
 	}
@@ -135,22 +235,118 @@ func UpdateVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, err
 		}
 		requiredVxlans := bridgeConf.bridge.Vxlans
 
-		for vxID, vx := range requiredVxlans {
-			if _, ok := vxs[vxID]; !ok {
-				if err = ovs.CreateVxlan(name, vx); err != nil {
-					return vs, fmt.Errorf("failed to create vxlan %s: %v", vxID, err)
+		// tunnels are named after their endpoints, so one whose options drifted keeps its name and is fixed in place
+		for _, vxID := range sortedKeys(requiredVxlans) {
+			vx := requiredVxlans[vxID]
+			current, ok := vxs[vxID]
+			delete(vxs, vxID)
+			if !ok {
+				txn.CreateVxlan(vx)
+				setLabels(txn, tunnelLabels(name, owner, vx))
+				if vx.Qos != nil {
+					txn.SetPortQos(vxID, vx.Qos)
 				}
+				if vx.Mtu > 0 {
+					txn.SetMtu(vxID, vx.Mtu)
+				}
+				if vx.PathCost > 0 {
+					txn.SetPathCost(vxID, vx.PathCost)
+				}
+				tunnelChanges.Created = append(tunnelChanges.Created, vxID)
+				continue
+			}
+			if !managedTunnel(current) || current.Neighbor != vx.Neighbor {
+				setLabels(txn, tunnelLabels(name, owner, vx))
+			}
+			modified := false
+			if !tunnelsEqual(current, vx) {
+				txn.ModifyVxlan(vx)
+				modified = true
+			}
+			if tunnelQosChanged(current, vx) {
+				txn.SetPortQos(vxID, vx.Qos)
+				modified = true
+			}
+			if vx.Mtu > 0 && vx.Mtu != current.Mtu {
+				txn.SetMtu(vxID, vx.Mtu)
+				modified = true
+			}
+			// the path cost is in the port, not in the interface the tunnel is read from, so it is set every time
+			if vx.PathCost > 0 {
+				txn.SetPathCost(vxID, vx.PathCost)
+			}
+			if modified {
+				tunnelChanges.Modified = append(tunnelChanges.Modified, vxID)
+			}
+		}
+		// tunnels of other tools are left alone
+		for _, vxID := range sortedKeys(vxs) {
+			switch {
+			case managedTunnel(vxs[vxID]):
+			case legacyTunnel(vxs[vxID]):
+				log.Printf("removing unlabelled tunnel %s of %s, named as a talpa tunnel", vxID, name)
+			default:
+				continue
+			}
+			// the QoS and queues of the tunnel are not garbage collected with it
+			txn.SetPortQos(vxID, nil)
+			txn.DeleteVxlan(vxID)
+			tunnelChanges.Removed = append(tunnelChanges.Removed, vxID)
+		}
+	}
 
-			} else {
-				delete(vxs, vxID)
+	if err = txn.Commit(); err != nil {
+		return vs, fmt.Errorf("failed to update bridge %s: %v", name, err)
+	}
+
+	if bridgeConf.setFields[FieldController] {
+		vs.bridge.Controller = bridgeConf.bridge.Controller
+	}
+	if bridgeConf.setFields[FieldProtocol] {
+		vs.bridge.Protocol = bridgeConf.bridge.Protocol
+	}
+	if bridgeConf.setFields[FieldDatapathId] {
+		vs.bridge.DatapathId = bridgeConf.bridge.DatapathId
+	}
+	if bridgeConf.setFields[FieldDatapathType] {
+		vs.bridge.DatapathType = bridgeConf.bridge.DatapathType
+	}
+	if bridgeConf.setFields[FieldFailMode] {
+		vs.bridge.FailMode = bridgeConf.bridge.FailMode
+	}
+	if bridgeConf.setFields[FieldFlowExport] {
+		vs.bridge.FlowExport = bridgeConf.bridge.FlowExport
+	}
+	if bridgeConf.setFields[FieldSpanningTree] {
+		vs.bridge.SpanningTree = bridgeConf.bridge.SpanningTree
+	}
+	if bridgeConf.setFields[FieldVxlans] {
+		vs.bridge.Vxlans = bridgeConf.bridge.Vxlans
+	}
+	vs.tunnelChanges = tunnelChanges
+	if vs.bridge.Ports == nil {
+		vs.bridge.Ports = make(map[string]plsv1.Port)
+	}
+
+	// interfaces of internal ports only exist once the transaction is applied, so links are configured afterwards
+	for _, member := range newMembers {
+		if err = ip.SetInterfaceUp(member); err != nil {
+			return vs, fmt.Errorf("failed to set interface %s up: %v", member, err)
+		}
+	}
+	for _, port := range newPorts {
+		for _, iface := range portInterfaces(port) {
+			if err = ip.SetInterfaceUp(iface); err != nil {
+				return vs, fmt.Errorf("failed to set interface %s up: %v", iface, err)
 			}
 		}
-		for vxID := range vxs {
-			if err = ovs.DeleteVxlan(name, vxID); err != nil {
-				return vs, fmt.Errorf("failed to delete vxlan %s: %v", vxID, err)
+		if port.Internal && port.IpAddress != nil {
+			if err = ip.AddIpAddress(port.Name, *port.IpAddress); err != nil {
+				return vs, fmt.Errorf("failed to add ip address to %s: %v", port.Name, err)
 			}
 		}
 
+		vs.bridge.Ports[port.Name] = port
 	}
 
 	return vs, nil
@@ -167,11 +363,8 @@ func NewVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 		opt(bridgeConf)
 	}
 
-	ovsService := NewOvsService()
+	ovsService := newBackend(bridgeConf)
 
-	if bridgeConf.setFields[FieldSudo] {
-		ovsService = NewSudoOvsService()
-	}
 	ipService := NewIpService()
 
 	if bridgeConf.setFields[FieldSudo] {
@@ -185,6 +378,37 @@ func NewVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 	if !bridgeConf.setFields[FieldName] || bridgeConf.bridge.Name == "" {
 		return vs, fmt.Errorf("bridge name must be set using WithName")
 	}
+	if bridgeConf.setFields[FieldFlowExport] {
+		if err = validateFlowExport(bridgeConf.bridge.FlowExport); err != nil {
+			return vs, err
+		}
+	}
+	if bridgeConf.setFields[FieldDatapathType] {
+		if err = validateDatapathType(bridgeConf.bridge.DatapathType); err != nil {
+			return vs, err
+		}
+	}
+	if bridgeConf.setFields[FieldFailMode] {
+		if err = validateFailMode(bridgeConf.bridge.FailMode); err != nil {
+			return vs, err
+		}
+	}
+	if bridgeConf.setFields[FieldSpanningTree] {
+		if err = validateSpanningTree(bridgeConf.bridge.SpanningTree); err != nil {
+			return vs, err
+		}
+	}
+	// the existing bridge is only deleted once the new one is known to be valid
+	if bridgeConf.setFields[FieldPorts] {
+		for _, port := range bridgeConf.bridge.Ports {
+			if err = validateVlan(port); err != nil {
+				return vs, err
+			}
+			if err = validateBond(port); err != nil {
+				return vs, err
+			}
+		}
+	}
 
 	// If bridge exists, delete it
 	if vs.exists() {
@@ -202,6 +426,19 @@ func NewVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 	if err != nil {
 		return vs, fmt.Errorf("could not create bridge %s: %v", vs.bridge.Name, err)
 	}
+	vs.bridge.Owner = bridgeConf.bridge.Owner
+	if err = vs.label([]rowLabels{{table: "Bridge", record: name, externalIds: ownerExternalIds(name, vs.bridge.Owner, "")}}); err != nil {
+		return vs, fmt.Errorf("could not label bridge %s: %v", name, err)
+	}
+
+	// the datapath goes before anything else, as ovs-vswitchd recreates the bridge interface when it changes
+	if bridgeConf.setFields[FieldDatapathType] {
+		err = ovs.SetDatapathType(vs.bridge.Name, bridgeConf.bridge.DatapathType)
+		if err != nil {
+			return vs, fmt.Errorf("could not set datapath type: %v", err)
+		}
+		vs.bridge.DatapathType = bridgeConf.bridge.DatapathType
+	}
 
 	// Bring interface up
 	err = vs.ipService.SetInterfaceUp(vs.bridge.Name)
@@ -226,6 +463,15 @@ func NewVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 		vs.bridge.Protocol = bridgeConf.bridge.Protocol
 	}
 
+	// the fail mode goes before the controller, so the bridge never runs with a controller in the wrong mode
+	if bridgeConf.setFields[FieldFailMode] {
+		err = ovs.SetFailMode(vs.bridge.Name, bridgeConf.bridge.FailMode)
+		if err != nil {
+			return vs, fmt.Errorf("could not set fail mode: %v", err)
+		}
+		vs.bridge.FailMode = bridgeConf.bridge.FailMode
+	}
+
 	if bridgeConf.setFields[FieldController] {
 		err = ovs.SetController(vs.bridge.Name, bridgeConf.bridge.Controller...)
 		if err != nil {
@@ -234,19 +480,62 @@ func NewVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 		vs.bridge.Controller = bridgeConf.bridge.Controller
 	}
 
+	if bridgeConf.setFields[FieldFlowExport] {
+		err = ovs.SetFlowExport(vs.bridge.Name, bridgeConf.bridge.FlowExport)
+		if err != nil {
+			return vs, fmt.Errorf("could not set flow export: %v", err)
+		}
+		vs.bridge.FlowExport = bridgeConf.bridge.FlowExport
+	}
+
+	if bridgeConf.setFields[FieldSpanningTree] {
+		err = ovs.SetSpanningTree(vs.bridge.Name, bridgeConf.bridge.SpanningTree)
+		if err != nil {
+			return vs, fmt.Errorf("could not set spanning tree: %v", err)
+		}
+		vs.bridge.SpanningTree = bridgeConf.bridge.SpanningTree
+	}
+
 	// TODO: interfaces exist in the void? Create new ones? Specific for NED
 	if bridgeConf.setFields[FieldPorts] {
 		for _, port := range bridgeConf.bridge.Ports {
 			i := NO_DEFAULT_ID
-			if err = ip.SetInterfaceUp(port.Name); err != nil {
-				return vs, fmt.Errorf("failed to add port %s: %v", port.Name, err)
+			for _, iface := range portInterfaces(port) {
+				if err = ip.SetInterfaceUp(iface); err != nil {
+					return vs, fmt.Errorf("failed to add port %s: %v", port.Name, err)
+				}
 			}
 			if port.Id != nil {
 				i = *port.Id
 			}
-			if err = ovs.AddPort(name, port.Name, i, port.Internal); err != nil {
+			if port.Bond != nil {
+				err = ovs.AddBond(name, port)
+			} else {
+				err = ovs.AddPort(name, port.Name, i, port.Internal)
+			}
+			if err != nil {
 				return vs, fmt.Errorf("failed to add port %s: %v", port.Name, err)
 			}
+			if err = vs.label(portLabels(name, vs.bridge.Owner, port)); err != nil {
+				return vs, fmt.Errorf("failed to label port %s: %v", port.Name, err)
+			}
+			if hasVlan(port) {
+				if err = ovs.SetPortVlan(name, port); err != nil {
+					return vs, fmt.Errorf("failed to set vlan of port %s: %v", port.Name, err)
+				}
+			}
+			if port.Qos != nil {
+				if err = ovs.SetPortQos(name, port.Name, port.Qos); err != nil {
+					return vs, fmt.Errorf("failed to set qos of port %s: %v", port.Name, err)
+				}
+			}
+			if port.Mtu > 0 {
+				for _, iface := range portInterfaces(port) {
+					if err = ovs.SetMtu(iface, port.Mtu); err != nil {
+						return vs, fmt.Errorf("failed to set mtu of port %s: %v", port.Name, err)
+					}
+				}
+			}
 
 		}
 		vs.bridge.Ports = bridgeConf.bridge.Ports
@@ -258,13 +547,47 @@ func NewVirtualSwitch(bridgeOptions ...func(*BridgeConf)) (VirtualSwitch, error)
 			if err != nil {
 				return vs, fmt.Errorf("could not create vxlan %s: %v", vx.VxlanId, err)
 			}
+			if err = vs.label(tunnelLabels(name, vs.bridge.Owner, vx)); err != nil {
+				return vs, fmt.Errorf("could not label vxlan %s: %v", vx.VxlanId, err)
+			}
+			if vx.Qos != nil {
+				if err = ovs.SetPortQos(name, vx.VxlanId, vx.Qos); err != nil {
+					return vs, fmt.Errorf("could not set qos of vxlan %s: %v", vx.VxlanId, err)
+				}
+			}
+			if vx.Mtu > 0 {
+				if err = ovs.SetMtu(vx.VxlanId, vx.Mtu); err != nil {
+					return vs, fmt.Errorf("could not set mtu of vxlan %s: %v", vx.VxlanId, err)
+				}
+			}
+			if vx.PathCost > 0 {
+				if err = ovs.SetPathCost(vx.VxlanId, vx.PathCost); err != nil {
+					return vs, fmt.Errorf("could not set path cost of vxlan %s: %v", vx.VxlanId, err)
+				}
+			}
 		}
 		vs.bridge.Vxlans = bridgeConf.bridge.Vxlans
+		vs.tunnelChanges.Created = sortedKeys(bridgeConf.bridge.Vxlans)
 	}
 
 	return vs, nil
 }
 
+// TunnelChanges returns the tunnels changed by the update or creation that returned the switch.
+func (vs *VirtualSwitch) TunnelChanges() TunnelChanges {
+	return vs.tunnelChanges
+}
+
+// label sets the external ids of the rows talpa created.
+func (vs *VirtualSwitch) label(labels []rowLabels) error {
+	for _, l := range labels {
+		if err := vs.ovsService.SetExternalIds(l.table, l.record, l.externalIds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (vs *VirtualSwitch) createVxlan(vxlan plsv1.Vxlan) error {
 
 	err := vs.ovsService.CreateVxlan(vs.bridge.Name, vxlan)
@@ -301,6 +624,30 @@ func (vs *VirtualSwitch) getPorts() error {
 	return err
 }
 
+// SetPortQos replaces the traffic controls of the port. A nil qos removes them.
+func (vs *VirtualSwitch) SetPortQos(portName string, qos *plsv1.PortQos) error {
+	if err := vs.ovsService.SetPortQos(vs.bridge.Name, portName, qos); err != nil {
+		return fmt.Errorf("could not set qos of port %s: %v", portName, err)
+	}
+	if port, ok := vs.bridge.Ports[portName]; ok {
+		port.Qos = qos
+		vs.bridge.Ports[portName] = port
+	}
+	return nil
+}
+
+// GetPorts returns the ports of the switch, with their state, sorted by name.
+func (vs *VirtualSwitch) GetPorts() ([]plsv1.Port, error) {
+	if err := vs.getPorts(); err != nil {
+		return []plsv1.Port{}, fmt.Errorf("could not get ports of %s: %v", vs.bridge.Name, err)
+	}
+	ports := []plsv1.Port{}
+	for _, portName := range sortedKeys(vs.bridge.Ports) {
+		ports = append(ports, vs.bridge.Ports[portName])
+	}
+	return ports, nil
+}
+
 func (vs *VirtualSwitch) getController() error {
 	var err error
 
@@ -309,10 +656,180 @@ func (vs *VirtualSwitch) getController() error {
 	return err
 }
 
-// TODO
+func (vs *VirtualSwitch) getFailMode() error {
+	var err error
+
+	vs.bridge.FailMode, err = vs.ovsService.GetFailMode(vs.bridge.Name)
+
+	return err
+}
+
+func (vs *VirtualSwitch) GetName() string {
+	return vs.bridge.Name
+}
+
+func (vs *VirtualSwitch) GetFailMode() string {
+	return vs.bridge.FailMode
+}
+
+func (vs *VirtualSwitch) SetFailMode(failMode string) error {
+	if err := validateFailMode(failMode); err != nil {
+		return err
+	}
+	if err := vs.ovsService.SetFailMode(vs.bridge.Name, failMode); err != nil {
+		return fmt.Errorf("could not set fail mode of %s: %v", vs.bridge.Name, err)
+	}
+	vs.bridge.FailMode = failMode
+	return nil
+}
+
+// GetSpanningTreeStatus returns the state of the spanning tree of the switch: the root bridge and the role and
+// state of every port, the blocked ones included. The status has no protocol if the spanning tree is disabled.
+func (vs *VirtualSwitch) GetSpanningTreeStatus() (plsv1.SpanningTreeStatus, error) {
+	return vs.ovsService.GetSpanningTreeStatus(vs.bridge.Name)
+}
+
+// GetControllerStatus returns the connection status of every controller of the switch, sorted by target whatever
+// order the backend lists them in.
+func (vs *VirtualSwitch) GetControllerStatus() ([]ControllerStatus, error) {
+	statuses, err := vs.ovsService.GetControllerStatus(vs.bridge.Name)
+	if err != nil {
+		return statuses, err
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Target < statuses[j].Target })
+	return statuses, nil
+}
+
+// ControllerConnected reports whether the switch is connected to any of its controllers.
+func (vs *VirtualSwitch) ControllerConnected() (bool, error) {
+	statuses, err := vs.GetControllerStatus()
+	if err != nil {
+		return false, err
+	}
+	for _, status := range statuses {
+		if status.IsConnected {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetVxlans returns the tunnels of the switch, with their status, sorted by name.
 func (vs *VirtualSwitch) GetVxlans() ([]plsv1.Vxlan, error) {
+	vxlans, err := vs.ovsService.GetVxlans(vs.bridge.Name)
+	if err != nil {
+		return []plsv1.Vxlan{}, fmt.Errorf("could not get vxlans of %s: %v", vs.bridge.Name, err)
+	}
+	vs.bridge.Vxlans = vxlans
+
+	vxs := []plsv1.Vxlan{}
+	for _, vxID := range sortedKeys(vxlans) {
+		vxs = append(vxs, vxlans[vxID])
+	}
+	return vxs, nil
+}
+
+// CreateMirror copies the traffic the mirror selects to its output port, replacing the mirror with the same name if there is one.
+func (vs *VirtualSwitch) CreateMirror(mirror plsv1.Mirror) error {
+	if err := validateMirror(mirror); err != nil {
+		return err
+	}
+	if err := vs.ovsService.CreateMirror(vs.bridge.Name, mirror); err != nil {
+		return fmt.Errorf("could not create mirror %s: %w", mirror.Name, err)
+	}
+	return nil
+}
+
+func (vs *VirtualSwitch) DeleteMirror(mirrorName string) error {
+	if err := vs.ovsService.DeleteMirror(vs.bridge.Name, mirrorName); err != nil {
+		return fmt.Errorf("could not delete mirror %s: %w", mirrorName, err)
+	}
+	return nil
+}
 
-	return []plsv1.Vxlan{}, nil
+// GetMirrors returns the mirrors of the switch sorted by name.
+func (vs *VirtualSwitch) GetMirrors() ([]plsv1.Mirror, error) {
+	mirrors, err := vs.ovsService.GetMirrors(vs.bridge.Name)
+	if err != nil {
+		return []plsv1.Mirror{}, fmt.Errorf("could not get mirrors of %s: %v", vs.bridge.Name, err)
+	}
+	ms := []plsv1.Mirror{}
+	for _, mirrorName := range sortedKeys(mirrors) {
+		ms = append(ms, mirrors[mirrorName])
+	}
+	return ms, nil
+}
+
+// DeleteExpiredMirrors removes the mirrors that expired by now and returns their names.
+func (vs *VirtualSwitch) DeleteExpiredMirrors(now time.Time) ([]string, error) {
+	mirrors, err := vs.GetMirrors()
+	if err != nil {
+		return nil, err
+	}
+	deleted := []string{}
+	for _, mirror := range mirrors {
+		if !mirrorExpired(mirror, now) {
+			continue
+		}
+		if err := vs.DeleteMirror(mirror.Name); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, mirror.Name)
+	}
+	return deleted, nil
+}
+
+// DeletePort removes the port from the switch. Ports that are not attached to it are ignored. A patch port talpa
+// created is removed together with its peer on the other bridge, which is useless without it.
+func (vs *VirtualSwitch) DeletePort(portName string) error {
+	current, ok := vs.bridge.Ports[portName]
+	if !ok {
+		return nil
+	}
+	if peerName := patchPeer(current); peerName != "" {
+		if err := vs.ovsService.DeletePatch(portName, peerName); err != nil {
+			return fmt.Errorf("could not delete port %s: %v", portName, err)
+		}
+		delete(vs.bridge.Ports, portName)
+		return nil
+	}
+	// the QoS and queues of the port are not garbage collected with it, so they go in the same transaction
+	txn := vs.ovsService.NewTxn(vs.bridge.Name)
+	txn.SetPortQos(portName, nil)
+	txn.DeletePort(portName)
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("could not delete port %s: %v", portName, err)
+	}
+	delete(vs.bridge.Ports, portName)
+	return nil
+}
+
+// CreatePatch links the switch to the bridge of peer, in the same OVS instance, with a pair of patch ports:
+// portName on this switch, requesting the OpenFlow port number ofport, and peer on the other bridge.
+// Both are labelled as ports of the owner of the switch, peer with the peer role unless it has its own labels, and
+// with the name of the port at their other end, so deleting the port deletes its peer too.
+func (vs *VirtualSwitch) CreatePatch(portName string, ofport int, peer PatchPort) error {
+	port := PatchPort{Bridge: vs.bridge.Name, Name: portName, Ofport: ofport,
+		ExternalIds: ownerExternalIds(vs.bridge.Name, vs.bridge.Owner, plsv1.PORT_ROLE_PORT),
+	}
+	port.ExternalIds[PATCH_PEER_EXTERNAL_ID] = peer.Name
+	externalIds := map[string]string{PATCH_PEER_EXTERNAL_ID: portName}
+	if peer.ExternalIds == nil {
+		peer.ExternalIds = ownerExternalIds(peer.Bridge, vs.bridge.Owner, plsv1.PORT_ROLE_PEER)
+	}
+	for k, v := range peer.ExternalIds {
+		externalIds[k] = v
+	}
+	peer.ExternalIds = externalIds
+	if err := vs.ovsService.CreatePatch(port, peer); err != nil {
+		return fmt.Errorf("could not patch %s to %s: %v", vs.bridge.Name, peer.Bridge, err)
+	}
+	if vs.bridge.Ports == nil {
+		vs.bridge.Ports = make(map[string]plsv1.Port)
+	}
+	vs.bridge.Ports[portName] = plsv1.Port{Name: portName, Role: plsv1.PORT_ROLE_PORT,
+		State: &plsv1.PortState{Type: "patch", ExternalIds: port.ExternalIds}}
+	return nil
 }
 
 func (vs *VirtualSwitch) GetPortNumber(portName string) (int64, error) {
@@ -326,7 +843,5 @@ func (vs *VirtualSwitch) GetPortNumber(portName string) (int64, error) {
 }
 
 func (vs *VirtualSwitch) exists() bool {
-	err := vs.ovsService.exec.Run("br-exists", vs.bridge.Name)
-
-	return err == nil
+	return vs.ovsService.BridgeExists(vs.bridge.Name)
 }
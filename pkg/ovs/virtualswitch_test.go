@@ -0,0 +1,599 @@
+package ovs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+func TestUpdateVirtualSwitchTunnelDrift(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	vx0 := plsv1.Vxlan{VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "7000"}
+	vx1 := plsv1.Vxlan{VxlanId: "vx1", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.3", UdpPort: "7000"}
+	vx2 := plsv1.Vxlan{VxlanId: "vx2", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.4", UdpPort: "7000"}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans([]plsv1.Vxlan{vx0, vx1, vx2})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// tunnels another tool created are not talpa's to remove, even if their name looks like one of talpa
+	for _, vxID := range []string{"vx9", "vxlan-lab01"} {
+		foreign := plsv1.Vxlan{VxlanId: vxID, LocalIp: "10.0.0.1", RemoteIp: "10.0.0.9", UdpPort: "7000"}
+		if err := svc.CreateVxlan("br0", foreign); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	// but an unlabelled tunnel named as talpa names its tunnels was created by talpa before it labelled them
+	legacy := plsv1.Vxlan{VxlanId: "vxlan-1a2b3", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.8", UdpPort: "7000"}
+	if err := svc.CreateVxlan("br0", legacy); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	uuid := fake.rows("Interface", map[string]any{"name": "vx1"})[0].uuid()
+
+	// vx0 is unchanged, vx1 drifted, vx2 is no longer wanted and vx3 is new
+	vx1.RemoteIp = "10.0.0.30"
+	vx3 := plsv1.Vxlan{VxlanId: "vx3", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.5", UdpPort: "7000"}
+	vs, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans([]plsv1.Vxlan{vx0, vx1, vx3}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := TunnelChanges{Created: []string{"vx3"}, Modified: []string{"vx1"}, Removed: []string{"vx2", "vxlan-1a2b3"}}
+	if changes := vs.TunnelChanges(); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected tunnel changes: %s", changes)
+	}
+
+	for _, vxID := range []string{"vx9", "vxlan-lab01"} {
+		if rows := fake.rows("Interface", map[string]any{"name": vxID}); len(rows) != 1 {
+			t.Errorf("expected the foreign tunnel %s to be kept", vxID)
+		}
+	}
+	if rows := fake.rows("Interface", map[string]any{"name": "vxlan-1a2b3"}); len(rows) != 0 {
+		t.Errorf("expected the legacy tunnel vxlan-1a2b3 to be removed")
+	}
+
+	rows := fake.rows("Interface", map[string]any{"name": "vx1"})
+	if len(rows) != 1 || rows[0].uuid() != uuid {
+		t.Fatalf("expected vx1 to be modified in place")
+	}
+	if remote := rows[0].strMap("options")["remote_ip"]; remote != "10.0.0.30" {
+		t.Errorf("expected vx1 remote_ip to be updated, got: %s", remote)
+	}
+
+	vs, err = UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans([]plsv1.Vxlan{vx0, vx1, vx3}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if changes := vs.TunnelChanges(); len(changes.Created)+len(changes.Modified)+len(changes.Removed) != 0 {
+		t.Errorf("expected no changes on an up to date switch, got: %s", changes)
+	}
+}
+
+func TestUpdateVirtualSwitchTunnelQos(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	update := func(vxlans ...plsv1.Vxlan) TunnelChanges {
+		t.Helper()
+		vs, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans(vxlans))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		return vs.TunnelChanges()
+	}
+
+	vx0 := plsv1.Vxlan{VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "7000",
+		Qos: &plsv1.PortQos{IngressPolicingRate: 10000, MaxRate: 50000000}}
+	if changes := update(vx0); !reflect.DeepEqual(changes.Created, []string{"vx0"}) {
+		t.Fatalf("expected vx0 to be created, got: %s", changes)
+	}
+	if n := len(fake.rows("QoS", nil)); n != 1 {
+		t.Fatalf("expected the tunnel qos to be created, got %d qos rows", n)
+	}
+
+	// the same traffic controls are not applied again
+	if changes := update(vx0); len(changes.Modified) != 0 {
+		t.Errorf("expected no changes on an up to date tunnel, got: %s", changes)
+	}
+
+	vx0.Qos = &plsv1.PortQos{IngressPolicingRate: 20000}
+	if changes := update(vx0); !reflect.DeepEqual(changes.Modified, []string{"vx0"}) {
+		t.Errorf("expected vx0 to be modified, got: %s", changes)
+	}
+	if n := len(fake.rows("QoS", nil)); n != 0 {
+		t.Errorf("expected the egress qos to be removed, got %d qos rows", n)
+	}
+	iface := fake.rows("Interface", map[string]any{"name": "vx0"})[0]
+	if rate, _ := iface.integer("ingress_policing_rate"); rate != 20000 {
+		t.Errorf("expected ingress policing rate 20000, got: %d", rate)
+	}
+
+	vx0.Qos = nil
+	if changes := update(vx0); !reflect.DeepEqual(changes.Modified, []string{"vx0"}) {
+		t.Errorf("expected vx0 to be modified, got: %s", changes)
+	}
+	if changes := update(vx0); len(changes.Modified) != 0 {
+		t.Errorf("expected no changes on a tunnel without traffic controls, got: %s", changes)
+	}
+
+	// the qos and queues of a removed tunnel go with it
+	vx0.Qos = &plsv1.PortQos{MaxRate: 50000000}
+	update(vx0)
+	if changes := update(); !reflect.DeepEqual(changes.Removed, []string{"vx0"}) {
+		t.Fatalf("expected vx0 to be removed, got: %s", changes)
+	}
+	if n := len(fake.rows("QoS", nil)) + len(fake.rows("Queue", nil)); n != 0 {
+		t.Errorf("expected the qos of vx0 to be removed, got %d qos and queue rows", n)
+	}
+}
+
+func TestVirtualSwitchPatch(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	for _, bridgeName := range []string{"ned", "sps"} {
+		if err := svc.AddBridge(bridgeName); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	vs, err := GetVirtualSwitch(WithName("ned"), WithOvsdb(svc.address))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := vs.CreatePatch("lsned003", 3, PatchPort{Bridge: "sps", Name: "lssps007", Ofport: 7}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// both ends request the port number of their bridge and record the other end
+	for name, expected := range map[string][2]any{"lsned003": {int64(3), "lssps007"}, "lssps007": {int64(7), "lsned003"}} {
+		iface := fake.rows("Interface", map[string]any{"name": name})[0]
+		if ofport, _ := iface.integer("ofport_request"); ofport != expected[0] {
+			t.Errorf("expected %s to request ofport %d, got: %d", name, expected[0], ofport)
+		}
+		if peer := iface.strMap("external_ids")[PATCH_PEER_EXTERNAL_ID]; peer != expected[1] {
+			t.Errorf("expected %s to record its peer %s, got: %s", name, expected[1], peer)
+		}
+	}
+
+	// the peer goes away with the port, also when the switch is read back
+	vs, err = GetVirtualSwitch(WithName("ned"), WithOvsdb(svc.address))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := vs.DeletePort("lsned003"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("Port", map[string]any{"name": "lssps007"})); n != 0 {
+		t.Errorf("expected the peer to be deleted with the port")
+	}
+	if n := len(fake.rows("Port", map[string]any{"name": "lsned003"})); n != 0 {
+		t.Errorf("expected the port to be deleted")
+	}
+}
+
+func TestUpdateVirtualSwitchPortQos(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	update := func(port plsv1.Port) {
+		t.Helper()
+		if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{port})); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	port := plsv1.Port{Name: "lsabcde1", Qos: &plsv1.PortQos{IngressPolicingRate: 10000, MaxRate: 50000000,
+		Queues: []plsv1.Queue{{Id: 1, MaxRate: 1000000}}}}
+	update(port)
+	qosRows := fake.rows("QoS", nil)
+	if len(qosRows) != 1 {
+		t.Fatalf("expected the port qos to be created, got %d qos rows", len(qosRows))
+	}
+
+	// the same traffic controls are not applied again
+	update(port)
+	if rows := fake.rows("QoS", nil); len(rows) != 1 || rows[0].uuid() != qosRows[0].uuid() {
+		t.Errorf("expected the qos of an up to date port to be kept")
+	}
+	ports, err := svc.GetPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := ports["lsabcde1"].Qos; qosFingerprint(got) != qosFingerprint(port.Qos) {
+		t.Errorf("unexpected qos read back: %+v", got)
+	}
+
+	// a port without traffic controls has the ones talpa applied cleared
+	port.Qos = nil
+	update(port)
+	if n := len(fake.rows("QoS", nil)) + len(fake.rows("Queue", nil)); n != 0 {
+		t.Errorf("expected no qos rows left, got %d", n)
+	}
+	iface := fake.rows("Interface", map[string]any{"name": "lsabcde1"})[0]
+	if rate, _ := iface.integer("ingress_policing_rate"); rate != 0 {
+		t.Errorf("expected ingress policing to be disabled, got: %d", rate)
+	}
+	if _, ok := iface.strMap("external_ids")[QOS_EXTERNAL_ID]; ok {
+		t.Errorf("expected the qos fingerprint to be removed")
+	}
+}
+
+func TestUpdateVirtualSwitchPortVlan(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	tag := 10
+	port := plsv1.Port{Name: "lsabcde1", Tag: &tag, VlanMode: plsv1.VLAN_MODE_ACCESS}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{port})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	row := fake.rows("Port", map[string]any{"name": "lsabcde1"})[0]
+	if tag, _ := row.integer("tag"); tag != 10 || row.str("vlan_mode") != plsv1.VLAN_MODE_ACCESS {
+		t.Errorf("unexpected vlan configuration: tag %d, mode %s", tag, row.str("vlan_mode"))
+	}
+
+	// a port requested without VLAN fields, e.g. to change its MTU, keeps the VLAN configuration it has
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{{Name: "lsabcde1", Mtu: 1450}})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	row = fake.rows("Port", map[string]any{"name": "lsabcde1"})[0]
+	if tag, _ := row.integer("tag"); tag != 10 || row.str("vlan_mode") != plsv1.VLAN_MODE_ACCESS {
+		t.Errorf("expected the vlan configuration to be kept, got tag %d, mode %s", tag, row.str("vlan_mode"))
+	}
+
+	port = plsv1.Port{Name: "lsabcde1", Trunks: []int{30, 20}, VlanMode: plsv1.VLAN_MODE_TRUNK}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{port})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	ports, err := svc.GetPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := ports["lsabcde1"]; got.Tag != nil || !reflect.DeepEqual(got.Trunks, []int{20, 30}) || got.VlanMode != plsv1.VLAN_MODE_TRUNK {
+		t.Errorf("unexpected vlan configuration: %+v", got)
+	}
+
+	port.VlanMode = "dot1q-tunnel"
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{port})); err == nil {
+		t.Errorf("expected an error on an unsupported vlan mode")
+	}
+
+	// an invalid port is rejected before the existing bridge is deleted
+	if _, err := NewVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{port})); err == nil {
+		t.Errorf("expected an error on an unsupported vlan mode")
+	}
+	if rows := fake.rows("Port", map[string]any{"name": "lsabcde1"}); len(rows) != 1 {
+		t.Errorf("expected br0 to keep its ports, got: %v", rows)
+	}
+}
+
+func TestDeleteExpiredMirrors(t *testing.T) {
+	svc, _ := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for i, portName := range []string{"lsabcde1", "lsabcdep7"} {
+		if err := svc.AddPort("br0", portName, i+1, false); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	vs, err := GetVirtualSwitch(WithName("br0"), WithOvsdb(svc.address))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	now := time.Now()
+	expired, later := now.Add(-time.Minute), now.Add(time.Hour)
+	for _, mirror := range []plsv1.Mirror{
+		{Name: "expired", SelectSrcPorts: []string{"lsabcde1"}, OutputPort: "lsabcdep7", ExpiresAt: &expired},
+		{Name: "later", SelectDstPorts: []string{"lsabcde1"}, OutputPort: "lsabcdep7", ExpiresAt: &later},
+		{Name: "forever", SelectAll: true, OutputPort: "lsabcdep7"},
+	} {
+		if err := vs.CreateMirror(mirror); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	if err := vs.CreateMirror(plsv1.Mirror{Name: "loop", SelectSrcPorts: []string{"lsabcdep7"}, OutputPort: "lsabcdep7"}); err == nil {
+		t.Errorf("expected an error on a mirror of its own output port")
+	}
+
+	deleted, err := vs.DeleteExpiredMirrors(now)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"expired"}) {
+		t.Errorf("unexpected deleted mirrors: %v", deleted)
+	}
+	mirrors, err := vs.GetMirrors()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mirrors) != 2 || mirrors[0].Name != "forever" || mirrors[1].Name != "later" {
+		t.Errorf("unexpected mirrors: %+v", mirrors)
+	}
+}
+
+func TestUpdateVirtualSwitchFlowExport(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	export := plsv1.FlowExport{
+		Sflow: &plsv1.Sflow{Targets: []string{"10.0.0.1:6343"}, Sampling: 64, Agent: "eth0"},
+		Ipfix: &plsv1.Ipfix{Targets: []string{"10.0.0.1:4739"}, CacheMaxFlows: 1024},
+	}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithFlowExport(export)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	sflow := fake.rows("sFlow", nil)
+	if len(sflow) != 1 || sflow[0].str("agent") != "eth0" || !reflect.DeepEqual(sflow[0].strings("targets"), []string{"10.0.0.1:6343"}) {
+		t.Fatalf("unexpected sflow rows: %v", sflow)
+	}
+	if sampling, _ := sflow[0].integer("sampling"); sampling != 64 {
+		t.Errorf("expected sampling 64, got: %d", sampling)
+	}
+	if ipfix := fake.rows("IPFIX", nil); len(ipfix) != 1 {
+		t.Errorf("expected an ipfix row, got: %v", ipfix)
+	}
+
+	// collectors left out are removed, and the replaced ones garbage collected
+	export = plsv1.FlowExport{Netflow: &plsv1.Netflow{Targets: []string{"10.0.0.1:2055"}, ActiveTimeout: 60}}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithFlowExport(export)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(fake.rows("sFlow", nil)) + len(fake.rows("IPFIX", nil)); n != 0 {
+		t.Errorf("expected the previous collectors to be removed, got %d", n)
+	}
+	bridge := fake.rows("Bridge", map[string]any{"name": "br0"})[0]
+	netflow := fake.rows("NetFlow", nil)
+	if len(netflow) != 1 || bridge.uuids("netflow")[0] != netflow[0].uuid() {
+		t.Errorf("expected the bridge to reference its netflow collector")
+	}
+
+	for _, invalid := range []plsv1.FlowExport{
+		{Sflow: &plsv1.Sflow{}},
+		{Ipfix: &plsv1.Ipfix{Targets: []string{"collector:4739"}}},
+		{Netflow: &plsv1.Netflow{Targets: []string{"10.0.0.1"}}},
+		{Sflow: &plsv1.Sflow{Targets: []string{"10.0.0.1:6343"}, Sampling: -1}},
+	} {
+		if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithFlowExport(invalid)); err == nil {
+			t.Errorf("expected an error on %+v", invalid)
+		}
+	}
+}
+
+func TestUpdateVirtualSwitchMtu(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	vx := plsv1.Vxlan{VxlanId: "vxlan-a", RemoteIp: "10.0.0.2", UdpPort: "7000", Mtu: 1450}
+	vs, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address),
+		WithVxlans([]plsv1.Vxlan{vx}), WithPorts([]plsv1.Port{{Name: "lsabcde1", Mtu: 1450}}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, name := range []string{"vxlan-a", "lsabcde1"} {
+		iface := fake.rows("Interface", map[string]any{"name": name})[0]
+		if mtu, _ := iface.integer("mtu_request"); mtu != 1450 {
+			t.Errorf("expected %s to request mtu 1450, got: %d", name, mtu)
+		}
+	}
+
+	// the same mtu leaves the tunnel alone, a different one is fixed in place
+	vs, err = UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans([]plsv1.Vxlan{vx}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if changes := vs.TunnelChanges(); len(changes.Modified) != 0 {
+		t.Errorf("expected no tunnel changes, got: %s", changes)
+	}
+	vx.Mtu = 8950
+	vs, err = UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans([]plsv1.Vxlan{vx}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if changes := vs.TunnelChanges(); !reflect.DeepEqual(changes.Modified, []string{"vxlan-a"}) {
+		t.Errorf("expected vxlan-a to be modified, got: %s", changes)
+	}
+	vxs, err := vs.GetVxlans()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(vxs) != 1 || vxs[0].Mtu != 8950 {
+		t.Errorf("unexpected tunnels: %+v", vxs)
+	}
+}
+
+func TestUpdateVirtualSwitchDatapathType(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithDatapathType("dpdk")); err == nil {
+		t.Fatalf("expected an error for an unsupported datapath type")
+	}
+	if datapathType := fake.rows("Bridge", map[string]any{"name": "br0"})[0].str("datapath_type"); datapathType != "" {
+		t.Errorf("expected the datapath type to be left alone, got: %s", datapathType)
+	}
+
+	vs, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithDatapathType(plsv1.DATAPATH_NETDEV))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if vs.bridge.DatapathType != plsv1.DATAPATH_NETDEV {
+		t.Errorf("expected the switch to be on the netdev datapath, got: %s", vs.bridge.DatapathType)
+	}
+	if datapathType := fake.rows("Bridge", map[string]any{"name": "br0"})[0].str("datapath_type"); datapathType != plsv1.DATAPATH_NETDEV {
+		t.Errorf("expected datapath_type netdev, got: %s", datapathType)
+	}
+}
+
+func TestUpdateVirtualSwitchSpanningTree(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	priority := 4000
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address),
+		WithSpanningTree(plsv1.SpanningTree{Protocol: plsv1.STP_PROTOCOL_RSTP, Priority: &priority})); err == nil {
+		t.Fatalf("expected an error for an rstp priority that is not a multiple of 4096")
+	}
+
+	priority = 4096
+	vx := plsv1.Vxlan{VxlanId: "vxlan-a", RemoteIp: "10.0.0.2", UdpPort: "7000", PathCost: 100}
+	_, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithVxlans([]plsv1.Vxlan{vx}),
+		WithSpanningTree(plsv1.SpanningTree{Protocol: plsv1.STP_PROTOCOL_RSTP, Priority: &priority}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	bridge := fake.rows("Bridge", map[string]any{"name": "br0"})[0]
+	if bridge["rstp_enable"] != true || bridge["stp_enable"] == true {
+		t.Errorf("expected only rstp to be enabled, got: %v", bridge)
+	}
+	if otherConfig := bridge.strMap("other_config"); otherConfig["rstp-priority"] != "4096" {
+		t.Errorf("expected rstp-priority 4096, got: %v", otherConfig)
+	}
+	port := fake.rows("Port", map[string]any{"name": "vxlan-a"})[0]
+	if cost := port.strMap("other_config")["rstp-path-cost"]; cost != "100" {
+		t.Errorf("expected vxlan-a to have path cost 100, got: %s", cost)
+	}
+
+	fake.update("Bridge", map[string]any{"name": "br0"}, map[string]any{"rstp_status": ovsdbMap(map[string]string{
+		"rstp_bridge_id": "1.000.aabbccddeeff", "rstp_root_id": "0.000.112233445566", "rstp_root_path_cost": "100",
+	})})
+	fake.update("Port", map[string]any{"name": "vxlan-a"}, map[string]any{"rstp_status": ovsdbMap(map[string]string{
+		"rstp_port_role": "Alternate", "rstp_port_state": "Discarding",
+	})})
+	status, err := svc.GetSpanningTreeStatus("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := plsv1.SpanningTreeStatus{
+		Protocol: plsv1.STP_PROTOCOL_RSTP, BridgeId: "1.000.aabbccddeeff", RootId: "0.000.112233445566", RootPathCost: 100,
+		Ports: []plsv1.SpanningTreePortStatus{{Name: "vxlan-a", Role: "alternate", State: "discarding", PathCost: 100, Blocked: true}},
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("unexpected spanning tree status: %+v", status)
+	}
+
+	// an empty protocol disables the spanning tree and drops the priority
+	if _, err = UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithSpanningTree(plsv1.SpanningTree{})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	bridge = fake.rows("Bridge", map[string]any{"name": "br0"})[0]
+	if bridge["rstp_enable"] == true || len(bridge.strMap("other_config")) != 0 {
+		t.Errorf("expected the spanning tree to be disabled, got: %v", bridge)
+	}
+}
+
+func TestUpdateVirtualSwitchBond(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	bond := plsv1.Port{Name: "bond0", Bond: &plsv1.Bond{Members: []string{"eth1", "eth2", "eth3"}, Mode: plsv1.BOND_MODE_ACTIVE_BACKUP}}
+	if err := svc.AddBond("br0", bond); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	bond.Bond = &plsv1.Bond{Members: []string{"eth1", "eth2"}, Mode: plsv1.BOND_MODE_BALANCE_TCP}
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{bond})); err == nil {
+		t.Fatalf("expected an error for a balance-tcp bond without lacp")
+	}
+
+	// the mode changes and eth3 leaves the bond, in place
+	bond.Bond.Mode, bond.Bond.Lacp = plsv1.BOND_MODE_BALANCE_SLB, plsv1.LACP_PASSIVE
+	if _, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{bond})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	ports, err := svc.GetPorts("br0")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	got := ports["bond0"].Bond
+	if got == nil || !reflect.DeepEqual(got.Members, []string{"eth1", "eth2"}) || got.Mode != plsv1.BOND_MODE_BALANCE_SLB || got.Lacp != plsv1.LACP_PASSIVE {
+		t.Errorf("unexpected bond: %+v", got)
+	}
+	if rows := fake.rows("Interface", map[string]any{"name": "eth3"}); len(rows) != 0 {
+		t.Errorf("expected the interface of eth3 to be removed, got: %v", rows)
+	}
+
+	if _, err = UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{{Name: "bond0"}})); err == nil {
+		t.Errorf("expected an error turning a bond into a single interface port")
+	}
+
+	// an invalid bond is rejected before the existing bridge is deleted
+	bond.Bond = &plsv1.Bond{Members: []string{"eth1"}}
+	if _, err = NewVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithPorts([]plsv1.Port{bond})); err == nil {
+		t.Errorf("expected an error for a bond with a single member")
+	}
+	if rows := fake.rows("Port", map[string]any{"name": "bond0"}); len(rows) != 1 {
+		t.Errorf("expected br0 to keep its ports, got: %v", rows)
+	}
+}
+
+func TestUpdateVirtualSwitchOwnership(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	owner := plsv1.Owner{Node: "node-a", Provider: "prov"}
+	port := plsv1.Port{Name: "lsabcde1", Role: plsv1.PORT_ROLE_PORT}
+	vx := plsv1.Vxlan{VxlanId: "vx0", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2", UdpPort: "7000", Neighbor: "node-b"}
+	_, err := UpdateVirtualSwitch(WithName("br0"), WithOvsdb(svc.address), WithOwner(owner),
+		WithPorts([]plsv1.Port{port}), WithVxlans([]plsv1.Vxlan{vx}))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	bridge := fake.rows("Bridge", map[string]any{"name": "br0"})[0].strMap("external_ids")
+	expected := map[string]string{
+		MANAGED_BY_EXTERNAL_ID: MANAGED_BY_TALPA,
+		SWITCH_EXTERNAL_ID:     "br0",
+		NODE_EXTERNAL_ID:       "node-a",
+		PROVIDER_EXTERNAL_ID:   "prov",
+	}
+	if !reflect.DeepEqual(bridge, expected) {
+		t.Errorf("unexpected bridge labels: %v", bridge)
+	}
+	for _, table := range []string{"Port", "Interface"} {
+		ids := fake.rows(table, map[string]any{"name": "lsabcde1"})[0].strMap("external_ids")
+		if ids[ROLE_EXTERNAL_ID] != plsv1.PORT_ROLE_PORT || ids[MANAGED_BY_EXTERNAL_ID] != MANAGED_BY_TALPA {
+			t.Errorf("unexpected %s labels of the adopted port: %v", table, ids)
+		}
+	}
+	ids := fake.rows("Interface", map[string]any{"name": "vx0"})[0].strMap("external_ids")
+	if ids[ROLE_EXTERNAL_ID] != plsv1.PORT_ROLE_TUNNEL || ids[NEIGHBOR_EXTERNAL_ID] != "node-b" {
+		t.Errorf("unexpected tunnel labels: %v", ids)
+	}
+
+	vs, err := GetVirtualSwitch(WithName("br0"), WithOvsdb(svc.address))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := vs.bridge.Vxlans["vx0"]; got.Neighbor != "node-b" {
+		t.Errorf("expected the neighbor to be read back from the tunnel labels, got: %+v", got)
+	}
+}
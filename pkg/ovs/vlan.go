@@ -0,0 +1,102 @@
+package ovs
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	plsv1 "github.com/Networks-it-uc3m/l2sm-switch/api/v1"
+)
+
+// vlanColumns are the Port columns the VLAN configuration of a port is read from.
+var vlanColumns = []string{"tag", "trunks", "vlan_mode"}
+
+// validateVlan checks that OVS accepts the VLAN configuration of the port.
+func validateVlan(port plsv1.Port) error {
+	if port.VlanMode != "" && !plsv1.IsVlanMode(port.VlanMode) {
+		return fmt.Errorf("unsupported vlan mode %s for port %s", port.VlanMode, port.Name)
+	}
+	if port.Tag != nil && (*port.Tag < 0 || *port.Tag > plsv1.MAX_VLAN_ID) {
+		return fmt.Errorf("invalid vlan tag %d for port %s", *port.Tag, port.Name)
+	}
+	for _, trunk := range port.Trunks {
+		if trunk < 0 || trunk > plsv1.MAX_VLAN_ID {
+			return fmt.Errorf("invalid trunk vlan %d for port %s", trunk, port.Name)
+		}
+	}
+	if port.VlanMode == plsv1.VLAN_MODE_ACCESS && len(port.Trunks) > 0 {
+		return fmt.Errorf("access port %s cannot have trunks", port.Name)
+	}
+	return nil
+}
+
+// hasVlan reports whether the port sets any of its VLAN columns.
+func hasVlan(port plsv1.Port) bool {
+	return port.Tag != nil || len(port.Trunks) > 0 || port.VlanMode != ""
+}
+
+// vlanEqual reports whether both ports have the same VLAN configuration.
+func vlanEqual(a, b plsv1.Port) bool {
+	if (a.Tag == nil) != (b.Tag == nil) || (a.Tag != nil && *a.Tag != *b.Tag) {
+		return false
+	}
+	return a.VlanMode == b.VlanMode && slices.Equal(sortedTrunks(a), sortedTrunks(b))
+}
+
+func sortedTrunks(port plsv1.Port) []int {
+	trunks := append([]int{}, port.Trunks...)
+	sort.Ints(trunks)
+	return trunks
+}
+
+// setPortVlanArgs sets, or clears when they are not set, the tag, trunks and vlan_mode of the port.
+func setPortVlanArgs(port plsv1.Port) []string {
+	tag := "tag=[]"
+	if port.Tag != nil {
+		tag = fmt.Sprintf("tag=%d", *port.Tag)
+	}
+	trunks := []string{}
+	for _, trunk := range sortedTrunks(port) {
+		trunks = append(trunks, strconv.Itoa(trunk))
+	}
+	vlanMode := "vlan_mode=[]"
+	if port.VlanMode != "" {
+		vlanMode = "vlan_mode=" + port.VlanMode
+	}
+	return []string{"set", "port", port.Name, tag, fmt.Sprintf("trunks=[%s]", strings.Join(trunks, ",")), vlanMode}
+}
+
+// portVlanColumns returns the tag, trunks and vlan_mode columns of the port row, empty sets clearing them.
+func portVlanColumns(port plsv1.Port) map[string]any {
+	tag := ovsdbSet()
+	if port.Tag != nil {
+		tag = ovsdbSet(*port.Tag)
+	}
+	trunks := []any{}
+	for _, trunk := range sortedTrunks(port) {
+		trunks = append(trunks, trunk)
+	}
+	vlanMode := ovsdbSet()
+	if port.VlanMode != "" {
+		vlanMode = ovsdbSet(port.VlanMode)
+	}
+	return map[string]any{"tag": tag, "trunks": ovsdbSet(trunks...), "vlan_mode": vlanMode}
+}
+
+// setVlanFromRow sets the VLAN configuration of the port from its Port row.
+func setVlanFromRow(port *plsv1.Port, row ovsdbRow) {
+	if tag, ok := row.integer("tag"); ok {
+		t := int(tag)
+		port.Tag = &t
+	}
+	port.Trunks = nil
+	for _, e := range row.elems("trunks") {
+		if trunk, ok := atomInt(e); ok {
+			port.Trunks = append(port.Trunks, int(trunk))
+		}
+	}
+	sort.Ints(port.Trunks)
+	port.VlanMode = row.str("vlan_mode")
+}
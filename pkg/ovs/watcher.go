@@ -0,0 +1,286 @@
+package ovs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventPortAdded              EventType = "port-added"
+	EventPortRemoved            EventType = "port-removed"
+	EventOfportAssigned         EventType = "ofport-assigned"
+	EventLinkStateChanged       EventType = "link-state-changed"
+	EventControllerConnected    EventType = "controller-connected"
+	EventControllerDisconnected EventType = "controller-disconnected"
+)
+
+// Event is a change on the watched bridge. Only the fields that make sense for its type are set:
+// Port for port and interface events, Ofport for EventOfportAssigned, LinkState for
+// EventLinkStateChanged and Controller for controller events.
+type Event struct {
+	Type       EventType
+	Bridge     string
+	Port       string
+	Ofport     int64
+	LinkState  string
+	Controller string
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case EventOfportAssigned:
+		return fmt.Sprintf("%s: %s %s ofport=%d", e.Bridge, e.Type, e.Port, e.Ofport)
+	case EventLinkStateChanged:
+		return fmt.Sprintf("%s: %s %s link_state=%s", e.Bridge, e.Type, e.Port, e.LinkState)
+	case EventControllerConnected, EventControllerDisconnected:
+		return fmt.Sprintf("%s: %s %s", e.Bridge, e.Type, e.Controller)
+	}
+	return fmt.Sprintf("%s: %s %s", e.Bridge, e.Type, e.Port)
+}
+
+const WATCHER_RETRY_INTERVAL = 5 * time.Second
+
+// monitoredColumns are the columns of each table the watcher subscribes to.
+var monitoredColumns = map[string][]string{
+	"Bridge":     {"name", "ports", "controller"},
+	"Port":       {"name", "interfaces"},
+	"Interface":  {"name", "ofport", "link_state"},
+	"Controller": {"target", "is_connected"},
+}
+
+// Watcher follows the changes of a bridge through an OVSDB monitor, so callers can react to
+// them instead of polling ovs-vsctl.
+type Watcher struct {
+	address    string
+	bridgeName string
+	// tables caches the monitored rows, by table and uuid
+	tables map[string]map[string]ovsdbRow
+	state  bridgeState
+	primed bool
+}
+
+// bridgeState is what the watcher knows about the bridge, derived from the monitored rows.
+type bridgeState struct {
+	ports       map[string]bool
+	ofports     map[string]int64
+	linkStates  map[string]string
+	controllers map[string]bool
+}
+
+func NewWatcher(address, bridgeName string) *Watcher {
+	if address == "" {
+		address = DEFAULT_OVSDB_ADDRESS
+	}
+	return &Watcher{address: address, bridgeName: bridgeName}
+}
+
+// Watch streams the events of the bridge until ctx is done, when the returned channel is closed.
+// The state found when the watch starts is taken as the baseline and produces no events.
+// If the connection to ovsdb-server is lost the watcher reconnects, reporting whatever changed meanwhile.
+func (w *Watcher) Watch(ctx context.Context) (<-chan Event, error) {
+	updates := make(chan json.RawMessage)
+	conn, _, err := w.monitor(ctx, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer func() { conn.Close() }()
+		for {
+			var pending []Event
+			select {
+			case <-ctx.Done():
+				return
+			case <-conn.done:
+				log.Printf("ovsdb monitor of %s lost: %v", w.bridgeName, conn.err)
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(WATCHER_RETRY_INTERVAL):
+					}
+					newConn, missed, err := w.monitor(ctx, updates)
+					if err == nil {
+						conn, pending = newConn, missed
+						break
+					}
+					log.Printf("could not restart ovsdb monitor of %s: %v", w.bridgeName, err)
+				}
+			case update := <-updates:
+				pending = w.apply(update, false)
+			}
+			for _, event := range pending {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// monitor connects to ovsdb-server and starts a monitor whose updates are sent to updates.
+// The initial contents replace the cached rows, and the events that lead to them are returned.
+func (w *Watcher) monitor(ctx context.Context, updates chan<- json.RawMessage) (*ovsdbConn, []Event, error) {
+	conn, err := dialOvsdb(w.address, func(method string, params json.RawMessage) {
+		if method != "update" {
+			return
+		}
+		var p []json.RawMessage
+		if err := json.Unmarshal(params, &p); err != nil || len(p) != 2 {
+			return
+		}
+		select {
+		case updates <- p[1]:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requests := map[string]any{}
+	for table, columns := range monitoredColumns {
+		requests[table] = map[string]any{"columns": columns}
+	}
+	initial, err := conn.call("monitor", OVSDB_DATABASE, "talpa", requests)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("could not monitor ovsdb: %v", err)
+	}
+	return conn, w.apply(initial, true), nil
+}
+
+// apply updates the cached rows with a table-updates object and returns the resulting events.
+func (w *Watcher) apply(raw json.RawMessage, replace bool) []Event {
+	var tableUpdates map[string]map[string]struct {
+		Old ovsdbRow `json:"old"`
+		New ovsdbRow `json:"new"`
+	}
+	if err := decodeOvsdb(raw, &tableUpdates); err != nil {
+		log.Printf("could not parse ovsdb update: %v", err)
+		return nil
+	}
+
+	if replace || w.tables == nil {
+		w.tables = make(map[string]map[string]ovsdbRow)
+		for table := range monitoredColumns {
+			w.tables[table] = make(map[string]ovsdbRow)
+		}
+	}
+	for table, rows := range tableUpdates {
+		if w.tables[table] == nil {
+			continue
+		}
+		for id, row := range rows {
+			if row.New == nil {
+				delete(w.tables[table], id)
+				continue
+			}
+			w.tables[table][id] = row.New
+		}
+	}
+
+	state := w.bridgeState()
+	var events []Event
+	if w.primed {
+		events = w.diff(w.state, state)
+	}
+	w.state = state
+	w.primed = true
+	return events
+}
+
+func (w *Watcher) bridgeState() bridgeState {
+	state := bridgeState{
+		ports:       make(map[string]bool),
+		ofports:     make(map[string]int64),
+		linkStates:  make(map[string]string),
+		controllers: make(map[string]bool),
+	}
+	for _, bridge := range w.tables["Bridge"] {
+		if bridge.str("name") != w.bridgeName {
+			continue
+		}
+		for _, portId := range bridge.uuids("ports") {
+			port, ok := w.tables["Port"][portId]
+			if !ok || port.str("name") == w.bridgeName {
+				continue
+			}
+			state.ports[port.str("name")] = true
+			for _, ifaceId := range port.uuids("interfaces") {
+				iface, ok := w.tables["Interface"][ifaceId]
+				if !ok {
+					continue
+				}
+				if ofport, ok := iface.integer("ofport"); ok {
+					state.ofports[iface.str("name")] = ofport
+				}
+				state.linkStates[iface.str("name")] = iface.str("link_state")
+			}
+		}
+		for _, controllerId := range bridge.uuids("controller") {
+			if controller, ok := w.tables["Controller"][controllerId]; ok {
+				connected := len(controller.elems("is_connected")) > 0 && controller.elems("is_connected")[0] == true
+				state.controllers[controller.str("target")] = connected
+			}
+		}
+	}
+	return state
+}
+
+// diff returns the events that lead from the old state to the new one, in a stable order.
+func (w *Watcher) diff(old, current bridgeState) []Event {
+	events := []Event{}
+	for _, port := range sortedKeys(current.ports) {
+		if !old.ports[port] {
+			events = append(events, Event{Type: EventPortAdded, Bridge: w.bridgeName, Port: port})
+		}
+	}
+	for _, port := range sortedKeys(old.ports) {
+		if !current.ports[port] {
+			events = append(events, Event{Type: EventPortRemoved, Bridge: w.bridgeName, Port: port})
+		}
+	}
+	for _, iface := range sortedKeys(current.ofports) {
+		// ovs-vswitchd sets ofport to -1 when it could not create the interface
+		if ofport := current.ofports[iface]; ofport > 0 && old.ofports[iface] != ofport {
+			events = append(events, Event{Type: EventOfportAssigned, Bridge: w.bridgeName, Port: iface, Ofport: ofport})
+		}
+	}
+	for _, iface := range sortedKeys(current.linkStates) {
+		if linkState := current.linkStates[iface]; linkState != "" && old.linkStates[iface] != linkState {
+			events = append(events, Event{Type: EventLinkStateChanged, Bridge: w.bridgeName, Port: iface, LinkState: linkState})
+		}
+	}
+	for _, target := range sortedKeys(current.controllers) {
+		if current.controllers[target] && !old.controllers[target] {
+			events = append(events, Event{Type: EventControllerConnected, Bridge: w.bridgeName, Controller: target})
+		}
+	}
+	for _, target := range sortedKeys(old.controllers) {
+		if old.controllers[target] && !current.controllers[target] {
+			events = append(events, Event{Type: EventControllerDisconnected, Bridge: w.bridgeName, Controller: target})
+		}
+	}
+	return events
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package ovs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatalf("event channel closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+func TestWatcher(t *testing.T) {
+	svc, fake := newTestOvsdbService(t)
+	if err := svc.AddBridge("br0"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddBridge("br1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := NewWatcher(svc.address, "br0").Watch(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// changes on other bridges are not reported
+	if err := svc.AddPort("br1", "lsother1", 1, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := svc.AddPort("br0", "lsabcde2", 2, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if event := nextEvent(t, events); event.Type != EventPortAdded || event.Port != "lsabcde2" {
+		t.Errorf("unexpected event: %v", event)
+	}
+	if event := nextEvent(t, events); event.Type != EventOfportAssigned || event.Port != "lsabcde2" || event.Ofport != 2 {
+		t.Errorf("unexpected event: %v", event)
+	}
+
+	fake.update("Interface", map[string]any{"name": "lsabcde1"}, map[string]any{"link_state": "up"})
+	if event := nextEvent(t, events); event.Type != EventLinkStateChanged || event.Port != "lsabcde1" || event.LinkState != "up" {
+		t.Errorf("unexpected event: %v", event)
+	}
+
+	if err := svc.SetController("br0", "tcp:127.0.0.1:6633"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	fake.update("Controller", map[string]any{"target": "tcp:127.0.0.1:6633"}, map[string]any{"is_connected": true})
+	if event := nextEvent(t, events); event.Type != EventControllerConnected || event.Controller != "tcp:127.0.0.1:6633" {
+		t.Errorf("unexpected event: %v", event)
+	}
+	fake.update("Controller", map[string]any{"target": "tcp:127.0.0.1:6633"}, map[string]any{"is_connected": false})
+	if event := nextEvent(t, events); event.Type != EventControllerDisconnected {
+		t.Errorf("unexpected event: %v", event)
+	}
+
+	if err := svc.DeletePort("br0", "lsabcde1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if event := nextEvent(t, events); event.Type != EventPortRemoved || event.Port != "lsabcde1" {
+		t.Errorf("unexpected event: %v", event)
+	}
+
+	cancel()
+	for range events {
+	}
+}